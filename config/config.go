@@ -2,24 +2,53 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+
+	"pw/crypto"
 )
 
+const DefaultProfile = "default"
+
 type Config struct {
+	Vaults         map[string]*VaultProfile     `json:"vaults"`
+	DefaultVault   string                       `json:"default_vault"`
+	PasswordLength int                          `json:"default_password_length"`
+	MinStrength    int                          `json:"minimum_password_strength"`
+	ShowStrength   bool                         `json:"show_password_strength"`
+	ClearScreen    bool                         `json:"clear_screen"`
+	HidePasswords  bool                         `json:"hide_passwords"`
+	InactivityLock int                          `json:"inactivity_lock_minutes"`
+	ExportFormat   string                       `json:"export_format"`
+	Theme          string                       `json:"theme"`
+	CSVMappings    map[string]map[string]string `json:"csv_mappings,omitempty"`
+	GPGCommand     string                       `json:"gpg_command"`
+	BreachIndex    string                       `json:"breach_index,omitempty"`
+	BreachAPI      string                       `json:"breach_api_url,omitempty"`
+	// GeneratorPresets are named password generator policies.
+	GeneratorPresets map[string]crypto.PasswordPolicy `json:"generator_presets,omitempty"`
+	// PassphraseWordlist replaces the EFF long wordlist for passphrases.
+	PassphraseWordlist string `json:"passphrase_wordlist,omitempty"`
+}
+
+type VaultProfile struct {
 	VaultPath      string `json:"vault_path"`
 	BackupDir      string `json:"backup_dir"`
 	MaxBackups     int    `json:"max_backups"`
 	AutoBackup     bool   `json:"auto_backup"`
 	BackupInterval int    `json:"backup_interval_days"`
-	PasswordLength int    `json:"default_password_length"`
-	MinStrength    int    `json:"minimum_password_strength"`
-	ShowStrength   bool   `json:"show_password_strength"`
-	ClearScreen    bool   `json:"clear_screen"`
-	HidePasswords  bool   `json:"hide_passwords"`
-	InactivityLock int    `json:"inactivity_lock_minutes"`
-	ExportFormat   string `json:"export_format"`
-	Theme          string `json:"theme"`
+	Team           bool   `json:"team,omitempty"`
+}
+
+type legacyConfig struct {
+	VaultPath      string `json:"vault_path"`
+	BackupDir      string `json:"backup_dir"`
+	MaxBackups     int    `json:"max_backups"`
+	AutoBackup     *bool  `json:"auto_backup"`
+	BackupInterval int    `json:"backup_interval_days"`
 }
 
 type Manager struct {
@@ -53,13 +82,11 @@ func NewManager() (*Manager, error) {
 }
 
 func getDefaultConfig() *Config {
-	homeDir, _ := os.UserHomeDir()
 	return &Config{
-		VaultPath:      filepath.Join(homeDir, ".pwvault", "vault.dat"),
-		BackupDir:      filepath.Join(homeDir, ".pwvault", "backups"),
-		MaxBackups:     5,
-		AutoBackup:     true,
-		BackupInterval: 7,
+		Vaults: map[string]*VaultProfile{
+			DefaultProfile: newProfile(DefaultProfile),
+		},
+		DefaultVault:   DefaultProfile,
 		PasswordLength: 16,
 		MinStrength:    60,
 		ShowStrength:   true,
@@ -68,9 +95,30 @@ func getDefaultConfig() *Config {
 		InactivityLock: 15,
 		ExportFormat:   "json",
 		Theme:          "default",
+		GPGCommand:     "gpg",
 	}
 }
 
+func newProfile(name string) *VaultProfile {
+	homeDir, _ := os.UserHomeDir()
+	baseDir := filepath.Join(homeDir, ".pwvault")
+
+	profile := &VaultProfile{
+		VaultPath:      filepath.Join(baseDir, "vault.dat"),
+		BackupDir:      filepath.Join(baseDir, "backups"),
+		MaxBackups:     5,
+		AutoBackup:     true,
+		BackupInterval: 7,
+	}
+
+	if name != DefaultProfile {
+		profile.VaultPath = filepath.Join(baseDir, "vaults", name+".dat")
+		profile.BackupDir = filepath.Join(baseDir, "backups", name)
+	}
+
+	return profile
+}
+
 func (m *Manager) Load() error {
 	data, err := os.ReadFile(m.configPath)
 	if err != nil {
@@ -80,7 +128,50 @@ func (m *Manager) Load() error {
 		return err
 	}
 
-	return json.Unmarshal(data, m.config)
+	m.config.Vaults = nil
+	if err := json.Unmarshal(data, m.config); err != nil {
+		return err
+	}
+
+	// A hand-edited length the generator cannot use falls back to the
+	// default rather than failing every time a password is generated.
+	if m.config.PasswordLength <= 0 || m.config.PasswordLength > crypto.MaxPolicyLength {
+		m.config.PasswordLength = getDefaultConfig().PasswordLength
+	}
+
+	if len(m.config.Vaults) == 0 {
+		return m.migrateLegacy(data)
+	}
+
+	return nil
+}
+
+func (m *Manager) migrateLegacy(data []byte) error {
+	var legacy legacyConfig
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+
+	profile := newProfile(DefaultProfile)
+	if legacy.VaultPath != "" {
+		profile.VaultPath = legacy.VaultPath
+	}
+	if legacy.BackupDir != "" {
+		profile.BackupDir = legacy.BackupDir
+	}
+	if legacy.MaxBackups > 0 {
+		profile.MaxBackups = legacy.MaxBackups
+	}
+	if legacy.AutoBackup != nil {
+		profile.AutoBackup = *legacy.AutoBackup
+	}
+	if legacy.BackupInterval > 0 {
+		profile.BackupInterval = legacy.BackupInterval
+	}
+
+	m.config.Vaults = map[string]*VaultProfile{DefaultProfile: profile}
+	m.config.DefaultVault = DefaultProfile
+	return m.Save()
 }
 
 func (m *Manager) Save() error {
@@ -105,3 +196,151 @@ func (m *Manager) Reset() error {
 	m.config = getDefaultConfig()
 	return m.Save()
 }
+
+func (c *Config) Profile(name string) (*VaultProfile, error) {
+	if name == "" {
+		name = c.DefaultVault
+	}
+
+	profile, ok := c.Vaults[name]
+	if !ok {
+		return nil, fmt.Errorf("vault profile %q does not exist", name)
+	}
+	return profile, nil
+}
+
+func (c *Config) ListProfiles() []string {
+	names := make([]string, 0, len(c.Vaults))
+	for name := range c.Vaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Config) CreateProfile(name, vaultPath string) (*VaultProfile, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, err
+	}
+	if _, exists := c.Vaults[name]; exists {
+		return nil, fmt.Errorf("vault profile %q already exists", name)
+	}
+
+	profile := newProfile(name)
+	if vaultPath != "" {
+		profile.VaultPath = vaultPath
+	}
+
+	for other, p := range c.Vaults {
+		if filepath.Clean(p.VaultPath) == filepath.Clean(profile.VaultPath) {
+			return nil, fmt.Errorf("vault file is already used by profile %q", other)
+		}
+	}
+
+	if c.Vaults == nil {
+		c.Vaults = make(map[string]*VaultProfile)
+	}
+	c.Vaults[name] = profile
+	return profile, nil
+}
+
+func (c *Config) RenameProfile(oldName, newName string) error {
+	if err := validateProfileName(newName); err != nil {
+		return err
+	}
+
+	profile, ok := c.Vaults[oldName]
+	if !ok {
+		return fmt.Errorf("vault profile %q does not exist", oldName)
+	}
+	if _, exists := c.Vaults[newName]; exists {
+		return fmt.Errorf("vault profile %q already exists", newName)
+	}
+
+	delete(c.Vaults, oldName)
+	c.Vaults[newName] = profile
+	if c.DefaultVault == oldName {
+		c.DefaultVault = newName
+	}
+	return nil
+}
+
+func (c *Config) DeleteProfile(name string) error {
+	if _, ok := c.Vaults[name]; !ok {
+		return fmt.Errorf("vault profile %q does not exist", name)
+	}
+	if name == c.DefaultVault {
+		return fmt.Errorf("cannot delete the default vault profile")
+	}
+
+	delete(c.Vaults, name)
+	return nil
+}
+
+func validateProfileName(name string) error {
+	if name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+	if strings.ContainsAny(name, `/\:*?"<>| `) {
+		return fmt.Errorf("profile name contains invalid characters")
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("profile name %q is reserved", name)
+	}
+	return nil
+}
+
+// GeneratorPolicy returns the named preset, or the default policy for an
+// empty name. A preset without a length uses the default password length.
+func (c *Config) GeneratorPolicy(name string) (crypto.PasswordPolicy, error) {
+	if name == "" {
+		return crypto.DefaultPolicy(c.PasswordLength), nil
+	}
+
+	policy, ok := c.GeneratorPresets[name]
+	if !ok {
+		return crypto.PasswordPolicy{}, fmt.Errorf("generator preset %q does not exist", name)
+	}
+	if policy.Length == 0 {
+		policy.Length = c.PasswordLength
+	}
+	return policy, nil
+}
+
+func (c *Config) ListPresets() []string {
+	names := make([]string, 0, len(c.GeneratorPresets))
+	for name := range c.GeneratorPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SavePreset adds or replaces a generator preset. A zero length follows the
+// default password length.
+func (c *Config) SavePreset(name string, policy crypto.PasswordPolicy) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("preset name cannot be empty")
+	}
+	check := policy
+	if check.Length == 0 {
+		check.Length = c.PasswordLength
+	}
+	if err := check.Validate(); err != nil {
+		return err
+	}
+
+	if c.GeneratorPresets == nil {
+		c.GeneratorPresets = make(map[string]crypto.PasswordPolicy)
+	}
+	c.GeneratorPresets[name] = policy
+	return nil
+}
+
+func (c *Config) DeletePreset(name string) error {
+	if _, ok := c.GeneratorPresets[name]; !ok {
+		return fmt.Errorf("generator preset %q does not exist", name)
+	}
+	delete(c.GeneratorPresets, name)
+	return nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+func TestValidateProfileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"work", true},
+		{"personal-2", true},
+		{".hidden", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"a/b", false},
+		{`a\b`, false},
+		{"my vault", false},
+	}
+	for _, tt := range tests {
+		if err := validateProfileName(tt.name); (err == nil) != tt.valid {
+			t.Errorf("validateProfileName(%q) = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
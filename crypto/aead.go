@@ -0,0 +1,46 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrAuthentication means sealed data was modified or the key is wrong.
+var ErrAuthentication = errors.New("data failed authentication")
+
+// Seal encrypts and authenticates data with XChaCha20-Poly1305 under a
+// 32-byte key. additionalData is authenticated but not encrypted. The random
+// nonce is prepended to the result.
+func Seal(data, additionalData, key []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, data, additionalData), nil
+}
+
+// Open reverses Seal. It returns ErrAuthentication when the data, the
+// additional data or the key do not match.
+func Open(sealed, additionalData, key []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrAuthentication
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return data, nil
+}
@@ -0,0 +1,184 @@
+package crypto
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// effLargeWordlist is the EFF long wordlist for five dice
+// (https://www.eff.org/dice), licensed CC BY 3.0 US.
+//
+//go:embed wordlists/eff_large_wordlist.txt
+var effLargeWordlist string
+
+// Wordlist is a set of distinct lower-case words for passphrases.
+type Wordlist struct {
+	Name  string
+	words []string
+	// capitalizable counts the words whose first letter has an upper-case
+	// form, which random capitalization can change.
+	capitalizable int
+}
+
+var effWordlist = sync.OnceValue(func() *Wordlist {
+	list, err := ParseWordlist("EFF long", strings.NewReader(effLargeWordlist))
+	if err != nil {
+		panic("crypto: embedded wordlist: " + err.Error())
+	}
+	return list
+})
+
+// EFFWordlist returns the embedded EFF long wordlist of 7776 words.
+func EFFWordlist() *Wordlist {
+	return effWordlist()
+}
+
+// ParseWordlist reads one word per line. Diceware lists with a roll before
+// each word are also accepted. Words are lower-cased and duplicates dropped.
+func ParseWordlist(name string, r io.Reader) (*Wordlist, error) {
+	list := &Wordlist{Name: name}
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		word := strings.ToLower(fields[len(fields)-1])
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		list.words = append(list.words, word)
+
+		first := []rune(word)[0]
+		if unicode.ToUpper(first) != first {
+			list.capitalizable++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(list.words) < 2 {
+		return nil, fmt.Errorf("wordlist %s needs at least two distinct words", name)
+	}
+	return list, nil
+}
+
+func LoadWordlist(path string) (*Wordlist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseWordlist(path, file)
+}
+
+func (w *Wordlist) Len() int {
+	return len(w.words)
+}
+
+type Capitalization string
+
+const (
+	LowerCase Capitalization = "lower"
+	// TitleCase capitalizes every word, which adds no entropy.
+	TitleCase Capitalization = "title"
+	// RandomCase capitalizes each word with probability one half.
+	RandomCase Capitalization = "random"
+)
+
+type PassphraseOptions struct {
+	Words     int
+	Separator string
+	Case      Capitalization
+	// Digits inserts a block of this many random digits as an extra word
+	// at a random position.
+	Digits int
+	// Wordlist defaults to the EFF long wordlist.
+	Wordlist *Wordlist
+}
+
+// DefaultPassphraseWords gives about 77 bits with the EFF long wordlist.
+const DefaultPassphraseWords = 6
+
+func (o PassphraseOptions) wordlist() *Wordlist {
+	if o.Wordlist != nil {
+		return o.Wordlist
+	}
+	return EFFWordlist()
+}
+
+func (o PassphraseOptions) Validate() error {
+	if o.Words <= 0 {
+		return fmt.Errorf("a passphrase needs at least one word")
+	}
+	if o.Digits < 0 {
+		return fmt.Errorf("the number of digits cannot be negative")
+	}
+	switch o.Case {
+	case "", LowerCase, TitleCase, RandomCase:
+		return nil
+	default:
+		return fmt.Errorf("unknown capitalization %q (use lower, title or random)", o.Case)
+	}
+}
+
+// Entropy is the exact entropy in bits of the passphrases GeneratePassphrase
+// produces, for an attacker who knows the wordlist and the options.
+func (o PassphraseOptions) Entropy() float64 {
+	list := o.wordlist()
+	n := float64(list.Len())
+
+	perWord := math.Log2(n)
+	if o.Case == RandomCase {
+		// One bit for the words a coin flip can change.
+		perWord += float64(list.capitalizable) / n
+	}
+
+	bits := float64(o.Words) * perWord
+	if o.Digits > 0 {
+		bits += float64(o.Digits)*math.Log2(10) + math.Log2(float64(o.Words+1))
+	}
+	return bits
+}
+
+// GeneratePassphrase picks words uniformly from the wordlist.
+func (g *Generator) GeneratePassphrase(o PassphraseOptions) (string, error) {
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+
+	list := o.wordlist()
+	words := make([]string, 0, o.Words+1)
+	for i := 0; i < o.Words; i++ {
+		word := list.words[g.intn(list.Len())]
+		if o.Case == TitleCase || (o.Case == RandomCase && g.intn(2) == 1) {
+			word = capitalize(word)
+		}
+		words = append(words, word)
+	}
+
+	if o.Digits > 0 {
+		digits := g.GenerateString(o.Digits, DigitChars)
+		pos := g.intn(len(words) + 1)
+		words = append(words[:pos], append([]string{digits}, words[pos:]...)...)
+	}
+
+	return strings.Join(words, o.Separator), nil
+}
+
+func capitalize(word string) string {
+	runes := []rune(word)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
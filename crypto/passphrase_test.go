@@ -0,0 +1,174 @@
+package crypto
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestParseWordlist(t *testing.T) {
+	input := "# rolls then words\n11111\tApple\n11112 banana\n\n11113 apple\ncherry\n11114 ébène\n42\n"
+	list, err := ParseWordlist("test", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"apple", "banana", "cherry", "ébène", "42"}
+	if strings.Join(list.words, " ") != strings.Join(want, " ") {
+		t.Errorf("words = %q, want %q", list.words, want)
+	}
+	if list.capitalizable != 4 {
+		t.Errorf("capitalizable = %d, want 4", list.capitalizable)
+	}
+
+	for _, input := range []string{"", "# only a comment\n", "same\nSAME\n11111 same\n"} {
+		if _, err := ParseWordlist("short", strings.NewReader(input)); err == nil {
+			t.Errorf("%q: parsed a list with fewer than two words", input)
+		}
+	}
+}
+
+func TestEFFWordlist(t *testing.T) {
+	list := EFFWordlist()
+	if list.Len() != 7776 {
+		t.Fatalf("EFF wordlist has %d words, want 7776", list.Len())
+	}
+	bits := PassphraseOptions{Words: DefaultPassphraseWords}.Entropy()
+	if math.Abs(bits-6*math.Log2(7776)) > 1e-9 {
+		t.Errorf("default entropy = %v", bits)
+	}
+}
+
+// passphraseDistribution enumerates every random choice GeneratePassphrase
+// can make and returns the probability of each passphrase.
+func passphraseDistribution(o PassphraseOptions) map[string]float64 {
+	list := o.wordlist()
+	dist := make(map[string]float64)
+
+	var digitBlocks []string
+	if o.Digits > 0 {
+		for n := 0; n < int(math.Pow10(o.Digits)); n++ {
+			digitBlocks = append(digitBlocks, fmt.Sprintf("%0*d", o.Digits, n))
+		}
+	}
+
+	var walk func(words []string, p float64)
+	walk = func(words []string, p float64) {
+		if len(words) < o.Words {
+			for _, word := range list.words {
+				p := p / float64(list.Len())
+				switch o.Case {
+				case TitleCase:
+					walk(append(words, capitalize(word)), p)
+				case RandomCase:
+					walk(append(words, word), p/2)
+					walk(append(words, capitalize(word)), p/2)
+				default:
+					walk(append(words, word), p)
+				}
+			}
+			return
+		}
+		if len(digitBlocks) == 0 {
+			dist[strings.Join(words, o.Separator)] += p
+			return
+		}
+		for _, block := range digitBlocks {
+			for pos := 0; pos <= len(words); pos++ {
+				withDigits := append(append(append([]string{}, words[:pos]...), block), words[pos:]...)
+				dist[strings.Join(withDigits, o.Separator)] += p / float64(len(digitBlocks)*(len(words)+1))
+			}
+		}
+	}
+	walk(nil, 1)
+	return dist
+}
+
+func TestPassphraseEntropyMatchesBruteForce(t *testing.T) {
+	list, err := ParseWordlist("tiny", strings.NewReader("ab\ncd\n9x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []PassphraseOptions{
+		{Words: 3, Separator: "-", Wordlist: list},
+		{Words: 2, Separator: "-", Case: TitleCase, Wordlist: list},
+		{Words: 2, Separator: "-", Case: RandomCase, Wordlist: list},
+		{Words: 2, Separator: " ", Digits: 1, Wordlist: list},
+		{Words: 2, Separator: ".", Case: RandomCase, Digits: 2, Wordlist: list},
+	} {
+		var want float64
+		for _, p := range passphraseDistribution(o) {
+			want -= p * math.Log2(p)
+		}
+		if got := o.Entropy(); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%+v: Entropy = %v, brute force = %v", o, got, want)
+		}
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	g := NewGenerator()
+	list := EFFWordlist()
+	inList := make(map[string]bool)
+	for _, w := range list.words {
+		inList[w] = true
+	}
+
+	for _, o := range []PassphraseOptions{
+		{Words: 6, Separator: "_"},
+		{Words: 4, Separator: " ", Case: TitleCase},
+		{Words: 5, Separator: ".", Case: RandomCase, Digits: 3},
+	} {
+		for i := 0; i < 100; i++ {
+			passphrase, err := g.GeneratePassphrase(o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts := strings.Split(passphrase, o.Separator)
+			digits := 0
+			for _, part := range parts {
+				if o.Digits > 0 && len(part) == o.Digits && strings.Trim(part, DigitChars) == "" {
+					digits++
+					continue
+				}
+				if !inList[strings.ToLower(part)] {
+					t.Fatalf("%+v: %q: %q is not in the wordlist", o, passphrase, part)
+				}
+				if o.Case == TitleCase && !unicode.IsUpper([]rune(part)[0]) {
+					t.Fatalf("%+v: %q: %q is not capitalized", o, passphrase, part)
+				}
+				if o.Case == "" && part != strings.ToLower(part) {
+					t.Fatalf("%+v: %q: %q is not lower-case", o, passphrase, part)
+				}
+			}
+			if len(parts)-digits != o.Words || (o.Digits > 0) != (digits == 1) {
+				t.Fatalf("%+v: %q has %d words and %d digit blocks", o, passphrase, len(parts)-digits, digits)
+			}
+		}
+	}
+}
+
+func TestPassphraseValidate(t *testing.T) {
+	for _, test := range []struct {
+		options PassphraseOptions
+		err     string
+	}{
+		{PassphraseOptions{Words: 6}, ""},
+		{PassphraseOptions{Words: 0}, "at least one word"},
+		{PassphraseOptions{Words: 4, Digits: -1}, "cannot be negative"},
+		{PassphraseOptions{Words: 4, Case: "upper"}, "unknown capitalization"},
+	} {
+		err := test.options.Validate()
+		if test.err == "" && err != nil {
+			t.Errorf("%+v: %v", test.options, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%+v: err = %v, want %q", test.options, err, test.err)
+		}
+		if err != nil {
+			if _, genErr := NewGenerator().GeneratePassphrase(test.options); genErr == nil {
+				t.Errorf("%+v: generated a passphrase for invalid options", test.options)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package crypto
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinPINLength = 4
+	MaxPINLength = 16
+)
+
+// PINOptions describes numeric PINs without the patterns people pick: the
+// same digit twice in a row, runs of three like 123 or 987, and, for 4, 6
+// and 8 digits, anything that reads as a date or a year.
+type PINOptions struct {
+	Length int
+}
+
+func (o PINOptions) Validate() error {
+	if o.Length < MinPINLength || o.Length > MaxPINLength {
+		return fmt.Errorf("PIN length must be between %d and %d", MinPINLength, MaxPINLength)
+	}
+	return nil
+}
+
+// Entropy is the exact entropy in bits of the PINs GeneratePIN produces,
+// which are uniform over every allowed PIN.
+func (o PINOptions) Entropy() float64 {
+	if o.Validate() != nil {
+		return 0
+	}
+
+	count := countPINsWithoutPatterns(o.Length)
+	for pin := range datePINs(o.Length) {
+		if !hasPINPattern(pin) {
+			count--
+		}
+	}
+	return math.Log2(float64(count))
+}
+
+// GeneratePIN draws random PINs until one has none of the forbidden
+// patterns, so every allowed PIN is equally likely.
+func (g *Generator) GeneratePIN(o PINOptions) (string, error) {
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+
+	for {
+		pin := g.GenerateString(o.Length, DigitChars)
+		if !hasPINPattern(pin) && !isDatePIN(pin) {
+			return pin, nil
+		}
+	}
+}
+
+// hasPINPattern reports repeated digits and runs of three.
+func hasPINPattern(pin string) bool {
+	for i := 1; i < len(pin); i++ {
+		if pin[i] == pin[i-1] {
+			return true
+		}
+		if i >= 2 {
+			step := int(pin[i]) - int(pin[i-1])
+			if (step == 1 || step == -1) && int(pin[i-1])-int(pin[i-2]) == step {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// countPINsWithoutPatterns counts the PINs of length n that hasPINPattern
+// accepts, tracking the last two digits.
+func countPINsWithoutPatterns(n int) uint64 {
+	var counts [10][10]uint64
+	for a := 0; a < 10; a++ {
+		for b := 0; b < 10; b++ {
+			if a != b {
+				counts[a][b] = 1
+			}
+		}
+	}
+
+	for length := 2; length < n; length++ {
+		var next [10][10]uint64
+		for a := 0; a < 10; a++ {
+			for b := 0; b < 10; b++ {
+				if counts[a][b] == 0 {
+					continue
+				}
+				for c := 0; c < 10; c++ {
+					step := c - b
+					if c == b || ((step == 1 || step == -1) && b-a == step) {
+						continue
+					}
+					next[b][c] += counts[a][b]
+				}
+			}
+		}
+		counts = next
+	}
+
+	var total uint64
+	for a := 0; a < 10; a++ {
+		for b := 0; b < 10; b++ {
+			total += counts[a][b]
+		}
+	}
+	return total
+}
+
+// dateLayouts are the date orders checked for each PIN length. D, M and Y
+// stand for day, month and year digits; four-digit years are 1900-2099.
+var dateLayouts = map[int][]string{
+	4: {"DDMM", "MMDD", "YYYY"},
+	6: {"DDMMYY", "MMDDYY", "YYMMDD"},
+	8: {"DDMMYYYY", "MMDDYYYY", "YYYYMMDD"},
+}
+
+var daysInMonth = []int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+func isDatePIN(pin string) bool {
+	for _, layout := range dateLayouts[len(pin)] {
+		if matchesDateLayout(pin, layout) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesDateLayout(pin, layout string) bool {
+	field := func(c byte) (int, bool) {
+		start, end := strings.IndexByte(layout, c), strings.LastIndexByte(layout, c)
+		if start < 0 {
+			return 0, false
+		}
+		n, _ := strconv.Atoi(pin[start : end+1])
+		return n, true
+	}
+
+	year, hasYear := field('Y')
+	if hasYear && strings.Count(layout, "Y") == 4 && (year < 1900 || year > 2099) {
+		return false
+	}
+	month, hasMonth := field('M')
+	day, hasDay := field('D')
+	if !hasMonth || !hasDay {
+		return hasYear
+	}
+	return month >= 1 && month <= 12 && day >= 1 && day <= daysInMonth[month-1]
+}
+
+// datePINs lists every PIN of length n that isDatePIN rejects.
+func datePINs(n int) map[string]bool {
+	dates := make(map[string]bool)
+	for _, layout := range dateLayouts[n] {
+		years := []int{0}
+		switch strings.Count(layout, "Y") {
+		case 2:
+			years = make([]int, 100)
+			for y := range years {
+				years[y] = y
+			}
+		case 4:
+			years = nil
+			for y := 1900; y <= 2099; y++ {
+				years = append(years, y)
+			}
+		}
+
+		for _, y := range years {
+			if !strings.Contains(layout, "M") {
+				dates[formatDatePIN(layout, 0, 0, y)] = true
+				continue
+			}
+			for m := 1; m <= 12; m++ {
+				for d := 1; d <= daysInMonth[m-1]; d++ {
+					dates[formatDatePIN(layout, d, m, y)] = true
+				}
+			}
+		}
+	}
+	return dates
+}
+
+func formatDatePIN(layout string, day, month, year int) string {
+	pin := layout
+	for _, f := range []struct {
+		c     string
+		value int
+	}{{"D", day}, {"M", month}, {"Y", year}} {
+		if width := strings.Count(layout, f.c); width > 0 {
+			pin = strings.Replace(pin, strings.Repeat(f.c, width), fmt.Sprintf("%0*d", width, f.value), 1)
+		}
+	}
+	return pin
+}
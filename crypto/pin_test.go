@@ -0,0 +1,121 @@
+package crypto
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPINPatterns(t *testing.T) {
+	for pin, want := range map[string]bool{
+		"1357": false,
+		"2580": false,
+		"1234": true,
+		"9870": true,
+		"1124": true,
+		"4813": false,
+		"0101": false,
+		"1214": false,
+	} {
+		if got := hasPINPattern(pin); got != want {
+			t.Errorf("hasPINPattern(%q) = %v, want %v", pin, got, want)
+		}
+	}
+
+	for pin, want := range map[string]bool{
+		"2512":     true,  // 25 December
+		"1225":     true,  // December 25
+		"1987":     true,  // a year
+		"3102":     false, // 31 February
+		"1357":     false,
+		"250399":   true,
+		"991231":   true,
+		"133199":   false,
+		"31121999": true,
+		"20240229": true,
+		"18991231": false,
+	} {
+		if got := isDatePIN(pin); got != want {
+			t.Errorf("isDatePIN(%q) = %v, want %v", pin, got, want)
+		}
+	}
+}
+
+// allPINs calls f with every string of n digits.
+func allPINs(n int, f func(pin string)) {
+	if n == 0 {
+		f("")
+		return
+	}
+	limit := int(math.Pow10(n))
+	for i := 0; i < limit; i++ {
+		f(fmt.Sprintf("%0*d", n, i))
+	}
+}
+
+func TestCountPINsMatchesBruteForce(t *testing.T) {
+	for n := 2; n <= 6; n++ {
+		var want uint64
+		allPINs(n, func(pin string) {
+			if !hasPINPattern(pin) {
+				want++
+			}
+		})
+		if got := countPINsWithoutPatterns(n); got != want {
+			t.Errorf("countPINsWithoutPatterns(%d) = %d, brute force = %d", n, got, want)
+		}
+	}
+}
+
+func TestDatePINsMatchIsDatePIN(t *testing.T) {
+	for _, n := range []int{4, 5, 6} {
+		dates := datePINs(n)
+		allPINs(n, func(pin string) {
+			if isDatePIN(pin) != dates[pin] {
+				t.Errorf("%s: isDatePIN = %v but datePINs has %v", pin, isDatePIN(pin), dates[pin])
+			}
+		})
+	}
+}
+
+func TestPINEntropyMatchesBruteForce(t *testing.T) {
+	for n := MinPINLength; n <= 6; n++ {
+		want := 0
+		allPINs(n, func(pin string) {
+			if !hasPINPattern(pin) && !isDatePIN(pin) {
+				want++
+			}
+		})
+		if got := (PINOptions{Length: n}).Entropy(); math.Abs(got-math.Log2(float64(want))) > 1e-9 {
+			t.Errorf("length %d: Entropy = %v, want log2(%d)", n, got, want)
+		}
+	}
+}
+
+func TestGeneratePIN(t *testing.T) {
+	g := NewGenerator()
+	for _, n := range []int{4, 6, 8, 16} {
+		for i := 0; i < 200; i++ {
+			pin, err := g.GeneratePIN(PINOptions{Length: n})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pin) != n || strings.Trim(pin, DigitChars) != "" {
+				t.Fatalf("GeneratePIN(%d) = %q", n, pin)
+			}
+			if hasPINPattern(pin) || isDatePIN(pin) {
+				t.Fatalf("GeneratePIN(%d) = %q, which has a forbidden pattern", n, pin)
+			}
+		}
+	}
+
+	for _, n := range []int{0, MinPINLength - 1, MaxPINLength + 1} {
+		if _, err := g.GeneratePIN(PINOptions{Length: n}); err == nil {
+			t.Errorf("GeneratePIN(%d) succeeded", n)
+		}
+		if bits := (PINOptions{Length: n}).Entropy(); bits != 0 {
+			t.Errorf("Entropy(%d) = %v for an invalid length", n, bits)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	LowercaseChars = "abcdefghijklmnopqrstuvwxyz"
+	UppercaseChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	DigitChars     = "0123456789"
+	DefaultSymbols = "!@#$%^&*()"
+	// AmbiguousChars are easily confused when a password is read or typed.
+	AmbiguousChars = "0O1lI"
+)
+
+// MaxPolicyLength bounds the work of counting the passwords a policy allows.
+const MaxPolicyLength = 128
+
+// PasswordPolicy describes the passwords the generator produces. A minimum
+// count of -1 leaves that character class out entirely.
+type PasswordPolicy struct {
+	Length int `json:"length"`
+	// MaxLength caps Length, for sites that reject long passwords.
+	MaxLength  int `json:"max_length,omitempty"`
+	MinLower   int `json:"min_lowercase"`
+	MinUpper   int `json:"min_uppercase"`
+	MinDigits  int `json:"min_digits"`
+	MinSymbols int `json:"min_symbols"`
+	// Symbols replaces DefaultSymbols when set.
+	Symbols          string `json:"symbols,omitempty"`
+	ExcludeAmbiguous bool   `json:"exclude_ambiguous,omitempty"`
+	// Exclude lists characters that are never used.
+	Exclude string `json:"exclude,omitempty"`
+}
+
+// DefaultPolicy uses every character class at least once when the length
+// allows it.
+func DefaultPolicy(length int) PasswordPolicy {
+	if length < 4 {
+		return PasswordPolicy{Length: length}
+	}
+	return PasswordPolicy{Length: length, MinLower: 1, MinUpper: 1, MinDigits: 1, MinSymbols: 1}
+}
+
+type charClass struct {
+	name  string
+	chars []rune
+	min   int
+}
+
+func (p PasswordPolicy) length() int {
+	if p.MaxLength > 0 && p.Length > p.MaxLength {
+		return p.MaxLength
+	}
+	return p.Length
+}
+
+func (p PasswordPolicy) classes() []charClass {
+	symbols := p.Symbols
+	if symbols == "" {
+		symbols = DefaultSymbols
+	}
+
+	// Letters and digits in a custom symbol set belong to their own class,
+	// so every character is counted once.
+	all := []charClass{
+		{"lowercase letters", []rune(LowercaseChars), p.MinLower},
+		{"uppercase letters", []rune(UppercaseChars), p.MinUpper},
+		{"digits", []rune(DigitChars), p.MinDigits},
+		{"symbols", removeRunes(uniqueRunes(symbols), LowercaseChars+UppercaseChars+DigitChars), p.MinSymbols},
+	}
+
+	var classes []charClass
+	for _, c := range all {
+		if c.min < 0 {
+			continue
+		}
+		if p.ExcludeAmbiguous {
+			c.chars = removeRunes(c.chars, AmbiguousChars)
+		}
+		if p.Exclude != "" {
+			c.chars = removeRunes(c.chars, p.Exclude)
+		}
+		classes = append(classes, c)
+	}
+	return classes
+}
+
+// Validate reports why no password can satisfy the policy.
+func (p PasswordPolicy) Validate() error {
+	length := p.length()
+	if length <= 0 {
+		return fmt.Errorf("password length must be positive")
+	}
+	if length > MaxPolicyLength {
+		return fmt.Errorf("password length cannot exceed %d", MaxPolicyLength)
+	}
+
+	classes := p.classes()
+	if len(classes) == 0 {
+		return fmt.Errorf("the policy excludes every character class")
+	}
+
+	required := 0
+	for _, c := range classes {
+		if len(c.chars) == 0 {
+			return fmt.Errorf("no %s are left to choose from", c.name)
+		}
+		required += c.min
+	}
+	if required > length {
+		return fmt.Errorf("the policy requires %d characters but the length is %d", required, length)
+	}
+	return nil
+}
+
+// String summarises the policy for menus and listings.
+func (p PasswordPolicy) String() string {
+	parts := []string{fmt.Sprintf("%d characters", p.length())}
+	for _, c := range []struct {
+		name string
+		min  int
+	}{{"lowercase", p.MinLower}, {"uppercase", p.MinUpper}, {"digits", p.MinDigits}, {"symbols", p.MinSymbols}} {
+		switch {
+		case c.min < 0:
+			parts = append(parts, "no "+c.name)
+		case c.min > 0:
+			parts = append(parts, fmt.Sprintf("%d+ %s", c.min, c.name))
+		}
+	}
+	if p.Symbols != "" && p.MinSymbols >= 0 {
+		parts = append(parts, "symbols "+p.Symbols)
+	}
+	if p.ExcludeAmbiguous {
+		parts = append(parts, "no ambiguous characters")
+	}
+	if p.Exclude != "" {
+		parts = append(parts, "excluding "+p.Exclude)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// classCounts is one way of splitting a password's length between the
+// character classes, and how many passwords have that split.
+type classCounts struct {
+	counts    []int
+	passwords *big.Int
+}
+
+// space lists every split of the length that meets the minimum counts. For
+// counts n1..nk there are multinomial(length; n1..nk) * prod(|Ci|^ni)
+// passwords.
+func (p PasswordPolicy) space() ([]classCounts, *big.Int) {
+	classes := p.classes()
+	length := p.length()
+
+	binomial := make([][]*big.Int, length+1)
+	for n := range binomial {
+		binomial[n] = make([]*big.Int, n+1)
+		binomial[n][0], binomial[n][n] = big.NewInt(1), big.NewInt(1)
+		for k := 1; k < n; k++ {
+			binomial[n][k] = new(big.Int).Add(binomial[n-1][k-1], binomial[n-1][k])
+		}
+	}
+
+	powers := make([][]*big.Int, len(classes))
+	for i, c := range classes {
+		powers[i] = make([]*big.Int, length+1)
+		powers[i][0] = big.NewInt(1)
+		for k := 1; k <= length; k++ {
+			powers[i][k] = new(big.Int).Mul(powers[i][k-1], big.NewInt(int64(len(c.chars))))
+		}
+	}
+
+	var splits []classCounts
+	total := new(big.Int)
+	counts := make([]int, len(classes))
+	var walk func(class, remaining int, passwords *big.Int)
+	walk = func(class, remaining int, passwords *big.Int) {
+		c := classes[class]
+		if class == len(classes)-1 {
+			if remaining < c.min {
+				return
+			}
+			counts[class] = remaining
+			n := new(big.Int).Mul(powers[class][remaining], passwords)
+			splits = append(splits, classCounts{counts: append([]int{}, counts...), passwords: n})
+			total.Add(total, n)
+			return
+		}
+		for k := c.min; k <= remaining; k++ {
+			counts[class] = k
+			n := new(big.Int).Mul(powers[class][k], binomial[remaining][k])
+			walk(class+1, remaining-k, n.Mul(n, passwords))
+		}
+	}
+	walk(0, length, big.NewInt(1))
+	return splits, total
+}
+
+// Entropy is the exact entropy in bits of the passwords GeneratePolicy
+// produces, which are uniform over every password the policy allows.
+func (p PasswordPolicy) Entropy() float64 {
+	if p.Validate() != nil {
+		return 0
+	}
+	_, total := p.space()
+	return log2Big(total)
+}
+
+// GeneratePolicy returns a password chosen uniformly among those that
+// satisfy the policy: it picks how many characters come from each class in
+// proportion to the passwords with that split, then the positions and the
+// characters.
+func (g *Generator) GeneratePolicy(p PasswordPolicy) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	splits, total := p.space()
+	pick, err := rand.Int(g.rand, total)
+	if err != nil {
+		return "", err
+	}
+	split := splits[len(splits)-1]
+	for _, s := range splits {
+		if pick.Cmp(s.passwords) < 0 {
+			split = s
+			break
+		}
+		pick.Sub(pick, s.passwords)
+	}
+
+	classes := p.classes()
+	password := make([]rune, 0, p.length())
+	for i, c := range classes {
+		for k := 0; k < split.counts[i]; k++ {
+			password = append(password, c.chars[g.intn(len(c.chars))])
+		}
+	}
+	g.shuffle(password)
+	return string(password), nil
+}
+
+// log2Big returns log2(x) for x > 0, also beyond the float64 range.
+func log2Big(x *big.Int) float64 {
+	shift := max(0, x.BitLen()-64)
+	top := new(big.Int).Rsh(x, uint(shift)).Uint64()
+	return float64(shift) + math.Log2(float64(top))
+}
+
+func uniqueRunes(s string) []rune {
+	seen := make(map[rune]bool)
+	var runes []rune
+	for _, r := range s {
+		if !seen[r] {
+			seen[r] = true
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+func removeRunes(chars []rune, remove string) []rune {
+	var kept []rune
+	for _, r := range chars {
+		if !strings.ContainsRune(remove, r) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,194 @@
+package crypto
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// excludeAllBut returns the letters and digits not in keep, so tests can
+// shrink the classes to a size that can be enumerated.
+func excludeAllBut(keep string) string {
+	var b strings.Builder
+	for _, r := range LowercaseChars + UppercaseChars + DigitChars {
+		if !strings.ContainsRune(keep, r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// meetsPolicy reports whether password is one the policy allows.
+func meetsPolicy(p PasswordPolicy, password string) bool {
+	runes := []rune(password)
+	if len(runes) != p.length() {
+		return false
+	}
+	classes := p.classes()
+	counts := make([]int, len(classes))
+	for _, r := range runes {
+		found := false
+		for i, c := range classes {
+			if strings.ContainsRune(string(c.chars), r) {
+				counts[i]++
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for i, c := range classes {
+		if counts[i] < c.min {
+			return false
+		}
+	}
+	return true
+}
+
+// bruteForceCount enumerates every string over the policy's characters and
+// counts those it allows.
+func bruteForceCount(p PasswordPolicy) int64 {
+	var alphabet []rune
+	for _, c := range p.classes() {
+		alphabet = append(alphabet, c.chars...)
+	}
+
+	var count int64
+	password := make([]rune, p.length())
+	var walk func(pos int)
+	walk = func(pos int) {
+		if pos == len(password) {
+			if meetsPolicy(p, string(password)) {
+				count++
+			}
+			return
+		}
+		for _, r := range alphabet {
+			password[pos] = r
+			walk(pos + 1)
+		}
+	}
+	walk(0)
+	return count
+}
+
+var smallPolicies = map[string]PasswordPolicy{
+	"no minimums":    {Length: 3, Symbols: "!@", Exclude: excludeAllBut("abAB01")},
+	"every class":    {Length: 4, MinLower: 1, MinUpper: 1, MinDigits: 1, MinSymbols: 1, Symbols: "!@", Exclude: excludeAllBut("abAB01")},
+	"two of a class": {Length: 4, MinDigits: 2, MinSymbols: 1, Symbols: "!", Exclude: excludeAllBut("abcA012")},
+	"class left out": {Length: 4, MinLower: 1, MinUpper: -1, MinDigits: 1, MinSymbols: -1, Exclude: excludeAllBut("abc012")},
+	"only one class": {Length: 5, MinLower: 2, MinUpper: -1, MinDigits: -1, MinSymbols: -1, Exclude: excludeAllBut("ab")},
+	"letter symbols": {Length: 3, MinSymbols: 1, Symbols: "a1#", Exclude: excludeAllBut("aA1")},
+	"ambiguous":      {Length: 3, MinUpper: 1, MinDigits: 1, ExcludeAmbiguous: true, Symbols: "-", Exclude: excludeAllBut("lIO01aB2")},
+	"capped length":  {Length: 20, MaxLength: 3, MinLower: 1, MinDigits: 1, Symbols: "!", Exclude: excludeAllBut("aA0")},
+	"exact minimums": {Length: 4, MinLower: 1, MinUpper: 1, MinDigits: 1, MinSymbols: 1, Symbols: "!", Exclude: excludeAllBut("aA0")},
+}
+
+func TestPolicySpaceMatchesBruteForce(t *testing.T) {
+	for name, p := range smallPolicies {
+		if err := p.Validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := bruteForceCount(p)
+		if _, total := p.space(); total.Cmp(big.NewInt(want)) != 0 {
+			t.Errorf("%s: space = %v, brute force = %d", name, total, want)
+		}
+		if got := p.Entropy(); math.Abs(got-math.Log2(float64(want))) > 1e-9 {
+			t.Errorf("%s: Entropy = %v, want log2(%d)", name, got, want)
+		}
+	}
+}
+
+func TestGeneratePolicyMeetsPolicy(t *testing.T) {
+	g := NewGenerator()
+	policies := map[string]PasswordPolicy{
+		"default":   DefaultPolicy(16),
+		"short":     DefaultPolicy(3),
+		"exclusion": {Length: 12, MinLower: 2, MinUpper: 2, MinDigits: 2, MinSymbols: 2, ExcludeAmbiguous: true, Exclude: "aeiou$"},
+		"no upper":  {Length: 8, MinUpper: -1, MinSymbols: 3, Symbols: "-_"},
+	}
+	for name, p := range smallPolicies {
+		policies[name] = p
+	}
+
+	for name, p := range policies {
+		for i := 0; i < 200; i++ {
+			password, err := g.GeneratePolicy(p)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !meetsPolicy(p, password) {
+				t.Fatalf("%s: %q does not meet the policy", name, password)
+			}
+			if strings.ContainsAny(password, p.Exclude) {
+				t.Fatalf("%s: %q uses an excluded character", name, password)
+			}
+			if p.ExcludeAmbiguous && strings.ContainsAny(password, AmbiguousChars) {
+				t.Fatalf("%s: %q uses an ambiguous character", name, password)
+			}
+		}
+	}
+}
+
+func TestGeneratePolicyIsUniform(t *testing.T) {
+	p := smallPolicies["exact minimums"]
+	want := bruteForceCount(p)
+
+	g := NewGenerator()
+	const draws = 24000
+	seen := make(map[string]int)
+	for i := 0; i < draws; i++ {
+		password, err := g.GeneratePolicy(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[password]++
+	}
+	if int64(len(seen)) != want {
+		t.Fatalf("saw %d distinct passwords, want %d", len(seen), want)
+	}
+	expected := float64(draws) / float64(want)
+	for password, n := range seen {
+		if math.Abs(float64(n)-expected) > expected/2 {
+			t.Errorf("%q drawn %d times, expected about %.0f", password, n, expected)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	for _, test := range []struct {
+		policy PasswordPolicy
+		err    string
+	}{
+		{PasswordPolicy{Length: 0}, "must be positive"},
+		{PasswordPolicy{Length: MaxPolicyLength + 1}, "cannot exceed"},
+		{PasswordPolicy{Length: 200, MaxLength: MaxPolicyLength}, ""},
+		{PasswordPolicy{Length: 8, MinLower: -1, MinUpper: -1, MinDigits: -1, MinSymbols: -1}, "every character class"},
+		{PasswordPolicy{Length: 8, Exclude: DigitChars}, "no digits are left"},
+		{PasswordPolicy{Length: 8, Symbols: "abc"}, "no symbols are left"},
+		{PasswordPolicy{Length: 8, Exclude: DigitChars, MinDigits: -1}, ""},
+		{PasswordPolicy{Length: 3, MinLower: 1, MinUpper: 1, MinDigits: 1, MinSymbols: 1}, "requires 4 characters but the length is 3"},
+		{PasswordPolicy{Length: 10, MaxLength: 3, MinLower: 2, MinDigits: 2}, "requires 4 characters but the length is 3"},
+	} {
+		err := test.policy.Validate()
+		if test.err == "" && err != nil {
+			t.Errorf("%+v: %v", test.policy, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%+v: err = %v, want %q", test.policy, err, test.err)
+		}
+		if err != nil && test.policy.Entropy() != 0 {
+			t.Errorf("%+v: invalid policy has entropy %v", test.policy, test.policy.Entropy())
+		}
+	}
+}
+
+func TestPolicyString(t *testing.T) {
+	p := PasswordPolicy{Length: 30, MaxLength: 20, MinLower: 1, MinUpper: -1, MinDigits: 2, Symbols: "-_", ExcludeAmbiguous: true, Exclude: "xyz"}
+	want := "20 characters, 1+ lowercase, no uppercase, 2+ digits, symbols -_, no ambiguous characters, excluding xyz"
+	if got := p.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package crypto
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Consonants and vowels for pronounceable passwords. Letters that are hard
+// to tell apart when spoken, like c/k and q, are left out.
+const (
+	pronounceableConsonants = "bdfghjklmnprstvz"
+	pronounceableVowels     = "aeiou"
+)
+
+// PronounceableOptions describes passwords made of consonant-vowel
+// syllables, such as "bamo-tefu-ridu", that can be read over the phone.
+type PronounceableOptions struct {
+	Syllables int
+	// Separator goes between every two syllables.
+	Separator string
+	// Capitalize makes the first letter upper-case, which adds no entropy.
+	Capitalize bool
+	// Digits appends this many random digits.
+	Digits int
+}
+
+// DefaultSyllables gives about 76 bits.
+const DefaultSyllables = 12
+
+func (o PronounceableOptions) Validate() error {
+	if o.Syllables <= 0 {
+		return fmt.Errorf("a pronounceable password needs at least one syllable")
+	}
+	if o.Digits < 0 {
+		return fmt.Errorf("the number of digits cannot be negative")
+	}
+	return nil
+}
+
+// Entropy is the exact entropy in bits of the passwords
+// GeneratePronounceable produces.
+func (o PronounceableOptions) Entropy() float64 {
+	perSyllable := math.Log2(float64(len(pronounceableConsonants) * len(pronounceableVowels)))
+	return float64(o.Syllables)*perSyllable + float64(o.Digits)*math.Log2(10)
+}
+
+func (g *Generator) GeneratePronounceable(o PronounceableOptions) (string, error) {
+	if err := o.Validate(); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i := 0; i < o.Syllables; i++ {
+		if i > 0 && i%2 == 0 {
+			b.WriteString(o.Separator)
+		}
+		b.WriteString(g.GenerateString(1, pronounceableConsonants))
+		b.WriteString(g.GenerateString(1, pronounceableVowels))
+	}
+	b.WriteString(g.GenerateString(o.Digits, DigitChars))
+
+	password := b.String()
+	if o.Capitalize {
+		password = capitalize(password)
+	}
+	return password, nil
+}
@@ -0,0 +1,98 @@
+package crypto
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// pronounceablePasswords lists every password GeneratePronounceable can
+// produce for o.
+func pronounceablePasswords(o PronounceableOptions) map[string]bool {
+	passwords := make(map[string]bool)
+	var walk func(prefix string, syllable int)
+	walk = func(prefix string, syllable int) {
+		if syllable == o.Syllables {
+			allPINs(o.Digits, func(digits string) {
+				passwords[prefix+digits] = true
+			})
+			return
+		}
+		if syllable > 0 && syllable%2 == 0 {
+			prefix += o.Separator
+		}
+		for _, c := range pronounceableConsonants {
+			for _, v := range pronounceableVowels {
+				walk(prefix+string(c)+string(v), syllable+1)
+			}
+		}
+	}
+	walk("", 0)
+	return passwords
+}
+
+func TestPronounceableEntropyMatchesBruteForce(t *testing.T) {
+	// Every combination of choices gives a different password, so the
+	// entropy is log2 of the number of passwords.
+	for _, o := range []PronounceableOptions{
+		{Syllables: 3, Separator: "-"},
+		{Syllables: 2, Digits: 2},
+	} {
+		n := len(pronounceablePasswords(o))
+		if got, want := o.Entropy(), math.Log2(float64(n)); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%+v: Entropy = %v, want log2(%d) = %v", o, got, n, want)
+		}
+	}
+	if bits := (PronounceableOptions{Syllables: DefaultSyllables}).Entropy(); bits < 75 || bits > 77 {
+		t.Errorf("default entropy = %v, want about 76", bits)
+	}
+}
+
+func TestGeneratePronounceable(t *testing.T) {
+	g := NewGenerator()
+	syllable := "[" + pronounceableConsonants + "][" + pronounceableVowels + "]"
+	for _, test := range []struct {
+		options PronounceableOptions
+		pattern string
+	}{
+		{PronounceableOptions{Syllables: 1}, "^" + syllable + "$"},
+		{PronounceableOptions{Syllables: 5, Separator: "-"}, "^(" + syllable + "){2}-(" + syllable + "){2}-" + syllable + "$"},
+		{PronounceableOptions{Syllables: 4, Digits: 3}, "^(" + syllable + "){4}[0-9]{3}$"},
+		{PronounceableOptions{Syllables: 2, Separator: ".", Capitalize: true}, "^[" + strings.ToUpper(pronounceableConsonants) + "][" + pronounceableVowels + "]" + syllable + "$"},
+	} {
+		re := regexp.MustCompile(test.pattern)
+		for i := 0; i < 100; i++ {
+			password, err := g.GeneratePronounceable(test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !re.MatchString(password) {
+				t.Fatalf("%+v: %q does not match %s", test.options, password, test.pattern)
+			}
+		}
+	}
+}
+
+func TestPronounceableValidate(t *testing.T) {
+	for _, test := range []struct {
+		options PronounceableOptions
+		err     string
+	}{
+		{PronounceableOptions{Syllables: DefaultSyllables}, ""},
+		{PronounceableOptions{Syllables: 0}, "at least one syllable"},
+		{PronounceableOptions{Syllables: 3, Digits: -2}, "cannot be negative"},
+	} {
+		err := test.options.Validate()
+		if test.err == "" && err != nil {
+			t.Errorf("%+v: %v", test.options, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%+v: err = %v, want %q", test.options, err, test.err)
+		}
+		if err != nil {
+			if _, genErr := NewGenerator().GeneratePronounceable(test.options); genErr == nil {
+				t.Errorf("%+v: generated a password for invalid options", test.options)
+			}
+		}
+	}
+}
@@ -1,49 +1,52 @@
 package crypto
 
 import (
-	"encoding/binary"
-	"time"
+	"crypto/rand"
+	"io"
+	"math/big"
 )
 
+// Generator produces random passwords from the operating system's secure
+// random source.
 type Generator struct {
-	seed uint64
+	rand io.Reader
 }
 
 func NewGenerator() *Generator {
-	return &Generator{seed: uint64(time.Now().UnixNano())}
-}
-
-func (g *Generator) next() uint64 {
-	g.seed = g.seed ^ (g.seed << 13)
-	g.seed = g.seed ^ (g.seed >> 7)
-	g.seed = g.seed ^ (g.seed << 17)
-	return g.seed
+	return &Generator{rand: rand.Reader}
 }
 
 func (g *Generator) GenerateBytes(length int) []byte {
 	bytes := make([]byte, length)
-	for i := 0; i < length; i += 8 {
-		val := g.next()
-		remaining := length - i
-		if remaining >= 8 {
-			binary.LittleEndian.PutUint64(bytes[i:], val)
-		} else {
-			temp := make([]byte, 8)
-			binary.LittleEndian.PutUint64(temp, val)
-			copy(bytes[i:], temp[:remaining])
-		}
+	if _, err := io.ReadFull(g.rand, bytes); err != nil {
+		panic("crypto: secure random source failed: " + err.Error())
 	}
 	return bytes
 }
 
-func (g *Generator) GenerateString(length int, charset string) string {
-	bytes := g.GenerateBytes(length)
-	result := make([]byte, length)
-	charsetLen := len(charset)
-
-	for i := 0; i < length; i++ {
-		result[i] = charset[bytes[i]%byte(charsetLen)]
+// intn returns a uniform random number in [0, n).
+func (g *Generator) intn(n int) int {
+	v, err := rand.Int(g.rand, big.NewInt(int64(n)))
+	if err != nil {
+		panic("crypto: secure random source failed: " + err.Error())
 	}
+	return int(v.Int64())
+}
 
+// GenerateString picks length characters from charset uniformly.
+func (g *Generator) GenerateString(length int, charset string) string {
+	chars := []rune(charset)
+	result := make([]rune, length)
+	for i := range result {
+		result[i] = chars[g.intn(len(chars))]
+	}
 	return string(result)
 }
+
+// shuffle permutes s uniformly (Fisher-Yates).
+func (g *Generator) shuffle(s []rune) {
+	for i := len(s) - 1; i > 0; i-- {
+		j := g.intn(i + 1)
+		s[i], s[j] = s[j], s[i]
+	}
+}
@@ -0,0 +1,212 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+type Share struct {
+	Threshold int
+	X         byte
+	Data      []byte
+}
+
+var gfExp [512]byte
+var gfLog [256]byte
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		gfExp[i] = x
+		gfLog[x] = byte(i)
+		x = gfMulSlow(x, 3)
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMulSlow(a, b byte) byte {
+	var p byte
+	for b > 0 {
+		if b&1 != 0 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+func Split(secret []byte, shares, threshold int) ([]Share, error) {
+	if threshold < 2 {
+		return nil, fmt.Errorf("threshold must be at least 2")
+	}
+	if shares < threshold {
+		return nil, fmt.Errorf("number of shares must be at least the threshold")
+	}
+	if shares > 255 {
+		return nil, fmt.Errorf("at most 255 shares are supported")
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("secret cannot be empty")
+	}
+
+	result := make([]Share, shares)
+	for i := range result {
+		result[i] = Share{
+			Threshold: threshold,
+			X:         byte(i + 1),
+			Data:      make([]byte, len(secret)),
+		}
+	}
+
+	coeffs := make([]byte, threshold)
+	for pos, b := range secret {
+		coeffs[0] = b
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+
+		for i := range result {
+			x := result[i].X
+			var y byte
+			for j := threshold - 1; j >= 0; j-- {
+				y = gfMul(y, x) ^ coeffs[j]
+			}
+			result[i].Data[pos] = y
+		}
+	}
+
+	return result, nil
+}
+
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, fmt.Errorf("no shares provided")
+	}
+
+	threshold := shares[0].Threshold
+	if threshold < 2 {
+		return nil, fmt.Errorf("invalid share threshold %d", threshold)
+	}
+	if len(shares) < threshold {
+		return nil, fmt.Errorf("need %d shares, got %d", threshold, len(shares))
+	}
+	shares = shares[:threshold]
+
+	size := len(shares[0].Data)
+	seen := make(map[byte]bool)
+	for _, s := range shares {
+		if s.Threshold != threshold || len(s.Data) != size {
+			return nil, fmt.Errorf("shares do not belong to the same set")
+		}
+		if s.X == 0 || seen[s.X] {
+			return nil, fmt.Errorf("duplicate or invalid share %d", s.X)
+		}
+		seen[s.X] = true
+	}
+
+	secret := make([]byte, size)
+	for pos := 0; pos < size; pos++ {
+		var value byte
+		for i, si := range shares {
+			basis := byte(1)
+			for j, sj := range shares {
+				if i == j {
+					continue
+				}
+				basis = gfMul(basis, gfDiv(sj.X, sj.X^si.X))
+			}
+			value ^= gfMul(si.Data[pos], basis)
+		}
+		secret[pos] = value
+	}
+
+	return secret, nil
+}
+
+func (s Share) bytes() []byte {
+	raw := append([]byte{byte(s.Threshold), s.X}, s.Data...)
+	sum := sha256.Sum256(raw)
+	return append(raw, sum[:2]...)
+}
+
+func shareFromBytes(raw []byte) (Share, error) {
+	if len(raw) < 5 {
+		return Share{}, fmt.Errorf("share is too short")
+	}
+	body, check := raw[:len(raw)-2], raw[len(raw)-2:]
+	sum := sha256.Sum256(body)
+	if sum[0] != check[0] || sum[1] != check[1] {
+		return Share{}, fmt.Errorf("share checksum mismatch")
+	}
+	if body[0] < 2 {
+		return Share{}, fmt.Errorf("invalid share threshold %d", body[0])
+	}
+	if body[1] == 0 {
+		return Share{}, fmt.Errorf("invalid share number 0")
+	}
+	return Share{
+		Threshold: int(body[0]),
+		X:         body[1],
+		Data:      append([]byte{}, body[2:]...),
+	}, nil
+}
+
+func (s Share) String() string {
+	return "pwv-" + hex.EncodeToString(s.bytes())
+}
+
+func (s Share) Mnemonic() string {
+	raw := s.bytes()
+	words := make([]string, len(raw))
+	for i, b := range raw {
+		words[i] = shareWords[b]
+	}
+	return strings.Join(words, " ")
+}
+
+func ParseShare(text string) (Share, error) {
+	text = strings.TrimSpace(strings.ToLower(text))
+
+	if strings.HasPrefix(text, "pwv-") {
+		raw, err := hex.DecodeString(strings.TrimPrefix(text, "pwv-"))
+		if err != nil {
+			return Share{}, fmt.Errorf("invalid share encoding: %v", err)
+		}
+		return shareFromBytes(raw)
+	}
+
+	fields := strings.Fields(text)
+	raw := make([]byte, len(fields))
+	for i, word := range fields {
+		b, ok := shareWordIndex[word]
+		if !ok {
+			return Share{}, fmt.Errorf("unknown share word %q", word)
+		}
+		raw[i] = b
+	}
+	return shareFromBytes(raw)
+}
@@ -0,0 +1,59 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pick := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}} {
+		var subset []Share
+		for _, i := range pick {
+			parsed, err := ParseShare(shares[i].Mnemonic())
+			if err != nil {
+				t.Fatal(err)
+			}
+			subset = append(subset, parsed)
+		}
+		got, err := Combine(subset)
+		if err != nil || !bytes.Equal(got, secret) {
+			t.Errorf("shares %v: got %q, %v", pick, got, err)
+		}
+	}
+
+	if _, err := Combine([]Share{shares[0], shares[0], shares[1]}); err == nil {
+		t.Error("combined a duplicate share")
+	}
+}
+
+// encodeShare builds a share string with a valid checksum around raw bytes.
+func encodeShare(body ...byte) string {
+	sum := sha256.Sum256(body)
+	return "pwv-" + hex.EncodeToString(append(body, sum[:2]...))
+}
+
+func TestMalformedShares(t *testing.T) {
+	for name, text := range map[string]string{
+		"threshold 0": encodeShare(0, 1, 0xaa),
+		"threshold 1": encodeShare(1, 1, 0xaa),
+		"share 0":     encodeShare(2, 0, 0xaa),
+	} {
+		if _, err := ParseShare(text); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+
+	for _, threshold := range []int{0, 1, -1} {
+		if _, err := Combine([]Share{{Threshold: threshold, X: 1, Data: []byte{1}}}); err == nil {
+			t.Errorf("threshold %d: combined without error", threshold)
+		}
+	}
+}
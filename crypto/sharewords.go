@@ -0,0 +1,44 @@
+package crypto
+
+var shareWords = [256]string{
+	"acid", "acorn", "actor", "adult", "agent", "alarm", "album", "alert",
+	"alley", "amber", "angle", "ankle", "apple", "april", "apron", "arena",
+	"argue", "armor", "arrow", "atlas", "attic", "audio", "autumn", "avoid",
+	"awake", "badge", "bagel", "baker", "bamboo", "banjo", "barn", "basil",
+	"basin", "beach", "beard", "bench", "berry", "bike", "bison", "blade",
+	"blanket", "blaze", "blend", "bloom", "board", "boat", "bonus", "boost",
+	"bottle", "brain", "brave", "bread", "brick", "bridge", "brush", "bucket",
+	"buffalo", "bugle", "cabin", "cable", "cactus", "camel", "candle", "canoe",
+	"canyon", "carbon", "cargo", "carpet", "carrot", "castle", "cedar", "cellar",
+	"chalk", "cherry", "chess", "chief", "cider", "cinema", "circus", "citrus",
+	"clay", "cliff", "clock", "cloud", "clover", "coast", "cobalt", "cocoa",
+	"comet", "coral", "cotton", "cougar", "crane", "crater", "crayon", "cricket",
+	"crown", "cubic", "cycle", "daisy", "dance", "delta", "denim", "desert",
+	"diesel", "dinner", "dolphin", "donkey", "dragon", "drum", "eagle", "easel",
+	"echo", "eclipse", "elbow", "ember", "empire", "engine", "fabric", "falcon",
+	"feather", "fence", "ferry", "fiber", "fiddle", "flame", "flute", "forest",
+	"fossil", "fox", "frost", "galaxy", "garden", "garlic", "gecko", "geyser",
+	"ginger", "glacier", "globe", "goblet", "gold", "gorilla", "grape", "gravel",
+	"guitar", "hammer", "harbor", "harvest", "hazel", "helmet", "hermit", "honey",
+	"hornet", "hotel", "hunter", "icicle", "igloo", "island", "ivory", "jacket",
+	"jaguar", "jelly", "jigsaw", "jungle", "kayak", "kernel", "kettle", "kiwi",
+	"koala", "ladder", "lagoon", "lantern", "laser", "lemon", "lentil", "lilac",
+	"lizard", "lobster", "locket", "lotus", "magnet", "mango", "maple", "marble",
+	"meadow", "melon", "mirror", "mitten", "monkey", "mosaic", "muffin", "nectar",
+	"needle", "nickel", "noodle", "nutmeg", "oasis", "ocean", "olive", "onion",
+	"opera", "orbit", "orchid", "otter", "oyster", "paddle", "panda", "parrot",
+	"peanut", "pebble", "pepper", "piano", "pickle", "pillow", "pilot", "plaza",
+	"plum", "pocket", "polar", "pony", "poppy", "prism", "pumpkin", "puzzle",
+	"quartz", "quill", "rabbit", "radar", "radish", "raven", "reef", "ribbon",
+	"river", "robot", "rocket", "saddle", "salmon", "sandal", "satin", "scarf",
+	"shovel", "silver", "sketch", "sled", "spider", "sponge", "squid", "stamp",
+	"stove", "sugar", "summit", "sunset", "swan", "syrup", "tablet", "tango",
+}
+
+var shareWordIndex = func() map[string]byte {
+	index := make(map[string]byte, len(shareWords))
+	for i, word := range shareWords {
+		index[word] = byte(i)
+	}
+	return index
+}()
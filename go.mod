@@ -1,3 +1,11 @@
 module pw
 
 go 1.21
+
+require (
+	filippo.io/age v1.2.1
+	golang.org/x/crypto v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.21.0 // indirect
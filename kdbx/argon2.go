@@ -0,0 +1,304 @@
+package kdbx
+
+import (
+	"encoding/binary"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// golang.org/x/crypto/argon2 only exposes Argon2i and Argon2id, but KeePass
+// databases default to Argon2d, so the core algorithm lives here.
+
+const (
+	argon2d  = 0
+	argon2id = 2
+
+	argon2Version10 = 0x10
+	argon2Version13 = 0x13
+
+	argon2BlockLength = 128
+	argon2SyncPoints  = 4
+)
+
+type argon2Block [argon2BlockLength]uint64
+
+type argon2Params struct {
+	mode        int
+	version     uint32
+	iterations  uint32
+	memoryKiB   uint32
+	parallelism uint32
+	salt        []byte
+	secret      []byte
+	assocData   []byte
+}
+
+func argon2Key(password []byte, p argon2Params, keyLen uint32) []byte {
+	if p.parallelism < 1 {
+		p.parallelism = 1
+	}
+	if p.iterations < 1 {
+		p.iterations = 1
+	}
+	if p.version == 0 {
+		p.version = argon2Version13
+	}
+
+	h0 := argon2InitHash(password, p, keyLen)
+
+	memory := p.memoryKiB / (argon2SyncPoints * p.parallelism) * (argon2SyncPoints * p.parallelism)
+	if memory < 2*argon2SyncPoints*p.parallelism {
+		memory = 2 * argon2SyncPoints * p.parallelism
+	}
+
+	B := argon2InitBlocks(&h0, memory, p.parallelism)
+	argon2ProcessBlocks(B, p, memory)
+	return argon2ExtractKey(B, memory, p.parallelism, keyLen)
+}
+
+func argon2InitHash(password []byte, p argon2Params, keyLen uint32) [blake2b.Size + 8]byte {
+	var h0 [blake2b.Size + 8]byte
+	var params [24]byte
+	var tmp [4]byte
+
+	b2, _ := blake2b.New512(nil)
+	binary.LittleEndian.PutUint32(params[0:4], p.parallelism)
+	binary.LittleEndian.PutUint32(params[4:8], keyLen)
+	binary.LittleEndian.PutUint32(params[8:12], p.memoryKiB)
+	binary.LittleEndian.PutUint32(params[12:16], p.iterations)
+	binary.LittleEndian.PutUint32(params[16:20], p.version)
+	binary.LittleEndian.PutUint32(params[20:24], uint32(p.mode))
+	b2.Write(params[:])
+
+	for _, input := range [][]byte{password, p.salt, p.secret, p.assocData} {
+		binary.LittleEndian.PutUint32(tmp[:], uint32(len(input)))
+		b2.Write(tmp[:])
+		b2.Write(input)
+	}
+
+	b2.Sum(h0[:0])
+	return h0
+}
+
+func argon2InitBlocks(h0 *[blake2b.Size + 8]byte, memory, threads uint32) []argon2Block {
+	var block0 [1024]byte
+	B := make([]argon2Block, memory)
+
+	for lane := uint32(0); lane < threads; lane++ {
+		j := lane * (memory / threads)
+		binary.LittleEndian.PutUint32(h0[blake2b.Size+4:], lane)
+
+		for k := uint32(0); k < 2; k++ {
+			binary.LittleEndian.PutUint32(h0[blake2b.Size:], k)
+			argon2Hash(block0[:], h0[:])
+			for i := range B[j+k] {
+				B[j+k][i] = binary.LittleEndian.Uint64(block0[i*8:])
+			}
+		}
+	}
+
+	return B
+}
+
+func argon2ProcessBlocks(B []argon2Block, p argon2Params, memory uint32) {
+	lanes := memory / p.parallelism
+	segments := lanes / argon2SyncPoints
+
+	processSegment := func(n, slice, lane uint32) {
+		var addresses, in, zero argon2Block
+		dataIndependent := p.mode == argon2id && n == 0 && slice < argon2SyncPoints/2
+
+		if dataIndependent {
+			in[0] = uint64(n)
+			in[1] = uint64(lane)
+			in[2] = uint64(slice)
+			in[3] = uint64(memory)
+			in[4] = uint64(p.iterations)
+			in[5] = uint64(p.mode)
+		}
+
+		index := uint32(0)
+		if n == 0 && slice == 0 {
+			index = 2
+			if dataIndependent {
+				in[6]++
+				argon2ProcessBlock(&addresses, &in, &zero, false)
+				argon2ProcessBlock(&addresses, &addresses, &zero, false)
+			}
+		}
+
+		offset := lane*lanes + slice*segments + index
+		for index < segments {
+			prev := offset - 1
+			if index == 0 && slice == 0 {
+				prev += lanes
+			}
+
+			var random uint64
+			if dataIndependent {
+				if index%argon2BlockLength == 0 {
+					in[6]++
+					argon2ProcessBlock(&addresses, &in, &zero, false)
+					argon2ProcessBlock(&addresses, &addresses, &zero, false)
+				}
+				random = addresses[index%argon2BlockLength]
+			} else {
+				random = B[prev][0]
+			}
+
+			ref := argon2IndexAlpha(random, lanes, segments, p.parallelism, n, slice, lane, index)
+			xor := n > 0 && p.version == argon2Version13
+			argon2ProcessBlock(&B[offset], &B[prev], &B[ref], xor)
+			index, offset = index+1, offset+1
+		}
+	}
+
+	for n := uint32(0); n < p.iterations; n++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			for lane := uint32(0); lane < p.parallelism; lane++ {
+				processSegment(n, slice, lane)
+			}
+		}
+	}
+}
+
+func argon2ExtractKey(B []argon2Block, memory, threads, keyLen uint32) []byte {
+	lanes := memory / threads
+	for lane := uint32(0); lane < threads-1; lane++ {
+		for i, v := range B[(lane*lanes)+lanes-1] {
+			B[memory-1][i] ^= v
+		}
+	}
+
+	var block [1024]byte
+	for i, v := range B[memory-1] {
+		binary.LittleEndian.PutUint64(block[i*8:], v)
+	}
+
+	key := make([]byte, keyLen)
+	argon2Hash(key, block[:])
+	return key
+}
+
+func argon2IndexAlpha(rand uint64, lanes, segments, threads, n, slice, lane, index uint32) uint32 {
+	refLane := uint32(rand>>32) % threads
+	if n == 0 && slice == 0 {
+		refLane = lane
+	}
+
+	m, s := 3*segments, ((slice+1)%argon2SyncPoints)*segments
+	if lane == refLane {
+		m += index
+	}
+	if n == 0 {
+		m, s = slice*segments, 0
+		if slice == 0 || lane == refLane {
+			m += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		m--
+	}
+
+	x := rand & 0xFFFFFFFF
+	x = (x * x) >> 32
+	x = (x * uint64(m)) >> 32
+	return refLane*lanes + uint32((uint64(s)+uint64(m)-(x+1))%uint64(lanes))
+}
+
+func argon2ProcessBlock(out, in1, in2 *argon2Block, xor bool) {
+	var t argon2Block
+	for i := range t {
+		t[i] = in1[i] ^ in2[i]
+	}
+
+	for i := 0; i < argon2BlockLength; i += 16 {
+		blamka(&t[i+0], &t[i+1], &t[i+2], &t[i+3],
+			&t[i+4], &t[i+5], &t[i+6], &t[i+7],
+			&t[i+8], &t[i+9], &t[i+10], &t[i+11],
+			&t[i+12], &t[i+13], &t[i+14], &t[i+15])
+	}
+	for i := 0; i < argon2BlockLength/8; i += 2 {
+		blamka(&t[i], &t[i+1], &t[16+i], &t[16+i+1],
+			&t[32+i], &t[32+i+1], &t[48+i], &t[48+i+1],
+			&t[64+i], &t[64+i+1], &t[80+i], &t[80+i+1],
+			&t[96+i], &t[96+i+1], &t[112+i], &t[112+i+1])
+	}
+
+	if xor {
+		for i := range t {
+			out[i] ^= in1[i] ^ in2[i] ^ t[i]
+		}
+	} else {
+		for i := range t {
+			out[i] = in1[i] ^ in2[i] ^ t[i]
+		}
+	}
+}
+
+func blamkaG(a, b, c, d *uint64) {
+	*a += *b + 2*uint64(uint32(*a))*uint64(uint32(*b))
+	*d ^= *a
+	*d = *d>>32 | *d<<32
+	*c += *d + 2*uint64(uint32(*c))*uint64(uint32(*d))
+	*b ^= *c
+	*b = *b>>24 | *b<<40
+	*a += *b + 2*uint64(uint32(*a))*uint64(uint32(*b))
+	*d ^= *a
+	*d = *d>>16 | *d<<48
+	*c += *d + 2*uint64(uint32(*c))*uint64(uint32(*d))
+	*b ^= *c
+	*b = *b>>63 | *b<<1
+}
+
+func blamka(t00, t01, t02, t03, t04, t05, t06, t07, t08, t09, t10, t11, t12, t13, t14, t15 *uint64) {
+	blamkaG(t00, t04, t08, t12)
+	blamkaG(t01, t05, t09, t13)
+	blamkaG(t02, t06, t10, t14)
+	blamkaG(t03, t07, t11, t15)
+
+	blamkaG(t00, t05, t10, t15)
+	blamkaG(t01, t06, t11, t12)
+	blamkaG(t02, t07, t08, t13)
+	blamkaG(t03, t04, t09, t14)
+}
+
+func argon2Hash(out []byte, in []byte) {
+	var b2 hash.Hash
+	if n := len(out); n < blake2b.Size {
+		b2, _ = blake2b.New(n, nil)
+	} else {
+		b2, _ = blake2b.New512(nil)
+	}
+
+	var buffer [blake2b.Size]byte
+	binary.LittleEndian.PutUint32(buffer[:4], uint32(len(out)))
+	b2.Write(buffer[:4])
+	b2.Write(in)
+
+	if len(out) <= blake2b.Size {
+		b2.Sum(out[:0])
+		return
+	}
+
+	outLen := len(out)
+	b2.Sum(buffer[:0])
+	b2.Reset()
+	copy(out, buffer[:32])
+	out = out[32:]
+	for len(out) > blake2b.Size {
+		b2.Write(buffer[:])
+		b2.Sum(buffer[:0])
+		copy(out, buffer[:32])
+		out = out[32:]
+		b2.Reset()
+	}
+
+	if outLen%blake2b.Size > 0 {
+		r := ((outLen + 31) / 32) - 2
+		b2, _ = blake2b.New(outLen-32*r, nil)
+	}
+	b2.Write(buffer[:])
+	b2.Sum(out[:0])
+}
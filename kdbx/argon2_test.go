@@ -0,0 +1,65 @@
+package kdbx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// rfc9106Params are the inputs shared by the test vectors in RFC 9106
+// section 5.
+func rfc9106Params(mode int) ([]byte, argon2Params) {
+	return bytes.Repeat([]byte{0x01}, 32), argon2Params{
+		mode:        mode,
+		version:     argon2Version13,
+		iterations:  3,
+		memoryKiB:   32,
+		parallelism: 4,
+		salt:        bytes.Repeat([]byte{0x02}, 16),
+		secret:      bytes.Repeat([]byte{0x03}, 8),
+		assocData:   bytes.Repeat([]byte{0x04}, 12),
+	}
+}
+
+func TestArgon2RFC9106Vectors(t *testing.T) {
+	tests := []struct {
+		name string
+		mode int
+		tag  string
+	}{
+		{"Argon2d", argon2d, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb"},
+		{"Argon2id", argon2id, "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			password, params := rfc9106Params(tt.mode)
+			got := hex.EncodeToString(argon2Key(password, params, 32))
+			if got != tt.tag {
+				t.Errorf("tag = %s, want %s", got, tt.tag)
+			}
+		})
+	}
+}
+
+func TestArgon2idMatchesXCrypto(t *testing.T) {
+	password, salt := []byte("correct horse"), []byte("saltsaltsaltsalt")
+	for _, params := range []struct{ time, memory uint32 }{{1, 64}, {2, 256}, {3, 1024}} {
+		for _, threads := range []uint8{1, 2, 4} {
+			want := argon2.IDKey(password, salt, params.time, params.memory, threads, 32)
+			got := argon2Key(password, argon2Params{
+				mode:        argon2id,
+				version:     argon2Version13,
+				iterations:  params.time,
+				memoryKiB:   params.memory,
+				parallelism: uint32(threads),
+				salt:        salt,
+			}, 32)
+			if !bytes.Equal(got, want) {
+				t.Errorf("t=%d m=%d p=%d: got %x, want %x", params.time, params.memory, threads, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package kdbx
+
+import (
+	"crypto/aes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	signature1 uint32 = 0x9AA2D903
+	signature2 uint32 = 0xB54BFB67
+
+	majorVersion4 = 4
+)
+
+var (
+	cipherAES256   = mustUUID("31c1f2e6bf714350be5805216afc5aff")
+	cipherChaCha20 = mustUUID("d6038a2b8b6f4cb5a524339a31dbb59a")
+
+	kdfAES      = mustUUID("c9d9f39a628a4460bf740d08c18a4fea")
+	kdfArgon2d  = mustUUID("ef636ddf8c29444b91f7a9a403e30a0c")
+	kdfArgon2id = mustUUID("9e298b1956db4773b23dfc3ec6f0a1e6")
+
+	ErrInvalidCredentials = errors.New("invalid password or key file")
+)
+
+const (
+	headerEnd            = 0
+	headerCipherID       = 2
+	headerCompression    = 3
+	headerMasterSeed     = 4
+	headerEncryptionIV   = 7
+	headerKdfParameters  = 11
+	headerPublicCustData = 12
+
+	innerHeaderEnd       = 0
+	innerHeaderStreamID  = 1
+	innerHeaderStreamKey = 2
+	innerHeaderBinary    = 3
+
+	streamSalsa20  = 2
+	streamChaCha20 = 3
+)
+
+type UUID [16]byte
+
+type Database struct {
+	Name           string
+	RecycleBinUUID UUID
+	Root           *Group
+}
+
+type Group struct {
+	UUID    UUID
+	Name    string
+	Groups  []*Group
+	Entries []*Entry
+}
+
+type Entry struct {
+	UUID        UUID
+	Fields      []Field
+	Attachments []Attachment
+	Tags        []string
+	Created     time.Time
+	Modified    time.Time
+	Expires     bool
+	ExpiryTime  time.Time
+	History     []*Entry
+}
+
+type Field struct {
+	Key       string
+	Value     string
+	Protected bool
+}
+
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+type Credentials struct {
+	Password string
+	KeyFile  []byte
+}
+
+func (e *Entry) Get(key string) string {
+	for _, f := range e.Fields {
+		if f.Key == key {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func IsStandardField(key string) bool {
+	switch key {
+	case "Title", "UserName", "Password", "URL", "Notes":
+		return true
+	}
+	return false
+}
+
+func mustUUID(s string) UUID {
+	var u UUID
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(u) {
+		panic("invalid uuid " + s)
+	}
+	copy(u[:], b)
+	return u
+}
+
+func (c Credentials) compositeKey() ([]byte, error) {
+	h := sha256.New()
+
+	if c.Password != "" || c.KeyFile == nil {
+		sum := sha256.Sum256([]byte(c.Password))
+		h.Write(sum[:])
+	}
+
+	if c.KeyFile != nil {
+		key, err := keyFileKey(c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		h.Write(key)
+	}
+
+	return h.Sum(nil), nil
+}
+
+func keyFileKey(data []byte) ([]byte, error) {
+	trimmed := strings.TrimSpace(string(data))
+
+	if strings.HasPrefix(trimmed, "<?xml") || strings.HasPrefix(trimmed, "<KeyFile") {
+		var keyFile struct {
+			Meta struct {
+				Version string `xml:"Version"`
+			} `xml:"Meta"`
+			Key struct {
+				Data string `xml:"Data"`
+			} `xml:"Key"`
+		}
+		if err := xml.Unmarshal(data, &keyFile); err != nil {
+			return nil, fmt.Errorf("invalid key file: %v", err)
+		}
+
+		value := strings.Join(strings.Fields(keyFile.Key.Data), "")
+		if strings.HasPrefix(keyFile.Meta.Version, "2.") {
+			return hex.DecodeString(value)
+		}
+		return decodeBase64(value)
+	}
+
+	if len(data) == 32 {
+		return data, nil
+	}
+	if len(data) == 64 {
+		if key, err := hex.DecodeString(string(data)); err == nil {
+			return key, nil
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+type kdfParams struct {
+	uuid   UUID
+	rounds uint64
+	seed   []byte
+	argon  argon2Params
+}
+
+// Limits on the key derivation parameters in a database header. The header
+// is read before the password is checked, so without them a crafted file
+// could exhaust memory or never finish opening. KeePassXC creates databases
+// with 64 MiB of Argon2 memory and a few dozen iterations, so the Argon2
+// limits leave room for slower settings without allowing minutes of work.
+const (
+	maxAESRounds         = 1 << 30
+	minArgon2SaltLength  = 8
+	maxArgon2MemoryKiB   = 1 << 20
+	maxArgon2Iterations  = 100
+	maxArgon2Parallelism = 256
+)
+
+func parseKdfParams(dict variantDictionary) (kdfParams, error) {
+	var p kdfParams
+	copy(p.uuid[:], dict.bytes("$UUID"))
+
+	switch p.uuid {
+	case kdfAES:
+		p.rounds = dict.uint64("R")
+		p.seed = dict.bytes("S")
+		if len(p.seed) != 32 {
+			return p, fmt.Errorf("invalid AES-KDF seed")
+		}
+		if p.rounds > maxAESRounds {
+			return p, fmt.Errorf("AES-KDF rounds %d exceed the limit of %d", p.rounds, maxAESRounds)
+		}
+	case kdfArgon2d, kdfArgon2id:
+		version := dict.uint32("V")
+		iterations := dict.uint64("I")
+		memoryKiB := dict.uint64("M") / 1024
+		parallelism := dict.uint32("P")
+		salt := dict.bytes("S")
+
+		switch {
+		case version != argon2Version10 && version != argon2Version13:
+			return p, fmt.Errorf("unsupported Argon2 version %#x", version)
+		case parallelism < 1 || parallelism > maxArgon2Parallelism:
+			return p, fmt.Errorf("Argon2 parallelism %d is outside 1-%d", parallelism, maxArgon2Parallelism)
+		case iterations < 1 || iterations > maxArgon2Iterations:
+			return p, fmt.Errorf("Argon2 iterations %d are outside 1-%d", iterations, maxArgon2Iterations)
+		case memoryKiB < 2*argon2SyncPoints*uint64(parallelism) || memoryKiB > maxArgon2MemoryKiB:
+			return p, fmt.Errorf("Argon2 memory %d KiB is outside %d-%d KiB", memoryKiB, 2*argon2SyncPoints*parallelism, maxArgon2MemoryKiB)
+		case len(salt) < minArgon2SaltLength:
+			return p, fmt.Errorf("Argon2 salt is shorter than %d bytes", minArgon2SaltLength)
+		}
+
+		p.argon = argon2Params{
+			mode:        argon2d,
+			version:     version,
+			iterations:  uint32(iterations),
+			memoryKiB:   uint32(memoryKiB),
+			parallelism: parallelism,
+			salt:        salt,
+			secret:      dict.bytes("K"),
+			assocData:   dict.bytes("A"),
+		}
+		if p.uuid == kdfArgon2id {
+			p.argon.mode = argon2id
+		}
+	default:
+		return p, fmt.Errorf("unsupported key derivation function")
+	}
+
+	return p, nil
+}
+
+func (p kdfParams) transform(composite []byte) ([]byte, error) {
+	if p.uuid == kdfAES {
+		block, err := aes.NewCipher(p.seed)
+		if err != nil {
+			return nil, err
+		}
+
+		key := append([]byte{}, composite...)
+		for i := uint64(0); i < p.rounds; i++ {
+			block.Encrypt(key[0:16], key[0:16])
+			block.Encrypt(key[16:32], key[16:32])
+		}
+		sum := sha256.Sum256(key)
+		return sum[:], nil
+	}
+
+	return argon2Key(composite, p.argon, 32), nil
+}
+
+type derivedKeys struct {
+	cipherKey []byte
+	hmacKey   []byte
+}
+
+func deriveKeys(masterSeed, transformed []byte) derivedKeys {
+	cipherKey := sha256.Sum256(append(append([]byte{}, masterSeed...), transformed...))
+
+	h := sha512.New()
+	h.Write(masterSeed)
+	h.Write(transformed)
+	h.Write([]byte{1})
+
+	return derivedKeys{cipherKey: cipherKey[:], hmacKey: h.Sum(nil)}
+}
+
+func (k derivedKeys) blockKey(index uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], index)
+
+	h := sha512.New()
+	h.Write(buf[:])
+	h.Write(k.hmacKey)
+	return h.Sum(nil)
+}
+
+// KeePass stores KDBX 4 timestamps as base64-encoded seconds since year 1.
+const kdbxEpochOffset = 62135596800
+
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+
+	raw, err := decodeBase64(value)
+	if err != nil || len(raw) != 8 {
+		return time.Time{}
+	}
+	seconds := int64(binary.LittleEndian.Uint64(raw))
+	return time.Unix(seconds-kdbxEpochOffset, 0).UTC()
+}
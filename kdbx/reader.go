@@ -0,0 +1,540 @@
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// maxInflatedSize bounds the decompressed payload, so a small file cannot
+// inflate to fill memory. The tests lower it.
+var maxInflatedSize int64 = 256 << 20
+
+func IsKDBX(prefix []byte) bool {
+	return len(prefix) >= 8 &&
+		binary.LittleEndian.Uint32(prefix[0:4]) == signature1 &&
+		binary.LittleEndian.Uint32(prefix[4:8]) == signature2
+}
+
+func Open(r io.Reader, creds Credentials) (*Database, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsKDBX(data) || len(data) < 12 {
+		return nil, fmt.Errorf("not a KeePass database")
+	}
+	minor := binary.LittleEndian.Uint16(data[8:10])
+	major := binary.LittleEndian.Uint16(data[10:12])
+	if major != majorVersion4 {
+		return nil, fmt.Errorf("unsupported KDBX version %d.%d, only KDBX 4 is supported", major, minor)
+	}
+
+	header, pos, err := readOuterHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < pos+64 {
+		return nil, fmt.Errorf("truncated database header")
+	}
+
+	headerBytes := data[:pos]
+	headerHash := sha256.Sum256(headerBytes)
+	if !hmac.Equal(headerHash[:], data[pos:pos+32]) {
+		return nil, fmt.Errorf("database header is corrupted")
+	}
+
+	composite, err := creds.compositeKey()
+	if err != nil {
+		return nil, err
+	}
+	transformed, err := header.kdf.transform(composite)
+	if err != nil {
+		return nil, err
+	}
+	keys := deriveKeys(header.masterSeed, transformed)
+
+	if !hmac.Equal(headerHMAC(keys, headerBytes), data[pos+32:pos+64]) {
+		return nil, ErrInvalidCredentials
+	}
+
+	payload, err := readHMACBlocks(data[pos+64:], keys)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := decryptPayload(header, keys.cipherKey, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.compressed {
+		gz, err := gzip.NewReader(bytes.NewReader(plain))
+		if err != nil {
+			return nil, err
+		}
+		plain, err = io.ReadAll(io.LimitReader(gz, maxInflatedSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(plain)) > maxInflatedSize {
+			return nil, fmt.Errorf("database inflates to more than %d MiB", maxInflatedSize>>20)
+		}
+	}
+
+	inner, xmlStart, err := readInnerHeader(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := parseXML(plain[xmlStart:])
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := inner.stream()
+	if err != nil {
+		return nil, err
+	}
+	if err := unprotect(root, stream); err != nil {
+		return nil, err
+	}
+
+	return buildDatabase(root, inner.binaries)
+}
+
+type outerHeader struct {
+	cipherID   UUID
+	compressed bool
+	masterSeed []byte
+	iv         []byte
+	kdf        kdfParams
+}
+
+func readOuterHeader(data []byte) (outerHeader, int, error) {
+	var h outerHeader
+	pos := 12
+
+	for {
+		if len(data) < pos+5 {
+			return h, 0, fmt.Errorf("truncated database header")
+		}
+		id := data[pos]
+		size := int(binary.LittleEndian.Uint32(data[pos+1 : pos+5]))
+		pos += 5
+		if size < 0 || len(data) < pos+size {
+			return h, 0, fmt.Errorf("truncated database header")
+		}
+		value := data[pos : pos+size]
+		pos += size
+
+		switch id {
+		case headerEnd:
+			if h.masterSeed == nil || h.iv == nil || h.kdf.uuid == (UUID{}) {
+				return h, 0, fmt.Errorf("database header is missing required fields")
+			}
+			return h, pos, nil
+		case headerCipherID:
+			copy(h.cipherID[:], value)
+		case headerCompression:
+			h.compressed = len(value) >= 4 && binary.LittleEndian.Uint32(value) == 1
+		case headerMasterSeed:
+			h.masterSeed = value
+		case headerEncryptionIV:
+			h.iv = value
+		case headerKdfParameters:
+			dict, err := parseVariantDictionary(value)
+			if err != nil {
+				return h, 0, err
+			}
+			if h.kdf, err = parseKdfParams(dict); err != nil {
+				return h, 0, err
+			}
+		}
+	}
+}
+
+func headerHMAC(keys derivedKeys, headerBytes []byte) []byte {
+	mac := hmac.New(sha256.New, keys.blockKey(math.MaxUint64))
+	mac.Write(headerBytes)
+	return mac.Sum(nil)
+}
+
+func readHMACBlocks(data []byte, keys derivedKeys) ([]byte, error) {
+	var out bytes.Buffer
+
+	for index := uint64(0); ; index++ {
+		if len(data) < 36 {
+			return nil, fmt.Errorf("truncated data block")
+		}
+		expected := data[:32]
+		size := int(int32(binary.LittleEndian.Uint32(data[32:36])))
+		if size < 0 || len(data) < 36+size {
+			return nil, fmt.Errorf("truncated data block")
+		}
+		block := data[36 : 36+size]
+
+		var indexBytes [8]byte
+		binary.LittleEndian.PutUint64(indexBytes[:], index)
+		mac := hmac.New(sha256.New, keys.blockKey(index))
+		mac.Write(indexBytes[:])
+		mac.Write(data[32:36])
+		mac.Write(block)
+		if !hmac.Equal(mac.Sum(nil), expected) {
+			return nil, fmt.Errorf("data block %d is corrupted", index)
+		}
+
+		if size == 0 {
+			return out.Bytes(), nil
+		}
+		out.Write(block)
+		data = data[36+size:]
+	}
+}
+
+func decryptPayload(h outerHeader, key, payload []byte) ([]byte, error) {
+	switch h.cipherID {
+	case cipherAES256:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(h.iv) != aes.BlockSize || len(payload)%aes.BlockSize != 0 || len(payload) == 0 {
+			return nil, fmt.Errorf("invalid encrypted payload")
+		}
+		plain := make([]byte, len(payload))
+		cipher.NewCBCDecrypter(block, h.iv).CryptBlocks(plain, payload)
+
+		padding := int(plain[len(plain)-1])
+		if padding < 1 || padding > aes.BlockSize {
+			return nil, ErrInvalidCredentials
+		}
+		return plain[:len(plain)-padding], nil
+	case cipherChaCha20:
+		stream, err := chacha20.NewUnauthenticatedCipher(key, h.iv)
+		if err != nil {
+			return nil, err
+		}
+		plain := make([]byte, len(payload))
+		stream.XORKeyStream(plain, payload)
+		return plain, nil
+	default:
+		return nil, fmt.Errorf("unsupported database cipher")
+	}
+}
+
+type innerHeader struct {
+	streamID  uint32
+	streamKey []byte
+	binaries  [][]byte
+}
+
+func readInnerHeader(data []byte) (innerHeader, int, error) {
+	var h innerHeader
+	pos := 0
+
+	for {
+		if len(data) < pos+5 {
+			return h, 0, fmt.Errorf("truncated inner header")
+		}
+		id := data[pos]
+		size := int(binary.LittleEndian.Uint32(data[pos+1 : pos+5]))
+		pos += 5
+		if size < 0 || len(data) < pos+size {
+			return h, 0, fmt.Errorf("truncated inner header")
+		}
+		value := data[pos : pos+size]
+		pos += size
+
+		switch id {
+		case innerHeaderEnd:
+			return h, pos, nil
+		case innerHeaderStreamID:
+			if len(value) >= 4 {
+				h.streamID = binary.LittleEndian.Uint32(value)
+			}
+		case innerHeaderStreamKey:
+			h.streamKey = value
+		case innerHeaderBinary:
+			if len(value) > 0 {
+				h.binaries = append(h.binaries, value[1:])
+			} else {
+				h.binaries = append(h.binaries, nil)
+			}
+		}
+	}
+}
+
+func (h innerHeader) stream() (*chacha20.Cipher, error) {
+	if h.streamID != streamChaCha20 {
+		return nil, fmt.Errorf("unsupported inner stream cipher %d", h.streamID)
+	}
+	sum := sha512.Sum512(h.streamKey)
+	return chacha20.NewUnauthenticatedCipher(sum[:32], sum[32:44])
+}
+
+type variantDictionary map[string][]byte
+
+func parseVariantDictionary(data []byte) (variantDictionary, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("invalid KDF parameters")
+	}
+	dict := make(variantDictionary)
+	pos := 2
+
+	for pos < len(data) {
+		kind := data[pos]
+		pos++
+		if kind == 0 {
+			return dict, nil
+		}
+		if len(data) < pos+4 {
+			break
+		}
+		keyLen := int(binary.LittleEndian.Uint32(data[pos:]))
+		pos += 4
+		if len(data) < pos+keyLen+4 {
+			break
+		}
+		key := string(data[pos : pos+keyLen])
+		pos += keyLen
+		valueLen := int(binary.LittleEndian.Uint32(data[pos:]))
+		pos += 4
+		if len(data) < pos+valueLen {
+			break
+		}
+		dict[key] = data[pos : pos+valueLen]
+		pos += valueLen
+	}
+
+	return nil, fmt.Errorf("invalid KDF parameters")
+}
+
+func (d variantDictionary) bytes(key string) []byte {
+	return d[key]
+}
+
+func (d variantDictionary) uint32(key string) uint32 {
+	if v := d[key]; len(v) >= 4 {
+		return binary.LittleEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (d variantDictionary) uint64(key string) uint64 {
+	if v := d[key]; len(v) >= 8 {
+		return binary.LittleEndian.Uint64(v)
+	}
+	return 0
+}
+
+type xmlNode struct {
+	Name     string
+	Attrs    map[string]string
+	Text     string
+	Children []*xmlNode
+}
+
+func parseXML(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	var root *xmlNode
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid database XML: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{Name: t.Name.Local, Attrs: make(map[string]string)}
+			for _, a := range t.Attr {
+				node.Attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("database XML is empty")
+	}
+	return root, nil
+}
+
+func (n *xmlNode) child(name string) *xmlNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *xmlNode) children(name string) []*xmlNode {
+	if n == nil {
+		return nil
+	}
+	var result []*xmlNode
+	for _, c := range n.Children {
+		if c.Name == name {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (n *xmlNode) text(name string) string {
+	if c := n.child(name); c != nil {
+		return c.Text
+	}
+	return ""
+}
+
+// Protected values are XORed with one continuous key stream in document order.
+func unprotect(n *xmlNode, stream *chacha20.Cipher) error {
+	if strings.EqualFold(n.Attrs["Protected"], "True") {
+		raw, err := decodeBase64(n.Text)
+		if err != nil {
+			return fmt.Errorf("invalid protected value: %v", err)
+		}
+		stream.XORKeyStream(raw, raw)
+		n.Text = string(raw)
+	}
+
+	for _, c := range n.Children {
+		if err := unprotect(c, stream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildDatabase(root *xmlNode, binaries [][]byte) (*Database, error) {
+	if root.Name != "KeePassFile" {
+		return nil, fmt.Errorf("unexpected database XML root %s", root.Name)
+	}
+
+	meta := root.child("Meta")
+	db := &Database{
+		Name:           meta.text("DatabaseName"),
+		RecycleBinUUID: parseUUID(meta.text("RecycleBinUUID")),
+	}
+
+	groupNode := root.child("Root").child("Group")
+	if groupNode == nil {
+		return nil, fmt.Errorf("database has no root group")
+	}
+	db.Root = buildGroup(groupNode, binaries)
+	return db, nil
+}
+
+func buildGroup(n *xmlNode, binaries [][]byte) *Group {
+	g := &Group{
+		UUID: parseUUID(n.text("UUID")),
+		Name: n.text("Name"),
+	}
+	for _, e := range n.children("Entry") {
+		g.Entries = append(g.Entries, buildEntry(e, binaries))
+	}
+	for _, child := range n.children("Group") {
+		g.Groups = append(g.Groups, buildGroup(child, binaries))
+	}
+	return g
+}
+
+func buildEntry(n *xmlNode, binaries [][]byte) *Entry {
+	times := n.child("Times")
+	e := &Entry{
+		UUID:       parseUUID(n.text("UUID")),
+		Created:    parseTime(times.text("CreationTime")),
+		Modified:   parseTime(times.text("LastModificationTime")),
+		Expires:    strings.EqualFold(times.text("Expires"), "True"),
+		ExpiryTime: parseTime(times.text("ExpiryTime")),
+	}
+
+	for _, s := range n.children("String") {
+		value := s.child("Value")
+		e.Fields = append(e.Fields, Field{
+			Key:       s.text("Key"),
+			Value:     value.textOrEmpty(),
+			Protected: value != nil && strings.EqualFold(value.Attrs["Protected"], "True"),
+		})
+	}
+
+	for _, b := range n.children("Binary") {
+		value := b.child("Value")
+		if value == nil {
+			continue
+		}
+		ref, err := strconv.Atoi(value.Attrs["Ref"])
+		if err != nil || ref < 0 || ref >= len(binaries) {
+			continue
+		}
+		e.Attachments = append(e.Attachments, Attachment{Name: b.text("Key"), Data: binaries[ref]})
+	}
+
+	for _, tag := range strings.FieldsFunc(n.text("Tags"), func(r rune) bool { return r == ';' || r == ',' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			e.Tags = append(e.Tags, tag)
+		}
+	}
+
+	for _, h := range n.child("History").children("Entry") {
+		e.History = append(e.History, buildEntry(h, binaries))
+	}
+
+	return e
+}
+
+func (n *xmlNode) textOrEmpty() string {
+	if n == nil {
+		return ""
+	}
+	return n.Text
+}
+
+func parseUUID(value string) UUID {
+	var u UUID
+	if raw, err := decodeBase64(value); err == nil {
+		copy(u[:], raw)
+	}
+	return u
+}
+
+func decodeBase64(value string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+}
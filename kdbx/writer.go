@@ -0,0 +1,372 @@
+package kdbx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	variantUInt32    = 0x04
+	variantUInt64    = 0x05
+	variantByteArray = 0x42
+
+	blockSize = 1024 * 1024
+)
+
+// Argon2d with 64 MiB and two lanes, as KeePassXC uses for new databases.
+var defaultArgon2 = argon2Params{
+	mode:        argon2d,
+	version:     argon2Version13,
+	iterations:  2,
+	memoryKiB:   64 * 1024,
+	parallelism: 2,
+}
+
+func NewUUID() UUID {
+	var u UUID
+	rand.Read(u[:])
+	return u
+}
+
+func Write(w io.Writer, db *Database, creds Credentials) error {
+	kdf := kdfParams{uuid: kdfArgon2d, argon: defaultArgon2}
+	var err error
+	if kdf.argon.salt, err = randomBytes(32); err != nil {
+		return err
+	}
+	return write(w, db, creds, kdf)
+}
+
+func write(w io.Writer, db *Database, creds Credentials, kdf kdfParams) error {
+	masterSeed, err := randomBytes(32)
+	if err != nil {
+		return err
+	}
+	iv, err := randomBytes(aes.BlockSize)
+	if err != nil {
+		return err
+	}
+	streamKey, err := randomBytes(64)
+	if err != nil {
+		return err
+	}
+
+	var header bytes.Buffer
+	binary.Write(&header, binary.LittleEndian, signature1)
+	binary.Write(&header, binary.LittleEndian, signature2)
+	binary.Write(&header, binary.LittleEndian, uint16(0))
+	binary.Write(&header, binary.LittleEndian, uint16(majorVersion4))
+	writeField(&header, headerCipherID, cipherAES256[:])
+	writeField(&header, headerCompression, uint32Bytes(1))
+	writeField(&header, headerMasterSeed, masterSeed)
+	writeField(&header, headerEncryptionIV, iv)
+	writeField(&header, headerKdfParameters, kdf.variantDictionary())
+	writeField(&header, headerEnd, []byte("\r\n\r\n"))
+
+	composite, err := creds.compositeKey()
+	if err != nil {
+		return err
+	}
+	transformed, err := kdf.transform(composite)
+	if err != nil {
+		return err
+	}
+	keys := deriveKeys(masterSeed, transformed)
+
+	sum := sha256.Sum256(header.Bytes())
+	out := bytes.NewBuffer(append([]byte{}, header.Bytes()...))
+	out.Write(sum[:])
+	out.Write(headerHMAC(keys, header.Bytes()))
+
+	var inner bytes.Buffer
+	writeField(&inner, innerHeaderStreamID, uint32Bytes(streamChaCha20))
+	writeField(&inner, innerHeaderStreamKey, streamKey)
+
+	x, err := newXMLWriter(streamKey)
+	if err != nil {
+		return err
+	}
+	x.database(db)
+	for _, b := range x.binaries {
+		writeField(&inner, innerHeaderBinary, append([]byte{0x01}, b...))
+	}
+	writeField(&inner, innerHeaderEnd, nil)
+	inner.Write(x.buf.Bytes())
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(inner.Bytes()); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	encrypted, err := encryptPayload(keys.cipherKey, iv, compressed.Bytes())
+	if err != nil {
+		return err
+	}
+
+	writeHMACBlocks(out, encrypted, keys)
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+func uint32Bytes(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func uint64Bytes(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, v)
+	return b
+}
+
+func writeField(buf *bytes.Buffer, id byte, value []byte) {
+	buf.WriteByte(id)
+	buf.Write(uint32Bytes(uint32(len(value))))
+	buf.Write(value)
+}
+
+func (p kdfParams) variantDictionary() []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0x00, 0x01})
+
+	item := func(kind byte, key string, value []byte) {
+		buf.WriteByte(kind)
+		buf.Write(uint32Bytes(uint32(len(key))))
+		buf.WriteString(key)
+		buf.Write(uint32Bytes(uint32(len(value))))
+		buf.Write(value)
+	}
+
+	item(variantByteArray, "$UUID", p.uuid[:])
+	if p.uuid == kdfAES {
+		item(variantUInt64, "R", uint64Bytes(p.rounds))
+		item(variantByteArray, "S", p.seed)
+	} else {
+		item(variantByteArray, "S", p.argon.salt)
+		item(variantUInt32, "P", uint32Bytes(p.argon.parallelism))
+		item(variantUInt64, "M", uint64Bytes(uint64(p.argon.memoryKiB)*1024))
+		item(variantUInt64, "I", uint64Bytes(uint64(p.argon.iterations)))
+		item(variantUInt32, "V", uint32Bytes(p.argon.version))
+	}
+	buf.WriteByte(0)
+
+	return buf.Bytes()
+}
+
+func encryptPayload(key, iv, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	padded := append(append([]byte{}, plain...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+	return encrypted, nil
+}
+
+func writeHMACBlocks(out *bytes.Buffer, data []byte, keys derivedKeys) {
+	for index := uint64(0); ; index++ {
+		size := len(data)
+		if size > blockSize {
+			size = blockSize
+		}
+		block := data[:size]
+		data = data[size:]
+
+		var indexBytes [8]byte
+		binary.LittleEndian.PutUint64(indexBytes[:], index)
+		sizeBytes := uint32Bytes(uint32(size))
+
+		mac := hmac.New(sha256.New, keys.blockKey(index))
+		mac.Write(indexBytes[:])
+		mac.Write(sizeBytes)
+		mac.Write(block)
+
+		out.Write(mac.Sum(nil))
+		out.Write(sizeBytes)
+		out.Write(block)
+
+		if size == 0 {
+			return
+		}
+	}
+}
+
+type xmlWriter struct {
+	buf      bytes.Buffer
+	stream   *chacha20.Cipher
+	binaries [][]byte
+	depth    int
+}
+
+func newXMLWriter(streamKey []byte) (*xmlWriter, error) {
+	sum := sha512.Sum512(streamKey)
+	stream, err := chacha20.NewUnauthenticatedCipher(sum[:32], sum[32:44])
+	if err != nil {
+		return nil, err
+	}
+	return &xmlWriter{stream: stream}, nil
+}
+
+func (x *xmlWriter) open(name string) {
+	x.buf.WriteString(strings.Repeat("\t", x.depth) + "<" + name + ">\n")
+	x.depth++
+}
+
+func (x *xmlWriter) close(name string) {
+	x.depth--
+	x.buf.WriteString(strings.Repeat("\t", x.depth) + "</" + name + ">\n")
+}
+
+func (x *xmlWriter) element(name, value string) {
+	x.buf.WriteString(strings.Repeat("\t", x.depth) + "<" + name + ">")
+	xml.EscapeText(&x.buf, []byte(value))
+	x.buf.WriteString("</" + name + ">\n")
+}
+
+func (x *xmlWriter) database(db *Database) {
+	x.buf.WriteString(`<?xml version="1.0" encoding="utf-8" standalone="yes"?>` + "\n")
+	x.open("KeePassFile")
+
+	x.open("Meta")
+	x.element("Generator", "pwvault")
+	x.element("DatabaseName", db.Name)
+	x.element("RecycleBinEnabled", "False")
+	x.open("MemoryProtection")
+	x.element("ProtectTitle", "False")
+	x.element("ProtectUserName", "False")
+	x.element("ProtectPassword", "True")
+	x.element("ProtectURL", "False")
+	x.element("ProtectNotes", "False")
+	x.close("MemoryProtection")
+	x.close("Meta")
+
+	x.open("Root")
+	x.group(db.Root)
+	x.close("Root")
+
+	x.close("KeePassFile")
+}
+
+func (x *xmlWriter) group(g *Group) {
+	x.open("Group")
+	x.element("UUID", encodeBase64(g.UUID[:]))
+	x.element("Name", g.Name)
+	x.times(time.Time{}, time.Time{}, false, time.Time{})
+	for _, e := range g.Entries {
+		x.entry(e, true)
+	}
+	for _, child := range g.Groups {
+		x.group(child)
+	}
+	x.close("Group")
+}
+
+func (x *xmlWriter) entry(e *Entry, withHistory bool) {
+	x.open("Entry")
+	x.element("UUID", encodeBase64(e.UUID[:]))
+	x.element("Tags", strings.Join(e.Tags, ";"))
+	x.times(e.Created, e.Modified, e.Expires, e.ExpiryTime)
+
+	for _, f := range e.Fields {
+		x.open("String")
+		x.element("Key", f.Key)
+		if f.Protected {
+			raw := []byte(f.Value)
+			x.stream.XORKeyStream(raw, raw)
+			x.buf.WriteString(strings.Repeat("\t", x.depth) + `<Value Protected="True">` + encodeBase64(raw) + "</Value>\n")
+		} else {
+			x.element("Value", f.Value)
+		}
+		x.close("String")
+	}
+
+	for _, a := range e.Attachments {
+		x.open("Binary")
+		x.element("Key", a.Name)
+		x.buf.WriteString(strings.Repeat("\t", x.depth) + `<Value Ref="` + strconv.Itoa(len(x.binaries)) + `"/>` + "\n")
+		x.binaries = append(x.binaries, a.Data)
+		x.close("Binary")
+	}
+
+	if withHistory && len(e.History) > 0 {
+		x.open("History")
+		for _, h := range e.History {
+			x.entry(h, false)
+		}
+		x.close("History")
+	}
+
+	x.close("Entry")
+}
+
+func (x *xmlWriter) times(created, modified time.Time, expires bool, expiry time.Time) {
+	if created.IsZero() {
+		created = time.Now()
+	}
+	if modified.IsZero() {
+		modified = created
+	}
+
+	x.open("Times")
+	x.element("CreationTime", formatTime(created))
+	x.element("LastModificationTime", formatTime(modified))
+	x.element("LastAccessTime", formatTime(modified))
+	x.element("ExpiryTime", formatTime(expiry))
+	x.element("Expires", formatBool(expires && !expiry.IsZero()))
+	x.element("UsageCount", "0")
+	x.element("LocationChanged", formatTime(modified))
+	x.close("Times")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	seconds := t.Unix() + kdbxEpochOffset
+	if seconds < 0 || seconds > math.MaxInt64/2 {
+		seconds = time.Now().Unix() + kdbxEpochOffset
+	}
+	return encodeBase64(uint64Bytes(uint64(seconds)))
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+func encodeBase64(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -1,17 +1,23 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"pw/config"
+	"pw/crypto"
 	"pw/ui"
 	"pw/util"
 	"pw/vault"
 )
 
 func main() {
+	vaultName := flag.String("vault", "", "name of the vault profile to open")
+	flag.Parse()
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
@@ -33,39 +39,160 @@ func main() {
 	}
 
 	cfg := configManager.Get()
+	args := flag.Args()
 
-	vaultDir := filepath.Dir(cfg.VaultPath)
-	if err := os.MkdirAll(vaultDir, 0700); err != nil {
-		logger.Error("Failed to create vault directory: %v", err)
-		os.Exit(1)
+	if len(args) > 0 && args[0] == "profile" {
+		if err := runProfileCommand(cfg, args[1:]); err != nil {
+			ui.ShowError("%v", err)
+			os.Exit(1)
+		}
+		if err := configManager.Save(); err != nil {
+			logger.Error("Failed to save configuration: %v", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	var key string
-	if len(os.Args) > 1 {
-		key = os.Args[1]
-	} else {
-		key = ui.ReadSecureInput("Enter your 32-character encryption key: ")
+	if len(args) > 1 && args[0] == "breach" && args[1] == "index" {
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, "Usage: pwvault breach index PWNED-PASSWORDS-FILE")
+			os.Exit(2)
+		}
+		if err := ui.BuildBreachIndex(cfg, args[2]); err != nil {
+			ui.ShowError("Failed to build breach index: %v", err)
+			os.Exit(1)
+		}
+		if err := configManager.Save(); err != nil {
+			logger.Error("Failed to save configuration: %v", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	if len(key) != 32 {
-		ui.ShowError("Key must be exactly 32 characters long")
-		os.Exit(1)
+	if len(args) > 0 && args[0] == "passphrase" {
+		if err := runPassphraseCommand(cfg, args[1:]); err != nil {
+			ui.ShowError("%v", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	v, err := vault.NewVault(cfg.VaultPath, []byte(key))
+	name := *vaultName
+	if name == "" {
+		name = cfg.DefaultVault
+	}
+	requested, err := cfg.Profile(name)
 	if err != nil {
-		logger.Error("Failed to initialize vault: %v", err)
+		ui.ShowError("%v", err)
+		os.Exit(1)
+	}
+
+	// Team vaults are unlocked with the identity stored in the personal vault.
+	teamName := ""
+	if requested.Team {
+		teamName, name = name, cfg.DefaultVault
+	}
+	profile, err := cfg.Profile(name)
+	if err != nil || profile.Team {
+		ui.ShowError("The default vault must be a personal vault to open team vault %s", teamName)
 		os.Exit(1)
 	}
 
-	cli := ui.NewCLI(cfg, v)
+	var key string
+	var importCmd *importCommand
+	var exportCmd *exportCommand
+	var auditCmd *auditCommand
+	breachCheck := false
+	switch {
+	case len(args) > 0 && args[0] == "recovery":
+		if teamName != "" {
+			ui.ShowError("Recovery shares are not supported for team vaults")
+			os.Exit(1)
+		}
+		if len(args) < 2 || (args[1] != "split" && args[1] != "combine") {
+			fmt.Fprintln(os.Stderr, "Usage: pwvault [--vault name] recovery <split|combine>")
+			os.Exit(2)
+		}
+
+		if args[1] == "combine" {
+			recovered, err := ui.RecoveryCombine()
+			if err != nil {
+				ui.ShowError("Recovery failed: %v", err)
+				os.Exit(1)
+			}
+			key = string(recovered)
+			break
+		}
+
+		v := openVault(profile, logger, readKey(args[2:]))
+		if err := ui.RecoverySplit(v); err != nil {
+			ui.ShowError("Failed to split recovery key: %v", err)
+			os.Exit(1)
+		}
+		return
+	case len(args) > 0 && args[0] == "import":
+		importCmd, err = parseImportCommand(args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		key = readKey(importCmd.rest)
+	case len(args) > 0 && args[0] == "breach":
+		if len(args) < 2 || args[1] != "check" {
+			fmt.Fprintln(os.Stderr, "Usage: pwvault [--vault name] breach <check [KEY]|index FILE>")
+			os.Exit(2)
+		}
+		breachCheck = true
+		key = readKey(args[2:])
+	case len(args) > 0 && args[0] == "export":
+		exportCmd, err = parseExportCommand(args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		// Keep stdout for the export itself.
+		if exportCmd.path == "-" {
+			ui.SetOutput(os.Stderr)
+		}
+		key = readKey(exportCmd.rest)
+	case len(args) > 0 && args[0] == "audit":
+		auditCmd, err = parseAuditCommand(args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if auditCmd.path == "-" {
+			ui.SetOutput(os.Stderr)
+		}
+		key = readKey(auditCmd.rest)
+	default:
+		key = readKey(args)
+	}
+
+	if name == config.DefaultProfile {
+		if err := ui.MigrateLegacyVault(profile); err != nil {
+			logger.Warning("Failed to copy the legacy vault: %v", err)
+			ui.ShowError("Failed to copy the old vault: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	v := openVault(profile, logger, key)
 
-	if cfg.AutoBackup {
-		backupManager, err := vault.NewBackupManager(cfg.BackupDir, cfg.MaxBackups)
+	cli := ui.NewCLI(cfg, name, v)
+	if teamName != "" {
+		if err := cli.Use(teamName); err != nil {
+			ui.ShowError("Failed to open team vault %s: %v", teamName, err)
+			os.Exit(1)
+		}
+	}
+
+	if profile.AutoBackup {
+		backupManager, err := vault.NewBackupManager(profile.BackupDir, profile.MaxBackups)
 		if err != nil {
 			logger.Warning("Failed to initialize backup manager: %v", err)
 		} else {
-			if err := backupManager.CreateBackup(cfg.VaultPath, v.Encrypt); err != nil {
+			if err := backupManager.CreateBackup(profile.VaultPath, v.Encrypt); err != nil {
 				logger.Warning("Auto-backup failed: %v", err)
 			} else {
 				logger.Info("Auto-backup created successfully")
@@ -73,7 +200,36 @@ func main() {
 		}
 	}
 
-	if err := cli.Run(); err != nil {
+	if exportCmd != nil {
+		if err := cli.ExportFile(exportCmd.path, exportCmd.options); err != nil {
+			ui.ShowError("Export failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if auditCmd != nil {
+		if err := cli.WriteAudit(auditCmd.path, auditCmd.format, auditCmd.maxAge); err != nil {
+			ui.ShowError("Audit failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if breachCheck {
+		if err := cli.CheckBreaches(); err != nil {
+			ui.ShowError("Breach check failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if importCmd != nil {
+		if err := cli.ImportFile(importCmd.path, importCmd.format, importCmd.mapping, importCmd.saveMapping, importCmd.dryRun); err != nil {
+			ui.ShowError("Import failed: %v", err)
+			os.Exit(1)
+		}
+	} else if err := cli.Run(); err != nil {
 		logger.Error("CLI error: %v", err)
 		os.Exit(1)
 	}
@@ -83,8 +239,240 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := v.Save(); err != nil {
+	if err := cli.SaveAll(); err != nil {
 		logger.Error("Failed to save vault: %v", err)
 		os.Exit(1)
 	}
 }
+
+func readKey(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return ui.ReadSecureInput("Enter your 32-character encryption key: ")
+}
+
+func openVault(profile *config.VaultProfile, logger *util.Logger, key string) *vault.Vault {
+	v, err := ui.OpenProfileVault(profile, key)
+	if err != nil {
+		logger.Error("Failed to initialize vault: %v", err)
+		ui.ShowError("Failed to open vault: %v", err)
+		os.Exit(1)
+	}
+	return v
+}
+
+type importCommand struct {
+	path        string
+	format      vault.ImportFormat
+	mapping     string
+	saveMapping string
+	dryRun      bool
+	rest        []string
+}
+
+func parseImportCommand(args []string) (*importCommand, error) {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", string(vault.AutoDetect), "import format, or auto to detect it")
+	mapping := fs.String("map", "", "CSV column mapping such as service=Title,username=Login, or a saved mapping name")
+	saveMapping := fs.String("save-map", "", "save the --map mapping under this name")
+	dryRun := fs.Bool("dry-run", false, "report the changes without applying them")
+	usage := fmt.Errorf("usage: pwvault [--vault name] import [--format F] [--map SPEC] [--save-map NAME] [--dry-run] FILE [KEY]")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, usage
+	}
+	if fs.NArg() < 1 || (*saveMapping != "" && *mapping == "") {
+		return nil, usage
+	}
+
+	return &importCommand{
+		path:        fs.Arg(0),
+		format:      vault.ImportFormat(*format),
+		mapping:     *mapping,
+		saveMapping: *saveMapping,
+		dryRun:      *dryRun,
+		rest:        fs.Args()[1:],
+	}, nil
+}
+
+type exportCommand struct {
+	path    string
+	options vault.ExportOptions
+	rest    []string
+}
+
+func parseExportCommand(args []string) (*exportCommand, error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", string(vault.JSONFormat), "export format: json, ndjson, yaml, csv, txt, env, kdbx or pass")
+	plaintext := fs.Bool("plaintext", false, "write the export unencrypted")
+	query := fs.String("query", "", "only export entries matching this search query")
+	tags := fs.String("tag", "", "only export entries with all of these comma separated tags")
+	folder := fs.String("folder", "", "only export entries in this folder")
+	since := fs.String("since", "", "only export entries created on or after this date (YYYY-MM-DD)")
+	until := fs.String("until", "", "only export entries created before this date (YYYY-MM-DD)")
+	fields := fs.String("fields", "", "comma separated fields to export, in order")
+	envKey := fs.String("env-key", vault.DefaultEnvKeyTemplate, "variable name template for env exports")
+	usage := fmt.Errorf("usage: pwvault [--vault name] export [--format F] [--plaintext] [--query Q] [--tag T] [--folder F] [--since DATE] [--until DATE] [--fields LIST] [--env-key TEMPLATE] FILE|- [KEY]")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() < 1 {
+		return nil, usage
+	}
+
+	filter := vault.EntryFilter{
+		Query:  *query,
+		Tags:   ui.ParseTags(*tags),
+		Folder: *folder,
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid --query: %v", err)
+	}
+	var err error
+	if filter.Since, err = parseDate(*since); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = parseDate(*until); err != nil {
+		return nil, err
+	}
+
+	return &exportCommand{
+		path: fs.Arg(0),
+		options: vault.ExportOptions{
+			Format:         vault.ExportFormat(*format),
+			Plaintext:      *plaintext,
+			Fields:         ui.ParseTags(*fields),
+			Filter:         filter,
+			EnvKeyTemplate: *envKey,
+		},
+		rest: fs.Args()[1:],
+	}, nil
+}
+
+type auditCommand struct {
+	path   string
+	format vault.AuditFormat
+	maxAge time.Duration
+	rest   []string
+}
+
+func parseAuditCommand(args []string) (*auditCommand, error) {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	format := fs.String("format", string(vault.MarkdownAudit), "report format: markdown, html or json")
+	maxAge := fs.Int("max-age", int(vault.DefaultMaxPasswordAge/(24*time.Hour)), "report passwords older than this many days")
+	usage := fmt.Errorf("usage: pwvault [--vault name] audit [--format markdown|html|json] [--max-age DAYS] FILE|- [KEY]")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() < 1 || *maxAge <= 0 {
+		return nil, usage
+	}
+
+	switch f := vault.AuditFormat(*format); f {
+	case vault.MarkdownAudit, vault.HTMLAudit, vault.JSONAudit:
+		return &auditCommand{
+			path:   fs.Arg(0),
+			format: f,
+			maxAge: time.Duration(*maxAge) * 24 * time.Hour,
+			rest:   fs.Args()[1:],
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown audit format %q (use markdown, html or json)", *format)
+	}
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", value)
+	}
+	return date, nil
+}
+
+// runPassphraseCommand prints a passphrase on stdout and its entropy on
+// stderr, so it can be piped without unlocking a vault.
+func runPassphraseCommand(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("passphrase", flag.ContinueOnError)
+	words := fs.Int("words", crypto.DefaultPassphraseWords, "number of words")
+	separator := fs.String("separator", "-", "text between words")
+	capitalization := fs.String("case", string(crypto.LowerCase), "capitalization: lower, title or random")
+	digits := fs.Int("digits", 0, "number of random digits to insert")
+	wordlist := fs.String("wordlist", cfg.PassphraseWordlist, "wordlist file instead of the EFF long wordlist")
+	usage := fmt.Errorf("usage: pwvault passphrase [--words N] [--separator S] [--case lower|title|random] [--digits N] [--wordlist FILE]")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return usage
+	}
+
+	options := crypto.PassphraseOptions{
+		Words:     *words,
+		Separator: *separator,
+		Case:      crypto.Capitalization(*capitalization),
+		Digits:    *digits,
+	}
+	if *wordlist != "" {
+		list, err := crypto.LoadWordlist(*wordlist)
+		if err != nil {
+			return err
+		}
+		options.Wordlist = list
+	}
+
+	passphrase, err := crypto.NewGenerator().GeneratePassphrase(options)
+	if err != nil {
+		return err
+	}
+	fmt.Println(passphrase)
+	fmt.Fprintf(os.Stderr, "Entropy: %.1f bits\n", options.Entropy())
+	return nil
+}
+
+func runProfileCommand(cfg *config.Config, args []string) error {
+	usage := fmt.Errorf("usage: pwvault profile <list|create NAME [PATH]|rename OLD NEW|delete NAME|default NAME>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch {
+	case args[0] == "list":
+		for _, name := range cfg.ListProfiles() {
+			profile, _ := cfg.Profile(name)
+			marker := " "
+			if name == cfg.DefaultVault {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\t%s\n", marker, name, profile.VaultPath)
+		}
+	case args[0] == "create" && len(args) >= 2:
+		path := ""
+		if len(args) > 2 {
+			path = args[2]
+		}
+		profile, err := cfg.CreateProfile(args[1], path)
+		if err != nil {
+			return err
+		}
+		profile.Team = vault.IsTeamVault(profile.VaultPath)
+		ui.ShowSuccess("Created vault profile %s at %s", args[1], profile.VaultPath)
+	case args[0] == "rename" && len(args) == 3:
+		if err := cfg.RenameProfile(args[1], args[2]); err != nil {
+			return err
+		}
+		ui.ShowSuccess("Renamed %s to %s", args[1], args[2])
+	case args[0] == "delete" && len(args) == 2:
+		if err := cfg.DeleteProfile(args[1]); err != nil {
+			return err
+		}
+		ui.ShowSuccess("Deleted vault profile %s (the vault file was kept)", args[1])
+	case args[0] == "default" && len(args) == 2:
+		if _, err := cfg.Profile(args[1]); err != nil {
+			return err
+		}
+		cfg.DefaultVault = args[1]
+		ui.ShowSuccess("Default vault set to %s", args[1])
+	default:
+		return usage
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package security
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BreachChecker reports how often a password hash appears in the Have I Been
+// Pwned "Pwned Passwords" data, or 0 if it does not.
+type BreachChecker interface {
+	Lookup(hash [sha1.Size]byte) (int, error)
+}
+
+func PasswordHash(password string) [sha1.Size]byte {
+	return sha1.Sum([]byte(password))
+}
+
+// A breach index is a header followed by fixed-size records of a SHA-1 hash
+// and a big-endian occurrence count, sorted by hash so lookups can binary
+// search the file without loading it.
+const (
+	breachIndexMagic = "PWBRIDX1"
+	breachRecordSize = sha1.Size + 4
+)
+
+var ErrBreachIndexUnsorted = errors.New("pwned passwords file is not sorted by hash; download the version ordered by hash")
+
+// BuildBreachIndex converts a Pwned Passwords text file ("HASH:COUNT" lines,
+// ordered by hash) into an index at path. It returns the number of hashes.
+func BuildBreachIndex(src io.Reader, path string) (int64, error) {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if _, err := w.WriteString(breachIndexMagic); err != nil {
+		return 0, err
+	}
+
+	scanner := bufio.NewScanner(src)
+	var prev [sha1.Size]byte
+	var count int64
+	record := make([]byte, breachRecordSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		hash, occurrences, err := parseHashLine(text)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %v", line, err)
+		}
+		if count > 0 && bytes.Compare(hash[:], prev[:]) <= 0 {
+			return 0, fmt.Errorf("line %d: %w", line, ErrBreachIndexUnsorted)
+		}
+		prev = hash
+
+		copy(record, hash[:])
+		binary.BigEndian.PutUint32(record[sha1.Size:], clampCount(occurrences))
+		if _, err := w.Write(record); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	return count, os.Rename(tmp, path)
+}
+
+func parseHashLine(line string) ([sha1.Size]byte, int, error) {
+	var hash [sha1.Size]byte
+	hexHash, countText, ok := strings.Cut(line, ":")
+	if !ok || len(hexHash) != sha1.Size*2 {
+		return hash, 0, fmt.Errorf("expected HASH:COUNT, got %q", line)
+	}
+
+	decoded, err := hex.DecodeString(hexHash)
+	if err != nil {
+		return hash, 0, fmt.Errorf("invalid hash %q", hexHash)
+	}
+	copy(hash[:], decoded)
+
+	count, err := strconv.Atoi(strings.TrimSpace(countText))
+	if err != nil || count < 0 {
+		return hash, 0, fmt.Errorf("invalid count %q", countText)
+	}
+	return hash, count, nil
+}
+
+func clampCount(count int) uint32 {
+	if count > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(count)
+}
+
+// BreachIndex looks hashes up in an index built by BuildBreachIndex.
+type BreachIndex struct {
+	file    *os.File
+	records int64
+}
+
+func OpenBreachIndex(path string) (*BreachIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, len(breachIndexMagic))
+	if _, err := io.ReadFull(file, magic); err != nil || string(magic) != breachIndexMagic {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a breach index", path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	size := info.Size() - int64(len(breachIndexMagic))
+	if size%breachRecordSize != 0 {
+		file.Close()
+		return nil, fmt.Errorf("%s is truncated", path)
+	}
+
+	return &BreachIndex{file: file, records: size / breachRecordSize}, nil
+}
+
+func (b *BreachIndex) Close() error {
+	return b.file.Close()
+}
+
+// Len returns the number of hashes in the index.
+func (b *BreachIndex) Len() int64 {
+	return b.records
+}
+
+func (b *BreachIndex) Lookup(hash [sha1.Size]byte) (int, error) {
+	record := make([]byte, breachRecordSize)
+	lo, hi := int64(0), b.records
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if _, err := b.file.ReadAt(record, int64(len(breachIndexMagic))+mid*breachRecordSize); err != nil {
+			return 0, err
+		}
+
+		switch bytes.Compare(record[:sha1.Size], hash[:]) {
+		case 0:
+			return int(binary.BigEndian.Uint32(record[sha1.Size:])), nil
+		case -1:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// DefaultRangeAPI is the Pwned Passwords k-anonymity range API.
+const DefaultRangeAPI = "https://api.pwnedpasswords.com"
+
+// RangeClient checks hashes with the range API. Only the first five hex
+// characters of a hash are sent; the matching suffix is found locally.
+type RangeClient struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func (c RangeClient) Lookup(hash [sha1.Size]byte) (int, error) {
+	hexHash := strings.ToUpper(hex.EncodeToString(hash[:]))
+	prefix, suffix := hexHash[:5], hexHash[5:]
+
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultRangeAPI
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(base, "/")+"/range/"+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "pwvault")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("range API returned %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		got, count, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(got, suffix) {
+			continue
+		}
+		// Padding entries have a count of 0.
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			return 0, fmt.Errorf("invalid range API response line %q", line)
+		}
+		return n, nil
+	}
+	return 0, scanner.Err()
+}
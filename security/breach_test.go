@@ -0,0 +1,158 @@
+package security
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func hexHash(hash [sha1.Size]byte) string {
+	return strings.ToUpper(hex.EncodeToString(hash[:]))
+}
+
+func TestRangeClient(t *testing.T) {
+	breached := PasswordHash("password")
+	padded := PasswordHash("padding entry")
+	prefix := hexHash(breached)[:5]
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Errorf("Add-Padding = %q, want true", r.Header.Get("Add-Padding"))
+		}
+		if r.URL.Path != "/range/"+prefix {
+			http.Error(w, "unknown prefix", http.StatusNotFound)
+			return
+		}
+		// The API answers with CRLF lines, and padding lines count 0.
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n")
+		fmt.Fprintf(w, "%s:10434004\r\n", hexHash(breached)[5:])
+		fmt.Fprintf(w, "%s:0\r\n", hexHash(padded)[5:])
+	}))
+	defer server.Close()
+
+	client := RangeClient{BaseURL: server.URL + "/", HTTPClient: server.Client()}
+
+	if n, err := client.Lookup(breached); err != nil || n != 10434004 {
+		t.Errorf("breached password: %d, %v", n, err)
+	}
+	if len(requests) != 1 || requests[0] != "/range/"+prefix {
+		t.Errorf("requests = %v, want only the five character prefix", requests)
+	}
+
+	// A hash whose suffix only appears as padding counts as not breached.
+	var paddedHash [sha1.Size]byte
+	copy(paddedHash[:], breached[:3])
+	copy(paddedHash[3:], padded[3:])
+	paddedHash[2] = breached[2]&0xf0 | padded[2]&0x0f
+	if hexHash(paddedHash)[:5] != prefix {
+		t.Fatalf("test hash %s does not share the prefix %s", hexHash(paddedHash), prefix)
+	}
+	if n, err := client.Lookup(paddedHash); err != nil || n != 0 {
+		t.Errorf("padding entry: %d, %v", n, err)
+	}
+
+	// A different prefix gets a 404 from the server.
+	if _, err := client.Lookup(PasswordHash("something else")); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("non-200 response: err = %v", err)
+	}
+}
+
+func TestRangeClientServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := RangeClient{BaseURL: server.URL, HTTPClient: server.Client()}
+	if _, err := client.Lookup(PasswordHash("password")); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("err = %v, want the 429 status", err)
+	}
+}
+
+func buildTestIndex(t *testing.T, lines []string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pwned.idx")
+	n, err := BuildBreachIndex(strings.NewReader(strings.Join(lines, "\r\n")), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(n) != len(lines) {
+		t.Fatalf("indexed %d hashes, want %d", n, len(lines))
+	}
+	return path
+}
+
+func TestBreachIndex(t *testing.T) {
+	counts := map[string]int{}
+	var hashes []string
+	for i := 0; i < 100; i++ {
+		h := hexHash(PasswordHash(fmt.Sprintf("password%d", i)))
+		hashes = append(hashes, h)
+		counts[h] = i + 1
+	}
+	sort.Strings(hashes)
+	lines := make([]string, len(hashes))
+	for i, h := range hashes {
+		lines[i] = fmt.Sprintf("%s:%d", h, counts[h])
+	}
+
+	index, err := OpenBreachIndex(buildTestIndex(t, lines))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	if index.Len() != int64(len(hashes)) {
+		t.Errorf("Len = %d, want %d", index.Len(), len(hashes))
+	}
+
+	for _, h := range []string{hashes[0], hashes[1], hashes[50], hashes[len(hashes)-2], hashes[len(hashes)-1]} {
+		var hash [sha1.Size]byte
+		hex.Decode(hash[:], []byte(h))
+		if n, err := index.Lookup(hash); err != nil || n != counts[h] {
+			t.Errorf("%s: %d, %v, want %d", h, n, err, counts[h])
+		}
+	}
+
+	var lowest, highest [sha1.Size]byte
+	for i := range highest {
+		highest[i] = 0xff
+	}
+	for _, hash := range [][sha1.Size]byte{lowest, highest, PasswordHash("not in the index")} {
+		if n, err := index.Lookup(hash); err != nil || n != 0 {
+			t.Errorf("missing %s: %d, %v", hexHash(hash), n, err)
+		}
+	}
+}
+
+func TestBuildBreachIndexErrors(t *testing.T) {
+	a := hexHash(PasswordHash("a"))
+	b := hexHash(PasswordHash("b"))
+	if a > b {
+		a, b = b, a
+	}
+
+	for name, lines := range map[string][]string{
+		"unsorted":  {b + ":1", a + ":1"},
+		"duplicate": {a + ":1", a + ":2"},
+	} {
+		path := filepath.Join(t.TempDir(), "pwned.idx")
+		_, err := BuildBreachIndex(strings.NewReader(strings.Join(lines, "\n")), path)
+		if !errors.Is(err, ErrBreachIndexUnsorted) {
+			t.Errorf("%s: err = %v, want ErrBreachIndexUnsorted", name, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "pwned.idx")
+	if _, err := BuildBreachIndex(strings.NewReader(a+"\nnot a hash:1\n"), path); err == nil {
+		t.Error("built an index from malformed lines")
+	}
+}
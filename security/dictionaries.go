@@ -0,0 +1,181 @@
+package security
+
+import (
+	"strings"
+	"unicode"
+)
+
+// rankedDictionary maps lower-case words to their frequency rank, 1 being the
+// most common.
+type rankedDictionary struct {
+	name  string
+	ranks map[string]int
+}
+
+// maxWordLength bounds the substrings looked up in the dictionaries.
+const maxWordLength = 24
+
+const (
+	passwordsDictionary  = "passwords"
+	englishDictionary    = "english"
+	namesDictionary      = "names"
+	userInputsDictionary = "user_inputs"
+)
+
+func newRankedDictionary(name string, words []string) rankedDictionary {
+	dict := rankedDictionary{name: name, ranks: make(map[string]int, len(words))}
+	for _, w := range words {
+		w = strings.ToLower(w)
+		if _, ok := dict.ranks[w]; !ok && w != "" {
+			dict.ranks[w] = len(dict.ranks) + 1
+		}
+	}
+	return dict
+}
+
+var builtinDictionaries = []rankedDictionary{
+	newRankedDictionary(passwordsDictionary, strings.Fields(commonPasswords)),
+	newRankedDictionary(englishDictionary, strings.Fields(englishWords)),
+	newRankedDictionary(namesDictionary, strings.Fields(commonNames)),
+}
+
+// userInputDictionary ranks information an attacker knows about the
+// account, such as the service and username, together with their parts.
+func userInputDictionary(inputs []string) rankedDictionary {
+	var words []string
+	for _, input := range inputs {
+		words = append(words, input)
+		words = append(words, strings.FieldsFunc(input, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+	return newRankedDictionary(userInputsDictionary, words)
+}
+
+// commonPasswords are frequently leaked passwords, most common first.
+const commonPasswords = `
+123456 password 12345678 qwerty 123456789 12345 1234 111111 1234567 dragon
+123123 baseball abc123 football monkey letmein 696969 shadow master 666666
+qwertyuiop 123321 mustang 1234567890 michael 654321 superman 1qaz2wsx 7777777
+121212 000000 qazwsx 123qwe killer trustno1 jordan jennifer zxcvbnm asdfgh
+hunter buster soccer harley batman andrew tigger sunshine iloveyou 2000
+charlie robert thomas hockey ranger daniel starwars klaster 112233 george
+computer michelle jessica pepper 1111 zxcvbn 555555 11111111 131313 freedom
+777777 pass maggie 159753 aaaaaa ginger princess joshua cheese amanda summer
+love ashley 6969 nicole chelsea biteme matthew access yankees 987654321 dallas
+austin thunder taylor matrix minecraft william corvette hello martin heather
+secret merlin diamond 1234qwer hammer silver 222222 88888888 anthony justin
+test bailey q1w2e3r4t5 patrick internet scooter orange 11111 golfer cookie
+richard samantha bigdog guitar jackson whatever mickey chicken sparky snoopy
+maverick phoenix camaro peanut morgan welcome falcon cowboy ferrari samsung
+andrea smokey steelers joseph mercedes dakota arsenal eagles melissa boomer
+booboo spider nascar monster tigers yellow xxxxxx 123123123 gateway marina
+diablo bulldog qwer1234 compaq purple banana junior hannah 123654 porsche
+lakers iceman money cowboys 987654 london tennis 999999 ncc1701 coffee scooby
+0000 miller boston q1w2e3r4 brandon yamaha chester mother forever johnny
+edward 333333 oliver redsox player nikita knight fender barney midnight please
+brandy chicago badboy slayer rangers charles angel flower bigdaddy rabbit
+wizard jasper enter rachel chris steven winner adidas victoria natasha
+1q2w3e4r jasmine winter prince marine fishing cocacola casper james 232323
+raiders 888888 marlboro gandalf asdfasdf crystal 87654321 12344321 golden
+8675309 panther lauren angela spanky thx1138 angels madison winston shannon
+mike toyota jordan23 canada sophie apples tiger razz 123abc pokemon qazxsw
+55555 qwaszx muffin johnson murphy cooper jonathan liverpoo david danielle
+159357 jackie 1990 123456a 789456 turtle abcd1234 scorpion qazwsxedc 101010
+butter carlos password1 dennis slipknot qwerty123 booger asdf 1991 black
+startrek 12341234 cameron newyork rainbow nathan john 1992 rocket viking
+redskins asdfghjkl 1212 sierra peaches gemini doctor wilson sandra helpme
+qwertyui victor florida dolphin pookie captain tucker blue liverpool theman
+bandit dolphins maddog packers jaguar lovers nicholas united tiffany maxwell
+zzzzzz nirvana jeremy monica elephant giants hotdog rosebud success debbie
+mountain 444444 xxxxxxxx warrior 1q2w3e4r5t q1w2e3 123456q albert metallic
+lucky azerty 7777 alex bond007 alexis 1111111 samson 5150 willie scorpio bonnie
+gators benjamin voodoo driver dexter 2112 jason calvin freddy 212121 creative
+12345a sydney rush2112 1989 asdfghjk red123 bubba 4815162342 passw0rd trouble
+gunner happy gordon legend jessie stella qwert eminem arthur apple nissan bear
+america 1qazxsw2 nothing parker 4444 rebecca qweqwe garfield 01012011 beavis
+69696969 jack asdasd december 2222 102030 252525 11223344 magic apollo skippy
+315475 girls kitten golf copper braves shelby godzilla beaver fred tomcat
+august buddy airborne 1993 1988 lifehack qqqqqq brooklyn animal platinum
+phantom online xavier darkness blink182 power fish green 789456123 voyager
+police travis 12qwaszx heaven snowball lover abcdef 00000 pakistan 007007
+walter playboy blazer cricket sniper donkey willow loveme saturn therock
+redwings bigboy pumpkin trinity williams nintendo digital destiny topgun
+runner marvin guinness chance bubbles testing fire november minnie kenneth
+welcome1 admin changeme letmein1 monkey1 abc123456 iloveyou1 1qaz2wsx3edc
+zaq12wsx admin123 root toor guest login default p@ssw0rd passw0rd1 qwerty1
+123qweasd 1q2w3e 1qaz 1234abcd password12 password123 football1 baseball1
+sunshine1 princess1 dragon1 master1 shadow1 superman1 hello123 test123
+`
+
+// englishWords are common English words, most frequent first.
+const englishWords = `
+the of and to in is you that it he was for on are as with his they at be
+this have from or one had by word but not what all were we when your can
+said there use an each which she do how their if will up other about out
+many then them these so some her would make like him into time has look two
+more write go see number no way could people my than first water been call
+who oil its now find long down day did get come made may part over new sound
+take only little work know place year live me back give most very after
+thing our just name good sentence man think say great where help through
+much before line right too mean old any same tell boy follow came want show
+also around form three small set put end does another well large must big
+even such because turn here why ask went men read need land different home
+us move try kind hand picture again change off play spell air away animal
+house point page letter mother answer found study still learn should world
+high every near add food between own below country plant last school father
+keep tree never start city earth eye light thought head under story saw left
+few while along might close something seem next hard open example begin life
+always those both paper together got group often run important until
+children side feet car mile night walk white sea began grow took river four
+carry state once book hear stop without second later miss idea enough eat
+face watch far indian real almost let above girl sometimes mountain cut young
+talk soon list song being leave family love sun moon star heart dream happy
+summer winter spring autumn flower garden orange apple banana cherry lemon
+music money power secret magic angel baby friend king queen prince princess
+dragon tiger lion horse dog cat bird fish monkey bear wolf eagle shark snake
+blue red green black yellow purple pink silver gold golden diamond crystal
+football soccer baseball hockey tennis golf basketball computer internet
+welcome hello login admin user guest master access password letmein freedom
+sunshine shadow thunder storm rain snow fire ice rock stone metal steel
+iron ocean beach island forest desert sky cloud wind storm coffee chocolate
+sugar candy cookie pizza cheese butter bread dinner lunch breakfast party
+holiday christmas birthday wedding summer sweet honey darling sweetheart
+forever always never nothing everything someone nobody anybody whatever
+correct horse battery staple trouble matrix phoenix galaxy planet rocket
+space rider ranger hunter killer warrior soldier knight wizard ninja pirate
+captain doctor teacher student office company business market window door
+table chair phone mobile email account bank secure private public personal
+`
+
+// commonNames are frequent first names and surnames.
+const commonNames = `
+james john robert michael william david richard joseph thomas charles
+christopher daniel matthew anthony mark donald steven paul andrew joshua
+kenneth kevin brian george timothy ronald edward jason jeffrey ryan jacob
+gary nicholas eric jonathan stephen larry justin scott brandon benjamin
+samuel gregory alexander frank patrick raymond jack dennis jerry tyler aaron
+jose adam nathan henry douglas zachary peter kyle walter ethan jeremy harold
+keith christian roger noah gerald carl terry sean austin arthur lawrence
+jesse dylan bryan joe jordan billy bruce albert willie gabriel logan alan
+juan wayne roy ralph randy eugene vincent russell elijah louis bobby philip
+johnny mary patricia jennifer linda elizabeth barbara susan jessica sarah
+karen lisa nancy betty margaret sandra ashley kimberly emily donna michelle
+carol amanda dorothy melissa deborah stephanie rebecca sharon laura cynthia
+kathleen amy angela shirley anna brenda pamela emma nicole helen samantha
+katherine christine debra rachel carolyn janet catherine maria heather diane
+ruth julie olivia joyce virginia victoria kelly lauren christina joan evelyn
+judith megan andrea cheryl hannah jacqueline martha gloria teresa ann sara
+madison frances kathryn janice jean abigail alice judy sophia grace denise
+amber doris marilyn danielle beverly isabella theresa diana natalie brittany
+charlotte marie kayla alexis lori alice smith johnson williams brown jones
+garcia miller davis rodriguez martinez hernandez lopez gonzalez wilson
+anderson thomas taylor moore jackson martin lee perez thompson white harris
+sanchez clark ramirez lewis robinson walker young allen king wright scott
+torres nguyen hill flores green adams nelson baker hall rivera campbell
+mitchell carter roberts gomez phillips evans turner diaz parker cruz edwards
+collins reyes stewart morris morales murphy cook rogers gutierrez ortiz
+morgan cooper peterson bailey reed kelly howard ramos kim cox ward richardson
+watson brooks chavez wood bennett gray mendoza ruiz hughes price alvarez
+castillo sanders patel myers long ross foster jimenez
+`
@@ -0,0 +1,83 @@
+package security
+
+// keyboard describes which keys touch on a staggered keyboard layout. Keys
+// are placed on a grid of half-key columns, so neighbours on the same row are
+// two columns apart and neighbours on the rows above and below are one
+// column apart.
+type keyboard struct {
+	keys    map[rune]keyPosition
+	grid    map[[2]int]bool
+	shifted map[rune]bool
+	// startingPositions and averageDegree feed the spatial guess estimate.
+	startingPositions int
+	averageDegree     float64
+}
+
+type keyPosition struct {
+	row, col int
+}
+
+// Directions are numbered clockwise from the left neighbour.
+var keyDirections = [6][2]int{{0, -2}, {-1, -1}, {-1, 1}, {0, 2}, {1, 1}, {1, -1}}
+
+var qwerty = newKeyboard(
+	[]string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"},
+	[]string{"~!@#$%^&*()_+", "QWERTYUIOP{}|", "ASDFGHJKL:\"", "ZXCVBNM<>?"},
+	[]int{0, 3, 4, 5},
+)
+
+func newKeyboard(rows, shiftedRows []string, offsets []int) *keyboard {
+	kb := &keyboard{
+		keys:    make(map[rune]keyPosition),
+		grid:    make(map[[2]int]bool),
+		shifted: make(map[rune]bool),
+	}
+
+	key := 0
+	for r, row := range rows {
+		shiftedRow := []rune(shiftedRows[r])
+		for c, ch := range []rune(row) {
+			pos := keyPosition{row: r, col: offsets[r] + 2*c}
+			kb.grid[[2]int{pos.row, pos.col}] = true
+			kb.keys[ch] = pos
+			kb.keys[shiftedRow[c]] = pos
+			kb.shifted[shiftedRow[c]] = true
+			key++
+		}
+	}
+
+	degrees := 0
+	for pos := range kb.grid {
+		for _, d := range keyDirections {
+			if kb.grid[[2]int{pos[0] + d[0], pos[1] + d[1]}] {
+				degrees++
+			}
+		}
+	}
+	kb.startingPositions = key
+	kb.averageDegree = float64(degrees) / float64(key)
+	return kb
+}
+
+func (kb *keyboard) isShifted(r rune) bool {
+	return kb.shifted[r]
+}
+
+// direction returns the direction from key a to the neighbouring key b, or
+// -1 if they are not neighbours.
+func (kb *keyboard) direction(a, b rune) int {
+	from, ok := kb.keys[a]
+	if !ok {
+		return -1
+	}
+	to, ok := kb.keys[b]
+	if !ok {
+		return -1
+	}
+	for i, d := range keyDirections {
+		if from.row+d[0] == to.row && from.col+d[1] == to.col {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,529 @@
+package security
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Match is a part of a password explained by a guessable pattern.
+type Match struct {
+	Pattern string
+	// I and J are the first and last rune of Token in the password.
+	I, J    int
+	Token   string
+	Guesses float64
+
+	// Dictionary matches.
+	Dictionary  string
+	MatchedWord string
+	Rank        int
+	Reversed    bool
+	L33t        bool
+	Sub         map[rune]rune
+
+	// Spatial matches.
+	Turns        int
+	ShiftedCount int
+
+	// Repeat matches.
+	BaseToken   string
+	BaseGuesses float64
+	RepeatCount int
+
+	// Sequence matches.
+	Ascending bool
+
+	// Date and year matches.
+	Year      int
+	Separator string
+}
+
+const (
+	dictionaryPattern = "dictionary"
+	spatialPattern    = "spatial"
+	repeatPattern     = "repeat"
+	sequencePattern   = "sequence"
+	yearPattern       = "year"
+	datePattern       = "date"
+	bruteforcePattern = "bruteforce"
+)
+
+func omnimatch(password []rune, dicts []rankedDictionary) []Match {
+	var matches []Match
+	matches = append(matches, dictionaryMatches(password, dicts)...)
+	matches = append(matches, reverseDictionaryMatches(password, dicts)...)
+	matches = append(matches, l33tMatches(password, dicts)...)
+	matches = append(matches, spatialMatches(password)...)
+	matches = append(matches, repeatMatches(password, dicts)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, yearMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].I != matches[b].I {
+			return matches[a].I < matches[b].I
+		}
+		return matches[a].J < matches[b].J
+	})
+	return matches
+}
+
+func lowerRunes(password []rune) []rune {
+	lower := make([]rune, len(password))
+	for i, r := range password {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func dictionaryMatches(password []rune, dicts []rankedDictionary) []Match {
+	lower := lowerRunes(password)
+
+	var matches []Match
+	for i := range lower {
+		for j := i; j < len(lower) && j-i < maxWordLength; j++ {
+			word := string(lower[i : j+1])
+			for _, dict := range dicts {
+				if rank, ok := dict.ranks[word]; ok {
+					matches = append(matches, Match{
+						Pattern:     dictionaryPattern,
+						I:           i,
+						J:           j,
+						Token:       string(password[i : j+1]),
+						Dictionary:  dict.name,
+						MatchedWord: word,
+						Rank:        rank,
+					})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func reverseRunes(s []rune) []rune {
+	reversed := make([]rune, len(s))
+	for i, r := range s {
+		reversed[len(s)-1-i] = r
+	}
+	return reversed
+}
+
+func reverseDictionaryMatches(password []rune, dicts []rankedDictionary) []Match {
+	n := len(password)
+	var matches []Match
+	for _, m := range dictionaryMatches(reverseRunes(password), dicts) {
+		// Palindromes are already found the normal way.
+		if len(m.MatchedWord) == 1 || string(reverseRunes([]rune(m.MatchedWord))) == m.MatchedWord {
+			continue
+		}
+		m.I, m.J = n-1-m.J, n-1-m.I
+		m.Token = string(password[m.I : m.J+1])
+		m.Reversed = true
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// l33tTable lists the characters commonly substituted for each letter.
+var l33tTable = map[rune][]rune{
+	'a': {'4', '@'},
+	'b': {'8'},
+	'c': {'(', '{', '[', '<'},
+	'e': {'3'},
+	'g': {'6', '9'},
+	'i': {'1', '!', '|'},
+	'l': {'1', '|', '7'},
+	'o': {'0'},
+	's': {'$', '5'},
+	't': {'+', '7'},
+	'x': {'%'},
+	'z': {'2'},
+}
+
+// l33tSubs returns every way to read the substitution characters found in
+// password back as letters.
+func l33tSubs(password []rune) []map[rune]rune {
+	candidates := make(map[rune][]rune)
+	for letter, subs := range l33tTable {
+		for _, sub := range subs {
+			for _, r := range password {
+				if r == sub {
+					candidates[sub] = append(candidates[sub], letter)
+					break
+				}
+			}
+		}
+	}
+
+	chars := make([]rune, 0, len(candidates))
+	for c := range candidates {
+		chars = append(chars, c)
+		sort.Slice(candidates[c], func(a, b int) bool { return candidates[c][a] < candidates[c][b] })
+	}
+	sort.Slice(chars, func(a, b int) bool { return chars[a] < chars[b] })
+
+	subs := []map[rune]rune{{}}
+	for _, c := range chars {
+		var next []map[rune]rune
+		for _, sub := range subs {
+			for _, letter := range candidates[c] {
+				extended := make(map[rune]rune, len(sub)+1)
+				for k, v := range sub {
+					extended[k] = v
+				}
+				extended[c] = letter
+				next = append(next, extended)
+			}
+		}
+		subs = next
+	}
+	return subs
+}
+
+func l33tMatches(password []rune, dicts []rankedDictionary) []Match {
+	var matches []Match
+	seen := make(map[string]bool)
+	for _, sub := range l33tSubs(password) {
+		if len(sub) == 0 {
+			continue
+		}
+
+		translated := make([]rune, len(password))
+		for i, r := range password {
+			if letter, ok := sub[r]; ok {
+				translated[i] = letter
+			} else {
+				translated[i] = r
+			}
+		}
+
+		for _, m := range dictionaryMatches(translated, dicts) {
+			token := password[m.I : m.J+1]
+			if len(token) == 1 || strings.ToLower(string(token)) == m.MatchedWord {
+				continue
+			}
+
+			used := make(map[rune]rune)
+			for _, r := range token {
+				if letter, ok := sub[r]; ok {
+					used[r] = letter
+				}
+			}
+			key := strconv.Itoa(m.I) + ":" + strconv.Itoa(m.J) + ":" + m.Dictionary + ":" + m.MatchedWord
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			m.Token = string(token)
+			m.L33t = true
+			m.Sub = used
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func spatialMatches(password []rune) []Match {
+	var matches []Match
+	i := 0
+	for i < len(password)-1 {
+		j := i + 1
+		lastDirection := -1
+		turns := 0
+		shifted := 0
+		if qwerty.isShifted(password[i]) {
+			shifted = 1
+		}
+
+		for {
+			direction := -1
+			if j < len(password) {
+				direction = qwerty.direction(password[j-1], password[j])
+			}
+			if direction >= 0 {
+				if qwerty.isShifted(password[j]) {
+					shifted++
+				}
+				if direction != lastDirection {
+					turns++
+					lastDirection = direction
+				}
+				j++
+				continue
+			}
+
+			if j-i > 2 {
+				matches = append(matches, Match{
+					Pattern:      spatialPattern,
+					I:            i,
+					J:            j - 1,
+					Token:        string(password[i:j]),
+					Turns:        turns,
+					ShiftedCount: shifted,
+				})
+			}
+			i = j
+			break
+		}
+	}
+	return matches
+}
+
+// repeatMatches finds runs of a repeated unit, preferring the unit that
+// covers the most characters, so "abcabcabc" is "abc" three times.
+func repeatMatches(password []rune, dicts []rankedDictionary) []Match {
+	var matches []Match
+	i := 0
+	for i < len(password) {
+		bestUnit, bestCount := 0, 0
+		for unit := 1; i+2*unit <= len(password); unit++ {
+			count := 1
+			for i+(count+1)*unit <= len(password) &&
+				string(password[i+count*unit:i+(count+1)*unit]) == string(password[i:i+unit]) {
+				count++
+			}
+			if count > 1 && unit*count > bestUnit*bestCount {
+				bestUnit, bestCount = unit, count
+			}
+		}
+
+		if bestCount < 2 {
+			i++
+			continue
+		}
+
+		base := password[i : i+bestUnit]
+		end := i + bestUnit*bestCount
+		matches = append(matches, Match{
+			Pattern:     repeatPattern,
+			I:           i,
+			J:           end - 1,
+			Token:       string(password[i:end]),
+			BaseToken:   string(base),
+			BaseGuesses: mostGuessable(base, omnimatch(base, dicts)).guesses,
+			RepeatCount: bestCount,
+		})
+		i = end
+	}
+	return matches
+}
+
+const maxSequenceDelta = 5
+
+func sequenceMatches(password []rune) []Match {
+	if len(password) < 3 {
+		return nil
+	}
+
+	var matches []Match
+	emit := func(i, j, delta int) {
+		if j-i < 2 || delta == 0 || delta > maxSequenceDelta || delta < -maxSequenceDelta {
+			return
+		}
+		matches = append(matches, Match{
+			Pattern:   sequencePattern,
+			I:         i,
+			J:         j,
+			Token:     string(password[i : j+1]),
+			Ascending: delta > 0,
+		})
+	}
+
+	i := 0
+	lastDelta := 0
+	for k := 1; k < len(password); k++ {
+		delta := int(password[k] - password[k-1])
+		if k == 1 {
+			lastDelta = delta
+		}
+		if delta == lastDelta {
+			continue
+		}
+		emit(i, k-1, lastDelta)
+		i = k - 1
+		lastDelta = delta
+	}
+	emit(i, len(password)-1, lastDelta)
+	return matches
+}
+
+// referenceYear is the year dates and years are judged against.
+var referenceYear = time.Now().Year()
+
+func yearMatches(password []rune) []Match {
+	var matches []Match
+	for i := 0; i+4 <= len(password); i++ {
+		token := string(password[i : i+4])
+		year, err := strconv.Atoi(token)
+		if err != nil || !strings.HasPrefix(token, "19") && !strings.HasPrefix(token, "20") {
+			continue
+		}
+		if year > referenceYear+50 {
+			continue
+		}
+		matches = append(matches, Match{Pattern: yearPattern, I: i, J: i + 3, Token: token, Year: year})
+	}
+	return matches
+}
+
+const (
+	minYear = 1000
+	maxYear = 2050
+)
+
+// dateSplits lists where to split digit-only dates of each length into day,
+// month and year parts.
+var dateSplits = map[int][][2]int{
+	4: {{1, 2}, {2, 3}},
+	5: {{1, 3}, {2, 3}},
+	6: {{1, 2}, {2, 4}, {4, 5}},
+	7: {{1, 3}, {2, 3}, {4, 5}, {4, 6}},
+	8: {{2, 4}, {4, 6}},
+}
+
+func isDateSeparator(r rune) bool {
+	return r == ' ' || r == '-' || r == '/' || r == '\\' || r == '_' || r == '.'
+}
+
+func dateMatches(password []rune) []Match {
+	var matches []Match
+
+	for i := 0; i < len(password); i++ {
+		for j := i + 3; j < len(password) && j-i < 10; j++ {
+			token := password[i : j+1]
+			year, separator, ok := parseDate(token)
+			if !ok {
+				continue
+			}
+			matches = append(matches, Match{
+				Pattern:   datePattern,
+				I:         i,
+				J:         j,
+				Token:     string(token),
+				Year:      year,
+				Separator: separator,
+			})
+		}
+	}
+
+	// Drop dates inside longer dates, such as "1/1/91" within "11/1/91".
+	var filtered []Match
+	for a, m := range matches {
+		contained := false
+		for b, other := range matches {
+			if a != b && other.I <= m.I && other.J >= m.J && (other.I != m.I || other.J != m.J) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// parseDate reads token as a day, month and year in any common order, with or
+// without separators, and returns the year closest to referenceYear.
+func parseDate(token []rune) (int, string, bool) {
+	var candidates [][3]int
+	separator := ""
+
+	allDigits := true
+	for _, r := range token {
+		if !unicode.IsDigit(r) {
+			allDigits = false
+			break
+		}
+	}
+
+	switch {
+	case allDigits:
+		for _, split := range dateSplits[len(token)] {
+			parts := [3]int{}
+			for p, s := range [][2]int{{0, split[0]}, {split[0], split[1]}, {split[1], len(token)}} {
+				parts[p], _ = strconv.Atoi(string(token[s[0]:s[1]]))
+			}
+			candidates = append(candidates, parts)
+		}
+	case len(token) >= 6:
+		sep := -1
+		for k, r := range token {
+			if !unicode.IsDigit(r) {
+				if !isDateSeparator(r) {
+					return 0, "", false
+				}
+				if sep < 0 {
+					sep = k
+				}
+			}
+		}
+		fields := strings.Split(string(token), string(token[sep]))
+		if len(fields) != 3 {
+			return 0, "", false
+		}
+		parts := [3]int{}
+		for p, f := range fields {
+			if f == "" || len(f) > 4 {
+				return 0, "", false
+			}
+			n, err := strconv.Atoi(f)
+			if err != nil {
+				return 0, "", false
+			}
+			parts[p] = n
+		}
+		separator = string(token[sep])
+		candidates = append(candidates, parts)
+	default:
+		return 0, "", false
+	}
+
+	best, found := 0, false
+	for _, parts := range candidates {
+		year, ok := dateYear(parts)
+		if !ok {
+			continue
+		}
+		if !found || abs(year-referenceYear) < abs(best-referenceYear) {
+			best, found = year, true
+		}
+	}
+	return best, separator, found
+}
+
+// dateYear checks the year-first and year-last orders of a date and returns
+// its year.
+func dateYear(parts [3]int) (int, bool) {
+	validDayMonth := func(a, b int) bool {
+		return (a >= 1 && a <= 31 && b >= 1 && b <= 12) || (b >= 1 && b <= 31 && a >= 1 && a <= 12)
+	}
+	for _, order := range [][3]int{{2, 0, 1}, {0, 1, 2}} {
+		year := parts[order[0]]
+		if !validDayMonth(parts[order[1]], parts[order[2]]) {
+			continue
+		}
+		switch {
+		case year >= minYear && year <= maxYear:
+			return year, true
+		case year >= 0 && year <= 99:
+			if year > 50 {
+				return 1900 + year, true
+			}
+			return 2000 + year, true
+		}
+	}
+	return 0, false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
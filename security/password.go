@@ -1,6 +1,7 @@
 package security
 
 import (
+	"fmt"
 	"math"
 	"strings"
 	"unicode"
@@ -16,12 +17,56 @@ const (
 	VeryStrong
 )
 
+// StrengthResult estimates how many guesses an attacker who knows common
+// password patterns needs to find a password.
 type StrengthResult struct {
-	Level       StrengthLevel
-	Score       float64
-	Suggestions []string
+	Level StrengthLevel
+	// Score maps the guesses onto 0-100 on a log scale, reaching 100 at
+	// 10^12 guesses.
+	Score        float64
+	Guesses      float64
+	GuessesLog10 float64
+	CrackTimes   []CrackTime
+	Warning      string
+	Suggestions  []string
+	// Sequence is the cheapest set of patterns that explains the password.
+	Sequence []Match
 }
 
+// CrackTime is how long an attacker guessing at a given rate needs.
+type CrackTime struct {
+	Scenario string
+	Seconds  float64
+}
+
+func (c CrackTime) String() string {
+	return FormatDuration(c.Seconds)
+}
+
+// Attack scenarios, in guesses per second.
+var crackScenarios = []struct {
+	name string
+	rate float64
+}{
+	{"Online attack, throttled (100/hour)", 100.0 / 3600},
+	{"Online attack, unthrottled (10/second)", 10},
+	{"Offline attack, slow hash (10k/second)", 1e4},
+	{"Offline attack, fast hash (10B/second)", 1e10},
+}
+
+// Level thresholds in guesses, following zxcvbn.
+var levelThresholds = []float64{1e3, 1e6, 1e8, 1e10}
+
+const maxScoreGuessesLog10 = 12
+
+// maxGuessesLog10 caps the estimate well below the largest float64, so
+// Guesses and the crack times stay finite for very long passwords.
+const maxGuessesLog10 = 300
+
+// maxAnalyzedLength bounds the pattern search; longer passwords are strong
+// regardless of how the tail is built.
+const maxAnalyzedLength = 100
+
 func (s StrengthLevel) String() string {
 	switch s {
 	case VeryWeak:
@@ -39,97 +84,178 @@ func (s StrengthLevel) String() string {
 	}
 }
 
-func AnalyzePassword(password string) StrengthResult {
-	var result StrengthResult
-	var suggestions []string
+// AnalyzePassword estimates the strength of password by matching dictionary
+// words, l33t substitutions, keyboard walks, dates, sequences and repeats.
+// userInputs, such as the service and username, count as known words.
+func AnalyzePassword(password string, userInputs ...string) StrengthResult {
+	runes := []rune(password)
+	extraLog := 0.0
+	if len(runes) > maxAnalyzedLength {
+		extraLog = float64(len(runes) - maxAnalyzedLength)
+		runes = runes[:maxAnalyzedLength]
+	}
 
-	length := len(password)
-	hasUpper := false
-	hasLower := false
-	hasNumber := false
-	hasSpecial := false
-	hasSpace := false
-	repeatCount := 0
-	prevChar := rune(0)
-
-	for _, char := range password {
-		if unicode.IsUpper(char) {
-			hasUpper = true
-		}
-		if unicode.IsLower(char) {
-			hasLower = true
-		}
-		if unicode.IsNumber(char) {
-			hasNumber = true
-		}
-		if unicode.IsPunct(char) || unicode.IsSymbol(char) {
-			hasSpecial = true
-		}
-		if unicode.IsSpace(char) {
-			hasSpace = true
-		}
-		if char == prevChar {
-			repeatCount++
+	dicts := builtinDictionaries
+	if len(userInputs) > 0 {
+		dicts = append(append([]rankedDictionary{}, builtinDictionaries...), userInputDictionary(userInputs))
+	}
+
+	est := mostGuessable(runes, omnimatch(runes, dicts))
+	logGuesses := math.Min(est.log10+extraLog, maxGuessesLog10)
+
+	result := StrengthResult{
+		Guesses:      math.Pow(10, logGuesses),
+		GuessesLog10: logGuesses,
+		Score:        math.Max(0, math.Min(100, logGuesses/maxScoreGuessesLog10*100)),
+		Sequence:     est.sequence,
+	}
+
+	for _, threshold := range levelThresholds {
+		if result.Guesses < threshold {
+			break
 		}
-		prevChar = char
+		result.Level++
+	}
+
+	for _, s := range crackScenarios {
+		result.CrackTimes = append(result.CrackTimes, CrackTime{Scenario: s.name, Seconds: result.Guesses / s.rate})
 	}
 
-	score := 0.0
+	result.Warning, result.Suggestions = feedback(password, result.Level, est.sequence)
+	return result
+}
 
-	score += math.Min(float64(length)*4, 40)
-	if hasUpper {
-		score += 10
-	} else {
-		suggestions = append(suggestions, "Add uppercase letters")
+// FormatDuration renders seconds the way crack times are usually shown.
+func FormatDuration(seconds float64) string {
+	const (
+		minute  = 60
+		hour    = minute * 60
+		day     = hour * 24
+		month   = day * 31
+		year    = month * 12
+		century = year * 100
+	)
+
+	units := []struct {
+		name string
+		size float64
+	}{
+		{"year", year}, {"month", month}, {"day", day}, {"hour", hour}, {"minute", minute}, {"second", 1},
 	}
-	if hasLower {
-		score += 10
-	} else {
-		suggestions = append(suggestions, "Add lowercase letters")
+
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds >= century:
+		return "centuries"
 	}
-	if hasNumber {
-		score += 10
-	} else {
-		suggestions = append(suggestions, "Add numbers")
+	for _, u := range units {
+		if seconds >= u.size {
+			n := int(math.Round(seconds / u.size))
+			if n == 1 {
+				return fmt.Sprintf("1 %s", u.name)
+			}
+			return fmt.Sprintf("%d %ss", n, u.name)
+		}
 	}
-	if hasSpecial {
-		score += 15
-	} else {
-		suggestions = append(suggestions, "Add special characters")
+	return "less than a second"
+}
+
+func feedback(password string, level StrengthLevel, sequence []Match) (string, []string) {
+	if password == "" {
+		return "", []string{
+			"Use a few words, avoid common phrases",
+			"No need for symbols, digits, or uppercase letters",
+		}
 	}
-	if hasSpace {
-		score += 5
+	if level >= Strong {
+		return "", nil
 	}
 
-	score -= float64(repeatCount) * 2
+	// Explain the longest pattern, since it does the most damage.
+	var longest *Match
+	for i := range sequence {
+		if longest == nil || len([]rune(sequence[i].Token)) > len([]rune(longest.Token)) {
+			longest = &sequence[i]
+		}
+	}
 
-	if length < 8 {
-		suggestions = append(suggestions, "Make the password longer (at least 8 characters)")
+	suggestions := []string{"Add another word or two. Uncommon words are better."}
+	if longest == nil {
+		return "", suggestions
 	}
 
-	commonPatterns := []string{"123", "abc", "qwerty", "password", "admin"}
-	for _, pattern := range commonPatterns {
-		if strings.Contains(strings.ToLower(password), pattern) {
-			score -= 10
-			suggestions = append(suggestions, "Avoid common patterns like '"+pattern+"'")
+	warning, extra := matchFeedback(*longest, len(sequence) == 1)
+	return warning, append(suggestions, extra...)
+}
+
+func matchFeedback(m Match, soleMatch bool) (string, []string) {
+	switch m.Pattern {
+	case dictionaryPattern:
+		return dictionaryFeedback(m, soleMatch)
+	case spatialPattern:
+		warning := "Short keyboard patterns are easy to guess"
+		if m.Turns == 1 {
+			warning = "Straight rows of keys are easy to guess"
+		}
+		return warning, []string{"Use a longer keyboard pattern with more turns"}
+	case repeatPattern:
+		warning := `Repeats like "abcabcabc" are only slightly harder to guess than "abc"`
+		if len([]rune(m.BaseToken)) == 1 {
+			warning = `Repeats like "aaa" are easy to guess`
+		}
+		return warning, []string{"Avoid repeated words and characters"}
+	case sequencePattern:
+		return "Sequences like abc or 6543 are easy to guess", []string{"Avoid sequences"}
+	case yearPattern:
+		return "Recent years are easy to guess", []string{
+			"Avoid recent years",
+			"Avoid years that are associated with you",
 		}
+	case datePattern:
+		return "Dates are often easy to guess", []string{"Avoid dates and years that are associated with you"}
 	}
+	return "", nil
+}
 
-	result.Score = math.Max(0, math.Min(100, score))
-	result.Suggestions = suggestions
-
-	switch {
-	case result.Score >= 80:
-		result.Level = VeryStrong
-	case result.Score >= 60:
-		result.Level = Strong
-	case result.Score >= 40:
-		result.Level = Medium
-	case result.Score >= 20:
-		result.Level = Weak
-	default:
-		result.Level = VeryWeak
+func dictionaryFeedback(m Match, soleMatch bool) (string, []string) {
+	var warning string
+	switch m.Dictionary {
+	case passwordsDictionary:
+		switch {
+		case soleMatch && !m.L33t && !m.Reversed && m.Rank <= 10:
+			warning = "This is a top-10 common password"
+		case soleMatch && !m.L33t && !m.Reversed && m.Rank <= 100:
+			warning = "This is a top-100 common password"
+		default:
+			warning = "This is similar to a commonly used password"
+		}
+	case englishDictionary:
+		if soleMatch {
+			warning = "A word by itself is easy to guess"
+		}
+	case namesDictionary:
+		if soleMatch {
+			warning = "Names and surnames by themselves are easy to guess"
+		} else {
+			warning = "Common names and surnames are easy to guess"
+		}
+	case userInputsDictionary:
+		warning = "The service name or username makes this password easy to guess"
 	}
 
-	return result
+	var suggestions []string
+	word := m.Token
+	if strings.ToUpper(word) == word && strings.ToLower(word) != word {
+		suggestions = append(suggestions, "All-uppercase is almost as easy to guess as all-lowercase")
+	} else if r := []rune(word); unicode.IsUpper(r[0]) {
+		suggestions = append(suggestions, "Capitalization doesn't help very much")
+	}
+	if m.Reversed && len([]rune(word)) >= 4 {
+		suggestions = append(suggestions, "Reversed words aren't much harder to guess")
+	}
+	if m.L33t {
+		suggestions = append(suggestions, "Predictable substitutions like '@' instead of 'a' don't help very much")
+	}
+	return warning, suggestions
 }
@@ -0,0 +1,84 @@
+package security
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func hasPattern(result StrengthResult, pattern string) bool {
+	for _, m := range result.Sequence {
+		if m.Pattern == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAnalyzePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		maxLevel StrengthLevel
+		pattern  string
+	}{
+		{"Password123!", Weak, dictionaryPattern},
+		{"password", VeryWeak, dictionaryPattern},
+		{"P@ssw0rd", Weak, dictionaryPattern},
+		{"asdfghjkl;'", Weak, spatialPattern},
+		{"hjkluiop", Weak, spatialPattern},
+		{"qwerfdsazxcv", Medium, spatialPattern},
+		{"12/25/1990", Weak, datePattern},
+		{"19900525", Weak, datePattern},
+		{"abcdefgh", VeryWeak, sequencePattern},
+		{"aaaaaaaaaa", VeryWeak, repeatPattern},
+	}
+	for _, tt := range tests {
+		result := AnalyzePassword(tt.password)
+		if result.Level > tt.maxLevel {
+			t.Errorf("%q: level %s, want at most %s", tt.password, result.Level, tt.maxLevel)
+		}
+		if !hasPattern(result, tt.pattern) {
+			t.Errorf("%q: no %s match in %+v", tt.password, tt.pattern, result.Sequence)
+		}
+		if result.Warning == "" {
+			t.Errorf("%q: no warning", tt.password)
+		}
+	}
+
+	for _, password := range []string{"correct horse battery staple", "tR7#vQ9!mZ2&kL4x"} {
+		if result := AnalyzePassword(password); result.Level < Strong {
+			t.Errorf("%q: level %s, want at least Strong", password, result.Level)
+		}
+	}
+}
+
+func TestAnalyzePasswordUserInputs(t *testing.T) {
+	plain := AnalyzePassword("octocat2024")
+	withInputs := AnalyzePassword("octocat2024", "GitHub", "octocat")
+	if withInputs.Guesses >= plain.Guesses {
+		t.Errorf("the username did not lower the estimate: %g >= %g", withInputs.Guesses, plain.Guesses)
+	}
+}
+
+func TestAnalyzePasswordGuessesFinite(t *testing.T) {
+	var b strings.Builder
+	for b.Len() < 5000 {
+		b.WriteString("xQ7#")
+	}
+	result := AnalyzePassword(b.String() + strings.Repeat("z", 400))
+	if math.IsInf(result.Guesses, 0) || math.IsNaN(result.Guesses) || result.GuessesLog10 > maxGuessesLog10 {
+		t.Fatalf("guesses = %g (10^%g)", result.Guesses, result.GuessesLog10)
+	}
+	for _, c := range result.CrackTimes {
+		if math.IsInf(c.Seconds, 0) {
+			t.Errorf("%s: infinite crack time", c.Scenario)
+		}
+	}
+	if result.Level != VeryStrong || result.Score != 100 {
+		t.Errorf("level %s, score %g", result.Level, result.Score)
+	}
+	if _, err := json.Marshal(result); err != nil {
+		t.Errorf("cannot encode the result: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package security
+
+import (
+	"math"
+	"unicode"
+)
+
+const (
+	bruteforceCardinality = 10
+	// minGuessesBeforeGrowingSequence penalises explaining a password with
+	// more patterns than it needs.
+	minGuessesBeforeGrowingSequence = 10000
+	minSubmatchGuessesSingleChar    = 10
+	minSubmatchGuessesMultiChar     = 50
+	minYearSpace                    = 20
+)
+
+// estimate is the cheapest way found to guess a password: the sequence of
+// matches covering it and the number of guesses that sequence needs.
+type estimate struct {
+	guesses  float64
+	log10    float64
+	sequence []Match
+}
+
+type searchState struct {
+	logPi float64
+	match Match
+	prevL int
+}
+
+// mostGuessable finds the sequence of non-overlapping matches, with
+// bruteforce filling the gaps, that minimises
+//
+//	l! * product(match guesses) + minGuessesBeforeGrowingSequence^(l-1)
+//
+// where l is the number of matches. It works in log10 to avoid overflow.
+func mostGuessable(password []rune, matches []Match) estimate {
+	n := len(password)
+	if n == 0 {
+		return estimate{guesses: 1}
+	}
+
+	byEnd := make([][]Match, n)
+	for _, m := range matches {
+		m.Guesses = matchGuesses(m, n)
+		byEnd[m.J] = append(byEnd[m.J], m)
+	}
+
+	states := make([]map[int]searchState, n)
+	for k := range states {
+		states[k] = make(map[int]searchState)
+	}
+	update := func(k, l int, logPi float64, m Match, prevL int) {
+		if s, ok := states[k][l]; ok && s.logPi <= logPi {
+			return
+		}
+		states[k][l] = searchState{logPi: logPi, match: m, prevL: prevL}
+	}
+
+	for k := 0; k < n; k++ {
+		candidates := byEnd[k]
+		for i := 0; i <= k; i++ {
+			candidates = append(candidates, bruteforceMatch(password, i, k))
+		}
+
+		for _, m := range candidates {
+			logG := math.Log10(m.Guesses)
+			if m.I == 0 {
+				update(k, 1, logG, m, 0)
+				continue
+			}
+			for l, prev := range states[m.I-1] {
+				update(k, l+1, prev.logPi+logG, m, l)
+			}
+		}
+	}
+
+	bestL, bestLog := 0, math.Inf(1)
+	for l, s := range states[n-1] {
+		logG := log10Sum(logFactorial(l)+s.logPi, float64(l-1)*math.Log10(minGuessesBeforeGrowingSequence))
+		if logG < bestLog || (logG == bestLog && l < bestL) {
+			bestL, bestLog = l, logG
+		}
+	}
+
+	sequence := make([]Match, bestL)
+	for k, l := n-1, bestL; l > 0; {
+		s := states[k][l]
+		sequence[l-1] = s.match
+		k, l = s.match.I-1, s.prevL
+	}
+
+	return estimate{guesses: math.Pow(10, bestLog), log10: bestLog, sequence: sequence}
+}
+
+func bruteforceMatch(password []rune, i, j int) Match {
+	m := Match{Pattern: bruteforcePattern, I: i, J: j, Token: string(password[i : j+1])}
+	m.Guesses = matchGuesses(m, len(password))
+	return m
+}
+
+func log10Sum(a, b float64) float64 {
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log10(1+math.Pow(10, b-a))
+}
+
+func logFactorial(n int) float64 {
+	lg, _ := math.Lgamma(float64(n + 1))
+	return lg / math.Ln10
+}
+
+func matchGuesses(m Match, passwordLen int) float64 {
+	tokenLen := len([]rune(m.Token))
+
+	minGuesses := 1.0
+	if tokenLen < passwordLen {
+		minGuesses = minSubmatchGuessesMultiChar
+		if tokenLen == 1 {
+			minGuesses = minSubmatchGuessesSingleChar
+		}
+	}
+
+	var guesses float64
+	switch m.Pattern {
+	case bruteforcePattern:
+		guesses = math.Pow(bruteforceCardinality, float64(tokenLen))
+		if tokenLen == 1 {
+			guesses = math.Max(guesses, minSubmatchGuessesSingleChar+1)
+		} else {
+			guesses = math.Max(guesses, minSubmatchGuessesMultiChar+1)
+		}
+	case dictionaryPattern:
+		guesses = float64(m.Rank) * uppercaseVariations(m.Token) * l33tVariations(m)
+		if m.Reversed {
+			guesses *= 2
+		}
+	case spatialPattern:
+		guesses = spatialGuesses(m)
+	case repeatPattern:
+		guesses = m.BaseGuesses * float64(m.RepeatCount)
+	case sequencePattern:
+		guesses = sequenceGuesses(m)
+	case yearPattern:
+		guesses = math.Max(float64(abs(m.Year-referenceYear)), minYearSpace)
+	case datePattern:
+		guesses = math.Max(float64(abs(m.Year-referenceYear)), minYearSpace) * 365
+		if m.Separator != "" {
+			guesses *= 4
+		}
+	}
+	return math.Max(guesses, minGuesses)
+}
+
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// uppercaseVariations counts the capitalisations an attacker tries before
+// reaching the one in token. Capitalising only the first or last letter, or
+// every letter, is common and barely helps.
+func uppercaseVariations(token string) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+
+	runes := []rune(token)
+	first, last := runes[0], runes[len(runes)-1]
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(first) || unicode.IsUpper(last))) {
+		return 2
+	}
+
+	variations := 0.0
+	for i := 1; i <= upper && i <= lower; i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+func l33tVariations(m Match) float64 {
+	if !m.L33t {
+		return 1
+	}
+
+	variations := 1.0
+	for sub, letter := range m.Sub {
+		subbed, unsubbed := 0, 0
+		for _, r := range m.Token {
+			switch unicode.ToLower(r) {
+			case sub:
+				subbed++
+			case letter:
+				unsubbed++
+			}
+		}
+		if subbed == 0 || unsubbed == 0 {
+			variations *= 2
+			continue
+		}
+		possibilities := 0.0
+		for i := 1; i <= subbed && i <= unsubbed; i++ {
+			possibilities += binomial(subbed+unsubbed, i)
+		}
+		variations *= possibilities
+	}
+	return variations
+}
+
+func spatialGuesses(m Match) float64 {
+	s := float64(qwerty.startingPositions)
+	d := qwerty.averageDegree
+	length := len([]rune(m.Token))
+
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= m.Turns && j <= i-1; j++ {
+			guesses += binomial(i-1, j-1) * s * math.Pow(d, float64(j))
+		}
+	}
+
+	if m.ShiftedCount > 0 {
+		unshifted := length - m.ShiftedCount
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			variations := 0.0
+			for i := 1; i <= m.ShiftedCount && i <= unshifted; i++ {
+				variations += binomial(m.ShiftedCount+unshifted, i)
+			}
+			guesses *= variations
+		}
+	}
+	return guesses
+}
+
+func sequenceGuesses(m Match) float64 {
+	first := []rune(m.Token)[0]
+
+	var base float64
+	switch {
+	case first == 'a' || first == 'A' || first == 'z' || first == 'Z' || first == '0' || first == '1' || first == '9':
+		// Obvious starting points.
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if !m.Ascending {
+		base *= 2
+	}
+	return base * float64(len([]rune(m.Token)))
+}
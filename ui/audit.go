@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"pw/vault"
+)
+
+// WriteAudit writes an audit report of the current vault to path, or to
+// stdout when path is "-". Breached passwords are checked when breach data is
+// configured.
+func (c *CLI) WriteAudit(path string, format vault.AuditFormat, maxAge time.Duration) error {
+	options := vault.AuditOptions{MaxPasswordAge: maxAge}
+	if c.breachDataConfigured() {
+		checker, close, err := c.breachChecker()
+		if err != nil {
+			return err
+		}
+		defer close()
+		options.Breaches = checker
+	}
+
+	report, err := c.vault.Audit(options)
+	if err != nil {
+		return err
+	}
+
+	if path == "-" {
+		return vault.WriteAuditReport(os.Stdout, report, format)
+	}
+
+	// The report names every service and username.
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := vault.WriteAuditReport(file, report, format); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	ShowSuccess("Audit report written to %s (score %d/100, %s)", path, report.Score, report.Rating)
+	return nil
+}
+
+func (c *CLI) handleAuditReport() {
+	fmt.Println("\nReport formats:")
+	fmt.Println("1. Markdown")
+	fmt.Println("2. HTML")
+	fmt.Println("3. JSON")
+
+	formats := map[string]vault.AuditFormat{"1": vault.MarkdownAudit, "2": vault.HTMLAudit, "3": vault.JSONAudit}
+	format, ok := formats[ReadInput("Choose format (1-3): ")]
+	if !ok {
+		ShowError("Invalid format choice.")
+		return
+	}
+
+	path := ReadInput("Enter report file path: ")
+	if path == "" {
+		ShowError("File path cannot be empty.")
+		return
+	}
+
+	if err := c.WriteAudit(path, format, 0); err != nil {
+		ShowError("Audit failed: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pw/config"
+	"pw/security"
+	"pw/vault"
+)
+
+// BuildBreachIndex converts a downloaded Pwned Passwords file into the index
+// used for offline breach checks and records its location in cfg.
+func BuildBreachIndex(cfg *config.Config, src string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dst := cfg.BreachIndex
+	if dst == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dst = filepath.Join(homeDir, ".pwvault", "pwned.idx")
+	}
+
+	fmt.Println("Building the breach index. This takes a while for the full dataset...")
+	count, err := security.BuildBreachIndex(file, dst)
+	if err != nil {
+		return err
+	}
+
+	cfg.BreachIndex = dst
+	ShowSuccess("Indexed %d password hashes in %s", count, dst)
+	return nil
+}
+
+func (c *CLI) breachDataConfigured() bool {
+	return c.config.BreachAPI != "" || c.config.BreachIndex != ""
+}
+
+// breachChecker opens the configured breach data: the range API when one is
+// set and the local index otherwise. close releases it.
+func (c *CLI) breachChecker() (checker security.BreachChecker, close func(), err error) {
+	switch {
+	case c.config.BreachAPI != "":
+		return security.RangeClient{BaseURL: c.config.BreachAPI}, func() {}, nil
+	case c.config.BreachIndex != "":
+		index, err := security.OpenBreachIndex(c.config.BreachIndex)
+		if err != nil {
+			return nil, nil, err
+		}
+		return index, func() { index.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("no breach data configured; run \"pwvault breach index FILE\" or set breach_api_url")
+	}
+}
+
+// CheckBreaches reports the entries whose passwords appear in the breach
+// data.
+func (c *CLI) CheckBreaches() error {
+	checker, close, err := c.breachChecker()
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	breached, err := c.vault.CheckBreaches(checker)
+	if err != nil {
+		return err
+	}
+	ShowBreachReport(breached)
+	return nil
+}
+
+func ShowBreachReport(breached []vault.BreachedEntry) {
+	if len(breached) == 0 {
+		ShowSuccess("No passwords were found in the breach data.")
+		return
+	}
+
+	fmt.Printf("\nCompromised passwords (%d entries):\n", len(breached))
+	for _, b := range breached {
+		fmt.Printf("- %s (%s): seen %d times\n", b.Service, b.Username, b.Count)
+	}
+	fmt.Println("\nChange these passwords; they are known to attackers.")
+}
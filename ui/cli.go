@@ -7,24 +7,49 @@ import (
 	"time"
 
 	"pw/config"
+	"pw/crypto"
 	"pw/security"
 	"pw/vault"
 )
 
 type CLI struct {
-	config *config.Config
-	vault  *vault.Vault
-	theme  Theme
+	config    *config.Config
+	vault     *vault.Vault
+	vaultName string
+	unlocked  map[string]*vault.Vault
+	theme     Theme
 }
 
-func NewCLI(cfg *config.Config, v *vault.Vault) *CLI {
+func NewCLI(cfg *config.Config, name string, v *vault.Vault) *CLI {
 	return &CLI{
-		config: cfg,
-		vault:  v,
-		theme:  GetTheme(cfg.Theme),
+		config:    cfg,
+		vault:     v,
+		vaultName: name,
+		unlocked:  map[string]*vault.Vault{name: v},
+		theme:     GetTheme(cfg.Theme),
 	}
 }
 
+func (c *CLI) SaveAll() error {
+	for name, v := range c.unlocked {
+		if v.ReadOnly() {
+			continue
+		}
+		if err := v.Save(); err != nil {
+			return fmt.Errorf("vault %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *CLI) profile() *config.VaultProfile {
+	profile, err := c.config.Profile(c.vaultName)
+	if err != nil {
+		return &config.VaultProfile{}
+	}
+	return profile
+}
+
 func (c *CLI) Run() error {
 	for {
 		if c.config.ClearScreen {
@@ -59,6 +84,20 @@ func (c *CLI) Run() error {
 			c.handleSettings()
 		case "11":
 			c.handleBackup()
+		case "12":
+			c.handleSwitchVault()
+		case "13":
+			c.handleVaultProfiles()
+		case "14":
+			c.handleShareEntries()
+		case "15":
+			c.handlePublicKeySharing()
+		case "16":
+			c.handleTeamVault()
+		case "17":
+			c.handlePasswordRotation()
+		case "18":
+			c.handlePasswordRequirements()
 		case "q", "Q":
 			return nil
 		default:
@@ -81,6 +120,11 @@ func (c *CLI) showHeader() {
               /_/                                                         
 `
 	fmt.Print(c.theme.HeaderStyle.Apply(header))
+	status := ""
+	if c.vault.IsTeam() {
+		status = fmt.Sprintf(" (team, %s)", c.vault.Role())
+	}
+	fmt.Printf("\nVault: %s%s\n", c.theme.HighlightStyle.Apply(c.vaultName), status)
 }
 
 func (c *CLI) showMenu() {
@@ -96,6 +140,13 @@ func (c *CLI) showMenu() {
 		"9. View Statistics",
 		"10. Settings",
 		"11. Backup Vault",
+		"12. Switch Vault",
+		"13. Manage Vault Profiles",
+		"14. Share or Move Entries",
+		"15. Public-Key Sharing",
+		"16. Team Vault",
+		"17. Password Rotation",
+		"18. Password Requirements",
 		"Q. Quit",
 	}
 
@@ -107,30 +158,31 @@ func (c *CLI) showMenu() {
 }
 
 func (c *CLI) handleGeneratePassword() {
-	length := c.config.PasswordLength
-	lengthStr := ReadInput(fmt.Sprintf("Password length (default %d): ", length))
-	if lengthStr != "" {
-		if n, err := strconv.Atoi(lengthStr); err == nil && n > 0 {
-			length = n
-		}
-	}
-
-	password := GeneratePassword(length)
-	strength := security.AnalyzePassword(password)
-
-	fmt.Printf("\nGenerated password: %s\n", c.theme.PasswordStyle.Apply(password))
-	if c.config.ShowStrength {
-		fmt.Printf("Password strength: %s (Score: %.0f)\n", strength.Level, strength.Score)
-		if len(strength.Suggestions) > 0 {
-			fmt.Println("Suggestions:")
-			for _, s := range strength.Suggestions {
-				fmt.Printf("- %s\n", s)
-			}
-		}
+	fmt.Println("\nGenerator modes:")
+	fmt.Println("1. Random characters")
+	fmt.Println("2. Passphrase")
+	fmt.Println("3. PIN")
+	fmt.Println("4. Pronounceable")
+
+	switch ReadInput("Choose mode (default 1): ") {
+	case "", "1":
+		c.handleGenerateRandom()
+	case "2":
+		c.handleGeneratePassphrase()
+	case "3":
+		c.handleGeneratePIN()
+	case "4":
+		c.handleGeneratePronounceable()
+	default:
+		ShowError("Invalid choice")
 	}
 }
 
 func (c *CLI) handleAddPassword() {
+	if !c.requireWritable() {
+		return
+	}
+
 	service := ReadInput("Enter service name: ")
 	if service == "" {
 		ShowError("Service name cannot be empty")
@@ -144,31 +196,52 @@ func (c *CLI) handleAddPassword() {
 	}
 
 	password := ReadSecureInput("Enter password (leave empty to generate): ")
+	preset := ""
 	if password == "" {
-		password = GeneratePassword(c.config.PasswordLength)
-		fmt.Printf("Generated password: %s\n", c.theme.PasswordStyle.Apply(password))
-	}
-
-	if c.config.ShowStrength {
-		strength := security.AnalyzePassword(password)
-		if strength.Score < float64(c.config.MinStrength) {
-			if !ConfirmAction(fmt.Sprintf("Password strength (%.0f) is below minimum (%d). Use anyway?",
-				strength.Score, c.config.MinStrength)) {
-				return
-			}
+		var ok bool
+		if preset, ok = c.readPreset("Generator preset"); !ok {
+			return
 		}
 	}
 
 	notes := ReadInput("Enter notes (optional): ")
+	folder := ReadInput("Enter folder (optional): ")
+	tags := ParseTags(ReadInput("Enter tags, comma separated (optional): "))
 
 	entry := vault.Entry{
 		Service:   service,
 		Username:  username,
 		Password:  password,
 		Notes:     notes,
+		Folder:    folder,
+		Tags:      tags,
+		Preset:    preset,
 		CreatedAt: time.Now(),
 	}
 
+	// Generation waits for the folder, whose requirements may apply.
+	if entry.Password == "" {
+		var err error
+		if entry.Password, err = c.generatePassword(entry); err != nil {
+			ShowError("Cannot generate a password: %v", err)
+			return
+		}
+		fmt.Printf("Generated password: %s\n", c.theme.PasswordStyle.Apply(entry.Password))
+	} else if !c.ensureCompliant(&entry) {
+		return
+	}
+
+	if c.config.ShowStrength {
+		strength := security.AnalyzePassword(entry.Password, service, username)
+		if strength.Score < float64(c.config.MinStrength) {
+			ShowStrength(strength)
+			if !ConfirmAction(fmt.Sprintf("Password strength (%.0f) is below minimum (%d). Use anyway?",
+				strength.Score, c.config.MinStrength)) {
+				return
+			}
+		}
+	}
+
 	if err := c.vault.AddEntry(entry); err != nil {
 		ShowError("Failed to add entry: %v", err)
 		return
@@ -190,22 +263,52 @@ func (c *CLI) handleViewVault() {
 }
 
 func (c *CLI) handleSearchEntries() {
-	query := ReadInput("Enter search term: ")
-	entries := c.vault.SearchEntries(query)
+	query := ReadInput("Enter search (e.g. git user:alice tag:prod -tag:old created:>2024-01-01): ")
+	if _, err := vault.ParseQuery(query); err != nil {
+		ShowError("Invalid search: %v", err)
+		return
+	}
 
-	if len(entries) == 0 {
-		ShowInfo("No matching entries found.")
+	if len(c.unlocked) == 1 {
+		entries, _ := c.vault.SearchEntries(query)
+		if len(entries) == 0 {
+			ShowInfo("No matching entries found.")
+			return
+		}
+
+		fmt.Printf("\nFound %d entries:\n", len(entries))
+		for i, entry := range entries {
+			fmt.Printf("\n%d. ", i+1)
+			ShowPasswordEntry(entry, c.config.HidePasswords)
+		}
 		return
 	}
 
-	fmt.Printf("\nFound %d entries:\n", len(entries))
-	for i, entry := range entries {
-		fmt.Printf("\n%d. ", i+1)
-		ShowPasswordEntry(entry, c.config.HidePasswords)
+	found := 0
+	for _, name := range c.unlockedNames() {
+		entries, _ := c.unlocked[name].SearchEntries(query)
+		if len(entries) == 0 {
+			continue
+		}
+
+		fmt.Printf("\n[%s] %d entries:\n", c.theme.HighlightStyle.Apply(name), len(entries))
+		for i, entry := range entries {
+			fmt.Printf("\n%d. ", i+1)
+			ShowPasswordEntry(entry, c.config.HidePasswords)
+		}
+		found += len(entries)
+	}
+
+	if found == 0 {
+		ShowInfo("No matching entries found in %d unlocked vaults.", len(c.unlocked))
 	}
 }
 
 func (c *CLI) handleUpdateEntry() {
+	if !c.requireWritable() {
+		return
+	}
+
 	c.handleViewVault()
 	entries := c.vault.GetEntries()
 
@@ -216,7 +319,8 @@ func (c *CLI) handleUpdateEntry() {
 		return
 	}
 
-	entry := entries[idx-1]
+	original := entries[idx-1]
+	entry := original
 	fmt.Println("\nLeave fields empty to keep current values:")
 
 	if service := ReadInput(fmt.Sprintf("Service (%s): ", entry.Service)); service != "" {
@@ -227,14 +331,44 @@ func (c *CLI) handleUpdateEntry() {
 		entry.Username = username
 	}
 
+	passwordChanged := false
 	if password := ReadSecureInput("New password (leave empty to keep current): "); password != "" {
 		entry.Password = password
+		passwordChanged = true
 	}
 
 	if notes := ReadInput(fmt.Sprintf("Notes (%s): ", entry.Notes)); notes != "" {
 		entry.Notes = notes
 	}
 
+	if folder := ReadInput(fmt.Sprintf("Folder (%s): ", entry.Folder)); folder != "" {
+		entry.Folder = folder
+	}
+
+	if tags := ReadInput(fmt.Sprintf("Tags (%s): ", strings.Join(entry.Tags, ", "))); tags != "" {
+		entry.Tags = ParseTags(tags)
+	}
+
+	if presets := c.config.ListPresets(); len(presets) > 0 {
+		preset := ReadInput(fmt.Sprintf("Generator preset (%s; one of %s, - for default): ", entry.Preset, strings.Join(presets, ", ")))
+		switch {
+		case preset == "-":
+			entry.Preset = ""
+		case preset != "":
+			if _, err := c.config.GeneratorPolicy(preset); err != nil {
+				ShowError("%v", err)
+				return
+			}
+			entry.Preset = preset
+		}
+	}
+
+	// A new service or folder can bring stricter requirements for the
+	// password the entry already has.
+	if (passwordChanged || c.vault.RequirementsChanged(original, entry)) && !c.ensureCompliant(&entry) {
+		return
+	}
+
 	if err := c.vault.UpdateEntry(idx-1, entry); err != nil {
 		ShowError("Failed to update entry: %v", err)
 		return
@@ -243,6 +377,10 @@ func (c *CLI) handleUpdateEntry() {
 }
 
 func (c *CLI) handleDeleteEntry() {
+	if !c.requireWritable() {
+		return
+	}
+
 	c.handleViewVault()
 
 	idxStr := ReadInput("\nEnter entry number to delete: ")
@@ -271,8 +409,13 @@ func (c *CLI) handleExportVault() {
 	fmt.Println("1. JSON")
 	fmt.Println("2. CSV")
 	fmt.Println("3. Text")
+	fmt.Println("4. KeePass (KDBX 4)")
+	fmt.Println("5. pass (password-store directory)")
+	fmt.Println("6. YAML")
+	fmt.Println("7. NDJSON (one JSON entry per line)")
+	fmt.Println("8. dotenv (.env)")
 
-	choice := ReadInput("Choose format (1-3): ")
+	choice := ReadInput("Choose format (1-8): ")
 
 	var format vault.ExportFormat
 	switch choice {
@@ -282,6 +425,16 @@ func (c *CLI) handleExportVault() {
 		format = vault.CSVFormat
 	case "3":
 		format = vault.TextFormat
+	case "4":
+		format = vault.KDBXFormat
+	case "5":
+		format = vault.PassFormat
+	case "6":
+		format = vault.YAMLFormat
+	case "7":
+		format = vault.NDJSONFormat
+	case "8":
+		format = vault.DotenvFormat
 	default:
 		ShowError("Invalid format choice.")
 		return
@@ -294,10 +447,36 @@ func (c *CLI) handleExportVault() {
 	}
 
 	options := vault.ExportOptions{
-		Format:          format,
-		IncludePassword: ConfirmAction("Include passwords in export?"),
-		IncludeNotes:    ConfirmAction("Include notes in export?"),
-		IncludeTime:     ConfirmAction("Include timestamps in export?"),
+		Format:     format,
+		GPGCommand: c.config.GPGCommand,
+	}
+
+	if ConfirmAction("Export only some of the entries?") {
+		filter, ok := c.readEntryFilter()
+		if !ok {
+			return
+		}
+		options.Filter = filter
+	}
+
+	switch format {
+	case vault.JSONFormat, vault.CSVFormat, vault.TextFormat, vault.YAMLFormat, vault.NDJSONFormat:
+		fields, ok := readExportFields()
+		if !ok {
+			return
+		}
+		options.Fields = fields
+	case vault.DotenvFormat:
+		options.EnvKeyTemplate = ReadInput(fmt.Sprintf("Variable name template (default %s): ", vault.DefaultEnvKeyTemplate))
+	}
+	if len(options.Fields) == 0 && format != vault.DotenvFormat {
+		options.IncludePassword = ConfirmAction("Include passwords in export?")
+		options.IncludeNotes = ConfirmAction("Include notes in export?")
+		options.IncludeTime = ConfirmAction("Include timestamps in export?")
+	}
+
+	if !readExportProtection(&options, filePath) {
+		return
 	}
 
 	if err := c.vault.Export(filePath, options); err != nil {
@@ -309,12 +488,23 @@ func (c *CLI) handleExportVault() {
 }
 
 func (c *CLI) handleImportVault() {
+	if !c.requireWritable() {
+		return
+	}
+
 	fmt.Println("\nImport formats:")
 	fmt.Println("1. JSON")
 	fmt.Println("2. CSV")
 	fmt.Println("3. Auto-detect")
+	fmt.Println("4. Bitwarden JSON")
+	fmt.Println("5. Bitwarden CSV")
+	fmt.Println("6. KeePass (KDBX 4)")
+	fmt.Println("7. 1Password (1PUX)")
+	fmt.Println("8. Other password manager or browser CSV")
+	fmt.Println("9. pass (password-store directory)")
+	fmt.Println("10. YAML")
 
-	choice := ReadInput("Choose format (1-3): ")
+	choice := ReadInput("Choose format (1-10): ")
 
 	var format vault.ImportFormat
 	switch choice {
@@ -324,6 +514,23 @@ func (c *CLI) handleImportVault() {
 		format = vault.CSVImport
 	case "3":
 		format = vault.AutoDetect
+	case "4":
+		format = vault.BitwardenJSON
+	case "5":
+		format = vault.BitwardenCSV
+	case "6":
+		format = vault.KeePassImport
+	case "7":
+		format = vault.OnePasswordPUX
+	case "8":
+		var ok bool
+		if format, ok = chooseCSVProfile(); !ok {
+			return
+		}
+	case "9":
+		format = vault.PassImport
+	case "10":
+		format = vault.YAMLImport
 	default:
 		ShowError("Invalid format choice.")
 		return
@@ -335,21 +542,9 @@ func (c *CLI) handleImportVault() {
 		return
 	}
 
-	skipDuplicates := ConfirmAction("Skip duplicate entries?")
-	options := vault.ImportOptions{
-		Format:          format,
-		SkipDuplicates:  skipDuplicates,
-		UpdateExisting:  !skipDuplicates && ConfirmAction("Update existing entries?"),
-		RequiredFields:  []string{"service", "username"},
-		DefaultPassword: GeneratePassword(c.config.PasswordLength),
-	}
-
-	if err := c.vault.Import(filePath, options); err != nil {
+	if err := c.importFile(filePath, format, nil, false); err != nil {
 		ShowError("Import failed: %v", err)
-		return
 	}
-
-	ShowSuccess("Vault imported successfully!")
 }
 
 func (c *CLI) handleVaultStatistics() {
@@ -365,6 +560,9 @@ func (c *CLI) handleVaultStatistics() {
 	fmt.Printf("Strong: %d\n", stats.StrongPasswords)
 	fmt.Printf("Medium: %d\n", stats.MediumPasswords)
 	fmt.Printf("Weak: %d\n", stats.WeakPasswords)
+	if stats.OverdueRotations > 0 {
+		fmt.Printf("Overdue for rotation: %d\n", stats.OverdueRotations)
+	}
 
 	if len(stats.CommonServices) > 0 {
 		fmt.Println("\nMost Used Services:")
@@ -380,6 +578,30 @@ func (c *CLI) handleVaultStatistics() {
 				p.Password, p.Count, strings.Join(p.ServicesList, ", "))
 		}
 	}
+
+	if len(stats.PasswordFamilies) > 0 {
+		fmt.Println("\nSimilar Passwords:")
+		for _, f := range stats.PasswordFamilies {
+			if f.BaseWord != "" {
+				fmt.Printf("Variations of %s:\n", f.BaseWord)
+			} else {
+				fmt.Println("Variations of each other:")
+			}
+			for _, m := range f.Members {
+				fmt.Printf("  %s: %s\n", m.Password, strings.Join(m.Services, ", "))
+			}
+		}
+	}
+
+	if c.breachDataConfigured() && ConfirmAction("\nCheck passwords against breach data?") {
+		if err := c.CheckBreaches(); err != nil {
+			ShowError("Breach check failed: %v", err)
+		}
+	}
+
+	if ConfirmAction("\nWrite a full audit report?") {
+		c.handleAuditReport()
+	}
 }
 
 func (c *CLI) handleSettings() {
@@ -392,7 +614,8 @@ func (c *CLI) handleSettings() {
 		fmt.Println("5. Toggle screen clearing")
 		fmt.Println("6. Change theme")
 		fmt.Println("7. Configure backup settings")
-		fmt.Println("8. Back to main menu")
+		fmt.Println("8. Manage generator presets")
+		fmt.Println("9. Back to main menu")
 
 		choice := ReadInput("\nEnter choice: ")
 
@@ -415,6 +638,8 @@ func (c *CLI) handleSettings() {
 		case "7":
 			c.handleBackupSettings()
 		case "8":
+			c.handleGeneratorPresets()
+		case "9":
 			return
 		default:
 			ShowError("Invalid choice")
@@ -425,11 +650,11 @@ func (c *CLI) handleSettings() {
 func (c *CLI) handlePasswordLengthSetting() {
 	lengthStr := ReadInput(fmt.Sprintf("Enter new default password length (current: %d): ",
 		c.config.PasswordLength))
-	if length, err := strconv.Atoi(lengthStr); err == nil && length > 0 {
+	if length, err := strconv.Atoi(lengthStr); err == nil && length > 0 && length <= crypto.MaxPolicyLength {
 		c.config.PasswordLength = length
 		ShowSuccess("Default password length updated to %d", length)
 	} else {
-		ShowError("Invalid length")
+		ShowError("Invalid length, expected 1 to %d", crypto.MaxPolicyLength)
 	}
 }
 
@@ -464,7 +689,7 @@ func (c *CLI) handleThemeSetting() {
 }
 
 func (c *CLI) handleBackupSettings() {
-	fmt.Println("\nBackup Settings:")
+	fmt.Printf("\nBackup Settings (%s):\n", c.vaultName)
 	fmt.Println("1. Toggle auto-backup")
 	fmt.Println("2. Change backup interval")
 	fmt.Println("3. Change max backups")
@@ -473,20 +698,22 @@ func (c *CLI) handleBackupSettings() {
 
 	choice := ReadInput("\nEnter choice: ")
 
+	profile := c.profile()
+
 	switch choice {
 	case "1":
-		c.config.AutoBackup = !c.config.AutoBackup
-		ShowSuccess("Auto-backup %s", onOff(c.config.AutoBackup))
+		profile.AutoBackup = !profile.AutoBackup
+		ShowSuccess("Auto-backup %s", onOff(profile.AutoBackup))
 	case "2":
 		if days, err := strconv.Atoi(ReadInput("Enter backup interval in days: ")); err == nil && days > 0 {
-			c.config.BackupInterval = days
+			profile.BackupInterval = days
 			ShowSuccess("Backup interval updated to %d days", days)
 		} else {
 			ShowError("Invalid interval")
 		}
 	case "3":
 		if max, err := strconv.Atoi(ReadInput("Enter maximum number of backups to keep: ")); err == nil && max > 0 {
-			c.config.MaxBackups = max
+			profile.MaxBackups = max
 			ShowSuccess("Maximum backups updated to %d", max)
 		} else {
 			ShowError("Invalid number")
@@ -494,7 +721,7 @@ func (c *CLI) handleBackupSettings() {
 	case "4":
 		dir := ReadInput("Enter new backup directory path: ")
 		if dir != "" {
-			c.config.BackupDir = dir
+			profile.BackupDir = dir
 			ShowSuccess("Backup directory updated")
 		} else {
 			ShowError("Invalid directory path")
@@ -507,7 +734,8 @@ func (c *CLI) handleBackupSettings() {
 }
 
 func (c *CLI) handleBackup() {
-	backupManager, err := vault.NewBackupManager(c.config.BackupDir, c.config.MaxBackups)
+	profile := c.profile()
+	backupManager, err := vault.NewBackupManager(profile.BackupDir, profile.MaxBackups)
 	if err != nil {
 		ShowError("Failed to initialize backup manager: %v", err)
 		return
@@ -523,7 +751,7 @@ func (c *CLI) handleBackup() {
 
 	switch choice {
 	case "1":
-		if err := backupManager.CreateBackup(c.config.VaultPath, c.vault.Encrypt); err != nil {
+		if err := backupManager.CreateBackup(profile.VaultPath, c.vault.Encrypt); err != nil {
 			ShowError("Backup failed: %v", err)
 		} else {
 			ShowSuccess("Backup created successfully")
@@ -547,7 +775,7 @@ func (c *CLI) handleBackup() {
 		choiceStr := ReadInput("\nChoose backup to restore: ")
 		if choice, err := strconv.Atoi(choiceStr); err == nil && choice > 0 && choice <= len(backups) {
 			if ConfirmAction("This will overwrite your current vault. Continue?") {
-				if err := backupManager.RestoreBackup(backups[choice-1], c.config.VaultPath, c.vault.Decrypt); err != nil {
+				if err := backupManager.RestoreBackup(backups[choice-1], profile.VaultPath, c.vault.Decrypt); err != nil {
 					ShowError("Restore failed: %v", err)
 				} else {
 					ShowSuccess("Backup restored successfully")
@@ -3,16 +3,27 @@ package ui
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"pw/crypto"
+	"pw/security"
 	"pw/vault"
 )
 
 var reader = bufio.NewReader(os.Stdin)
 
+// output receives prompts and messages. It is switched to stderr when an
+// export is written to stdout.
+var output io.Writer = os.Stdout
+
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 func ClearScreen() {
 	if runtime.GOOS == "windows" {
 		fmt.Print("\033[H\033[2J")
@@ -22,29 +33,28 @@ func ClearScreen() {
 }
 
 func ReadInput(prompt string) string {
-	fmt.Print(prompt)
+	fmt.Fprint(output, prompt)
 	input, _ := reader.ReadString('\n')
 	return strings.TrimSpace(input)
 }
 
 func ReadSecureInput(prompt string) string {
-	fmt.Print(prompt)
-	var input string
-	fmt.Scanln(&input)
-	fmt.Print("\n")
-	return input
+	fmt.Fprint(output, prompt)
+	input, _ := reader.ReadString('\n')
+	fmt.Fprint(output, "\n")
+	return strings.TrimRight(input, "\r\n")
 }
 
 func ShowError(format string, args ...interface{}) {
-	fmt.Printf("\nError: "+format+"\n", args...)
+	fmt.Fprintf(output, "\nError: "+format+"\n", args...)
 }
 
 func ShowSuccess(format string, args ...interface{}) {
-	fmt.Printf("\nSuccess: "+format+"\n", args...)
+	fmt.Fprintf(output, "\nSuccess: "+format+"\n", args...)
 }
 
 func ShowInfo(format string, args ...interface{}) {
-	fmt.Printf("\nInfo: "+format+"\n", args...)
+	fmt.Fprintf(output, "\nInfo: "+format+"\n", args...)
 }
 
 func PressEnterToContinue() {
@@ -57,9 +67,93 @@ func ConfirmAction(prompt string) bool {
 	return strings.ToLower(response) == "y"
 }
 
-func GeneratePassword(length int) string {
-	gen := crypto.NewGenerator()
-	return gen.GenerateString(length, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()")
+// GeneratePassword uses the default policy. It fails for lengths outside
+// 1 to crypto.MaxPolicyLength.
+func GeneratePassword(length int) (string, error) {
+	return crypto.NewGenerator().GeneratePolicy(crypto.DefaultPolicy(length))
+}
+
+func ShowStrength(strength security.StrengthResult) {
+	fmt.Printf("Password strength: %s (Score: %.0f)\n", strength.Level, strength.Score)
+	fmt.Printf("Estimated guesses: 10^%.1f\n", strength.GuessesLog10)
+	fmt.Println("Time to crack:")
+	for _, t := range strength.CrackTimes {
+		fmt.Printf("- %s: %s\n", t.Scenario, t)
+	}
+	if strength.Warning != "" {
+		fmt.Printf("Warning: %s\n", strength.Warning)
+	}
+	if len(strength.Suggestions) > 0 {
+		fmt.Println("Suggestions:")
+		for _, s := range strength.Suggestions {
+			fmt.Printf("- %s\n", s)
+		}
+	}
+}
+
+func ShowImportResult(result vault.ImportResult) {
+	if result.DryRun {
+		fmt.Println("\nImport preview (nothing has been changed yet):")
+	} else {
+		fmt.Println("\nImport summary:")
+	}
+	fmt.Printf("%d to add, %d to update, %d duplicates, %d rejected\n",
+		len(result.Added), len(result.Updated), len(result.Duplicates), len(result.Rejected))
+
+	if len(result.Added) > 0 {
+		fmt.Printf("\nAdded (%d):\n", len(result.Added))
+		for _, record := range result.Added {
+			fmt.Printf("- %s\n", record)
+		}
+	}
+	if len(result.Updated) > 0 {
+		fmt.Printf("\nUpdated (%d):\n", len(result.Updated))
+		for _, u := range result.Updated {
+			fmt.Printf("- %s: %s\n", u.Record, strings.Join(u.Changes, ", "))
+		}
+	}
+	if len(result.Duplicates) > 0 {
+		fmt.Printf("\nDuplicates left unchanged (%d):\n", len(result.Duplicates))
+		for _, record := range result.Duplicates {
+			fmt.Printf("- %s\n", record)
+		}
+	}
+	if len(result.Rejected) > 0 {
+		fmt.Printf("\nRejected (%d):\n", len(result.Rejected))
+		for _, r := range result.Rejected {
+			fmt.Printf("- %s: %s\n", r.Record, r.Reason)
+		}
+	}
+
+	if result.Imported() == 0 {
+		ShowInfo("There is nothing to import.")
+	}
+}
+
+func chooseCSVProfile() (vault.ImportFormat, bool) {
+	profiles := vault.CSVProfiles()
+
+	fmt.Println("\nCSV formats:")
+	for i, p := range profiles {
+		fmt.Printf("%d. %s\n", i+1, p.Name)
+	}
+
+	idx, err := strconv.Atoi(ReadInput(fmt.Sprintf("Choose format (1-%d): ", len(profiles))))
+	if err != nil || idx < 1 || idx > len(profiles) {
+		ShowError("Invalid format choice.")
+		return "", false
+	}
+	return profiles[idx-1].Format, true
+}
+
+func ParseTags(input string) []string {
+	var tags []string
+	for _, tag := range strings.Split(input, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
 }
 
 func ShowPasswordEntry(entry interface{}, hidePassword bool) {
@@ -75,7 +169,44 @@ func ShowPasswordEntry(entry interface{}, hidePassword bool) {
 		if e.Notes != "" {
 			fmt.Printf("Notes: %s\n", e.Notes)
 		}
+		if e.Folder != "" {
+			fmt.Printf("Folder: %s\n", e.Folder)
+		}
+		if len(e.Tags) > 0 {
+			fmt.Printf("Tags: %s\n", strings.Join(e.Tags, ", "))
+		}
+		for _, url := range e.URLs {
+			fmt.Printf("URL: %s\n", url)
+		}
+		if e.TOTP != "" {
+			fmt.Println("TOTP: configured")
+		}
+		for _, f := range e.Fields {
+			value := f.Value
+			if f.Hidden && hidePassword {
+				value = strings.Repeat("*", len(value))
+			}
+			fmt.Printf("%s: %s\n", f.Name, value)
+		}
+		if len(e.Attachments) > 0 {
+			fmt.Printf("Attachments: %d\n", len(e.Attachments))
+		}
+		if len(e.History) > 0 {
+			fmt.Printf("Previous passwords: %d\n", len(e.History))
+		}
 		fmt.Printf("Created: %s\n", e.CreatedAt.Format("2006-01-02 15:04:05"))
+		if !e.PasswordChangedAt.IsZero() {
+			fmt.Printf("Password changed: %s\n", e.PasswordChangedAt.Format("2006-01-02 15:04:05"))
+		}
+		if e.Preset != "" {
+			fmt.Printf("Generator preset: %s\n", e.Preset)
+		}
+		if e.RotationDays > 0 {
+			fmt.Printf("Rotate every: %d days\n", e.RotationDays)
+		}
+		if !e.ExpiresAt.IsZero() {
+			fmt.Printf("Expires: %s\n", e.ExpiresAt.Format("2006-01-02 15:04:05"))
+		}
 	} else {
 		fmt.Println("Invalid entry format")
 	}
@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pw/vault"
+)
+
+// ExportFile writes the entries selected by options to filePath, or to
+// stdout when filePath is "-". Exports are encrypted with a passphrase
+// unless options.Plaintext is set.
+func (c *CLI) ExportFile(filePath string, options vault.ExportOptions) error {
+	if len(options.Fields) == 0 {
+		options.IncludePassword = true
+		options.IncludeNotes = true
+		options.IncludeTime = true
+	}
+	options.GPGCommand = c.config.GPGCommand
+
+	switch {
+	case options.Format == vault.PassFormat:
+		options.GPGRecipients = readGPGRecipients(filePath)
+	case options.Format == vault.KDBXFormat || !options.Plaintext:
+		passphrase, ok := readNewPassphrase("Enter a passphrase for the export: ")
+		if !ok {
+			return fmt.Errorf("export cancelled")
+		}
+		options.Passphrase = passphrase
+		options.Encrypt = true
+	}
+
+	if filePath == "-" {
+		return c.vault.ExportTo(os.Stdout, options)
+	}
+	if err := c.vault.Export(filePath, options); err != nil {
+		return err
+	}
+	ShowSuccess("Vault exported to %s", filePath)
+	return nil
+}
+
+// readExportFields asks for an explicit field list. An empty answer keeps
+// the include questions.
+func readExportFields() ([]string, bool) {
+	fmt.Printf("\nAvailable fields: %s\n", strings.Join(vault.ExportFields, ", "))
+	fields := ParseTags(ReadInput("Fields to export in order, comma separated (empty for the defaults): "))
+	for _, f := range fields {
+		if !isExportField(f) {
+			ShowError("Unknown field %q.", f)
+			return nil, false
+		}
+	}
+	return fields, true
+}
+
+func isExportField(field string) bool {
+	for _, f := range vault.ExportFields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// readExportProtection asks for a passphrase, or for an explicit
+// confirmation before writing an unencrypted export.
+func readExportProtection(options *vault.ExportOptions, filePath string) bool {
+	if options.Format == vault.PassFormat {
+		options.GPGRecipients = readGPGRecipients(filePath)
+		return true
+	}
+	if options.Format == vault.KDBXFormat {
+		passphrase, ok := readNewPassphrase("Enter a passphrase for the KeePass database: ")
+		options.Passphrase = passphrase
+		return ok
+	}
+
+	if ConfirmAction("Encrypt the export with a passphrase?") {
+		passphrase, ok := readNewPassphrase("Enter a passphrase for the export: ")
+		options.Passphrase = passphrase
+		options.Encrypt = true
+		return ok
+	}
+
+	fmt.Println("The export will be readable by anyone who can access the file.")
+	if ReadInput(`Type "plaintext" to confirm: `) != "plaintext" {
+		ShowInfo("Export cancelled.")
+		return false
+	}
+	options.Plaintext = true
+	return true
+}
+
+// readGPGRecipients asks for the keys to encrypt a pass store to, unless
+// the store already has a .gpg-id file.
+func readGPGRecipients(dir string) []string {
+	if _, err := os.Stat(filepath.Join(dir, ".gpg-id")); err == nil {
+		return nil
+	}
+	return ParseTags(ReadInput("GPG key IDs to encrypt to, comma separated: "))
+}
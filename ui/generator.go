@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pw/crypto"
+	"pw/security"
+	"pw/vault"
+)
+
+// generatePassword follows the entry's preset, or the default policy when it
+// has none, adjusted to meet the requirements of its service or folder.
+func (c *CLI) generatePassword(entry vault.Entry) (string, error) {
+	policy, err := c.config.GeneratorPolicy(entry.Preset)
+	if err != nil {
+		return "", err
+	}
+	if r, ok := c.vault.RequirementsFor(entry); ok {
+		policy = r.Constrain(policy)
+	}
+	return crypto.NewGenerator().GeneratePolicy(policy)
+}
+
+// readPreset asks for a generator preset when any are configured. It returns
+// false when the answer is not a known preset.
+func (c *CLI) readPreset(prompt string) (string, bool) {
+	presets := c.config.ListPresets()
+	if len(presets) == 0 {
+		return "", true
+	}
+
+	name := ReadInput(fmt.Sprintf("%s (%s, leave empty for default): ", prompt, strings.Join(presets, ", ")))
+	if name == "" {
+		return "", true
+	}
+	if _, err := c.config.GeneratorPolicy(name); err != nil {
+		ShowError("%v", err)
+		return "", false
+	}
+	return name, true
+}
+
+func (c *CLI) handleGeneratorPresets() {
+	fmt.Println("\nGenerator Presets:")
+	presets := c.config.ListPresets()
+	if len(presets) == 0 {
+		fmt.Println("No presets yet.")
+	}
+	for _, name := range presets {
+		policy, _ := c.config.GeneratorPolicy(name)
+		fmt.Printf("- %s: %s\n", name, policy)
+	}
+
+	fmt.Println("\n1. Add or replace preset")
+	fmt.Println("2. Delete preset")
+	fmt.Println("3. Back")
+
+	switch ReadInput("\nEnter choice: ") {
+	case "1":
+		name := ReadInput("Preset name: ")
+		policy, ok := c.readPolicy()
+		if !ok {
+			return
+		}
+		if err := c.config.SavePreset(name, policy); err != nil {
+			ShowError("Invalid preset: %v", err)
+			return
+		}
+		ShowSuccess("Saved preset %s", name)
+	case "2":
+		name := ReadInput("Preset name: ")
+		if err := c.config.DeletePreset(name); err != nil {
+			ShowError("%v", err)
+			return
+		}
+		ShowSuccess("Deleted preset %s", name)
+	case "3":
+		return
+	default:
+		ShowError("Invalid choice")
+	}
+}
+
+func (c *CLI) readPolicy() (crypto.PasswordPolicy, bool) {
+	fmt.Println("Minimum counts are per character class; enter -1 to leave a class out.")
+
+	policy := crypto.DefaultPolicy(c.config.PasswordLength)
+	policy.Length = 0
+	fields := []struct {
+		prompt string
+		value  *int
+	}{
+		{fmt.Sprintf("Length (default %d): ", c.config.PasswordLength), &policy.Length},
+		{"Maximum length accepted by the site (0 for none): ", &policy.MaxLength},
+		{fmt.Sprintf("Minimum lowercase letters (default %d): ", policy.MinLower), &policy.MinLower},
+		{fmt.Sprintf("Minimum uppercase letters (default %d): ", policy.MinUpper), &policy.MinUpper},
+		{fmt.Sprintf("Minimum digits (default %d): ", policy.MinDigits), &policy.MinDigits},
+		{fmt.Sprintf("Minimum symbols (default %d): ", policy.MinSymbols), &policy.MinSymbols},
+	}
+	for _, f := range fields {
+		input := ReadInput(f.prompt)
+		if input == "" {
+			continue
+		}
+		n, err := strconv.Atoi(input)
+		if err != nil || n < -1 {
+			ShowError("Invalid number %q", input)
+			return policy, false
+		}
+		*f.value = n
+	}
+
+	if policy.MinSymbols >= 0 {
+		policy.Symbols = ReadInput(fmt.Sprintf("Symbols to use (default %s): ", crypto.DefaultSymbols))
+	}
+	policy.ExcludeAmbiguous = ConfirmAction(fmt.Sprintf("Exclude ambiguous characters (%s)?", crypto.AmbiguousChars))
+	return policy, true
+}
+
+func (c *CLI) handleGenerateRandom() {
+	preset, ok := c.readPreset("Generator preset")
+	if !ok {
+		return
+	}
+	policy, err := c.config.GeneratorPolicy(preset)
+	if err != nil {
+		ShowError("%v", err)
+		return
+	}
+
+	lengthStr := ReadInput(fmt.Sprintf("Password length (default %d): ", policy.Length))
+	if lengthStr != "" {
+		if n, err := strconv.Atoi(lengthStr); err == nil && n > 0 {
+			policy.Length = n
+		}
+	}
+
+	password, err := crypto.NewGenerator().GeneratePolicy(policy)
+	if err != nil {
+		ShowError("Cannot generate a password: %v", err)
+		return
+	}
+	c.showGenerated(password, policy.Entropy())
+}
+
+func (c *CLI) handleGeneratePassphrase() {
+	options := crypto.PassphraseOptions{
+		Words:     crypto.DefaultPassphraseWords,
+		Separator: "-",
+		Case:      crypto.LowerCase,
+	}
+
+	path := c.config.PassphraseWordlist
+	if input := ReadInput(fmt.Sprintf("Wordlist file (default %s): ", wordlistName(path))); input != "" {
+		path = input
+	}
+	if path != "" {
+		list, err := crypto.LoadWordlist(path)
+		if err != nil {
+			ShowError("Cannot read wordlist: %v", err)
+			return
+		}
+		options.Wordlist = list
+	}
+
+	if input := ReadInput(fmt.Sprintf("Number of words (default %d): ", options.Words)); input != "" {
+		n, err := strconv.Atoi(input)
+		if err != nil || n <= 0 {
+			ShowError("Invalid number of words")
+			return
+		}
+		options.Words = n
+	}
+	switch input := ReadInput(fmt.Sprintf("Separator (default %q, or \"space\" or \"none\"): ", options.Separator)); input {
+	case "":
+	case "space":
+		options.Separator = " "
+	case "none":
+		options.Separator = ""
+	default:
+		options.Separator = input
+	}
+	if input := ReadInput("Capitalization: lower, title or random (default lower): "); input != "" {
+		options.Case = crypto.Capitalization(input)
+	}
+	if input := ReadInput("Random digits to insert (default 0): "); input != "" {
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 0 {
+			ShowError("Invalid number of digits")
+			return
+		}
+		options.Digits = n
+	}
+
+	passphrase, err := crypto.NewGenerator().GeneratePassphrase(options)
+	if err != nil {
+		ShowError("Cannot generate a passphrase: %v", err)
+		return
+	}
+	c.showGenerated(passphrase, options.Entropy())
+}
+
+func (c *CLI) handleGeneratePIN() {
+	options := crypto.PINOptions{Length: 6}
+	if input := ReadInput(fmt.Sprintf("PIN length (%d-%d, default %d): ", crypto.MinPINLength, crypto.MaxPINLength, options.Length)); input != "" {
+		n, err := strconv.Atoi(input)
+		if err != nil {
+			ShowError("Invalid length")
+			return
+		}
+		options.Length = n
+	}
+
+	pin, err := crypto.NewGenerator().GeneratePIN(options)
+	if err != nil {
+		ShowError("Cannot generate a PIN: %v", err)
+		return
+	}
+	fmt.Println("Repeated digits, runs like 123 and date-like PINs are never generated.")
+	c.showGenerated(pin, options.Entropy())
+}
+
+func (c *CLI) handleGeneratePronounceable() {
+	options := crypto.PronounceableOptions{Syllables: crypto.DefaultSyllables, Separator: "-"}
+	if input := ReadInput(fmt.Sprintf("Number of syllables (default %d): ", options.Syllables)); input != "" {
+		n, err := strconv.Atoi(input)
+		if err != nil || n <= 0 {
+			ShowError("Invalid number of syllables")
+			return
+		}
+		options.Syllables = n
+	}
+	if input := ReadInput("Random digits to append (default 0): "); input != "" {
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 0 {
+			ShowError("Invalid number of digits")
+			return
+		}
+		options.Digits = n
+	}
+	options.Capitalize = ConfirmAction("Capitalize the first letter?")
+
+	password, err := crypto.NewGenerator().GeneratePronounceable(options)
+	if err != nil {
+		ShowError("Cannot generate a password: %v", err)
+		return
+	}
+	c.showGenerated(password, options.Entropy())
+}
+
+func wordlistName(path string) string {
+	if path == "" {
+		return "EFF long wordlist"
+	}
+	return path
+}
+
+// showGenerated prints a generated password with its entropy, when known,
+// and the strength estimate.
+func (c *CLI) showGenerated(password string, entropy float64) {
+	fmt.Printf("\nGenerated password: %s\n", c.theme.PasswordStyle.Apply(password))
+	if entropy > 0 {
+		fmt.Printf("Entropy: %.1f bits\n", entropy)
+	}
+	if c.config.ShowStrength {
+		ShowStrength(security.AnalyzePassword(password))
+	}
+}
@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pw/vault"
+)
+
+// ImportFile imports filePath into the current vault. mapSpec is either a
+// field=column list or the name of a saved mapping; when saveAs is set the
+// mapping that was used is saved under that name. With dryRun the changes
+// are reported but not applied.
+func (c *CLI) ImportFile(filePath string, format vault.ImportFormat, mapSpec, saveAs string, dryRun bool) error {
+	if c.vault.ReadOnly() {
+		return vault.ErrReadOnly
+	}
+
+	var mapping vault.ColumnMapping
+	if mapSpec != "" {
+		var err error
+		if mapping, err = c.lookupMapping(mapSpec); err != nil {
+			return err
+		}
+		if saveAs != "" {
+			c.saveMapping(saveAs, mapping)
+		}
+	}
+
+	return c.importFile(filePath, format, mapping, dryRun)
+}
+
+func (c *CLI) importFile(filePath string, format vault.ImportFormat, mapping vault.ColumnMapping, dryRun bool) error {
+	if format == vault.AutoDetect {
+		detected, err := vault.DetectImportFormat(filePath)
+		if err != nil {
+			return err
+		}
+		format = detected
+	}
+
+	var passphrase, keyFile string
+	switch format {
+	case vault.KeePassImport:
+		passphrase = ReadSecureInput("Enter the KeePass master password: ")
+		keyFile = ReadInput("Enter key file path (leave empty if none): ")
+	case vault.EncryptedImport:
+		passphrase = ReadSecureInput("Enter the export passphrase: ")
+	}
+
+	// Rows without a password get a generated one.
+	defaultPassword, err := GeneratePassword(c.config.PasswordLength)
+	if err != nil {
+		return fmt.Errorf("cannot generate passwords for rows without one: %v", err)
+	}
+
+	required := []string{"service", "username"}
+	if format.HasSecureNotes() {
+		required = []string{"service"}
+	}
+
+	skipDuplicates := ConfirmAction("Skip duplicate entries?")
+	options := vault.ImportOptions{
+		Format:          format,
+		SkipDuplicates:  skipDuplicates,
+		UpdateExisting:  !skipDuplicates && ConfirmAction("Update existing entries?"),
+		RequiredFields:  required,
+		DefaultPassword: defaultPassword,
+		Passphrase:      passphrase,
+		KeyFile:         keyFile,
+		GPGCommand:      c.config.GPGCommand,
+	}
+
+	return reviewImport(c.vault, options, dryRun, func(options vault.ImportOptions) (vault.ImportResult, error) {
+		options.Mapping = mapping
+		result, err := c.vault.Import(filePath, options)
+
+		var unmapped *vault.UnmappedColumnsError
+		if errors.As(err, &unmapped) {
+			fmt.Println("\nThe CSV columns were not recognized.")
+			if mapping, err = c.mapColumns(unmapped); err != nil {
+				return result, err
+			}
+			options.Mapping = mapping
+			result, err = c.vault.Import(filePath, options)
+		}
+		return result, err
+	})
+}
+
+// reviewImport runs the import as a dry run, shows what it would change and
+// applies the entries it read only once the user confirms. The source is
+// read once.
+func reviewImport(v *vault.Vault, options vault.ImportOptions, dryRun bool, run func(vault.ImportOptions) (vault.ImportResult, error)) error {
+	options.DryRun = true
+	preview, err := run(options)
+	if err != nil {
+		return err
+	}
+
+	ShowImportResult(preview)
+	if dryRun || preview.Imported() == 0 || !ConfirmAction("Apply these changes?") {
+		return nil
+	}
+
+	result, err := v.ApplyImport(preview)
+	if err != nil {
+		return err
+	}
+	ShowSuccess("Imported %d entries.", result.Imported())
+	return nil
+}
+
+func (c *CLI) lookupMapping(spec string) (vault.ColumnMapping, error) {
+	if strings.Contains(spec, "=") {
+		return vault.ParseColumnMapping(spec)
+	}
+	if saved, ok := c.config.CSVMappings[spec]; ok {
+		return vault.ColumnMapping(saved), nil
+	}
+	return nil, fmt.Errorf("no saved column mapping named %s", spec)
+}
+
+func (c *CLI) saveMapping(name string, mapping vault.ColumnMapping) {
+	if c.config.CSVMappings == nil {
+		c.config.CSVMappings = make(map[string]map[string]string)
+	}
+	c.config.CSVMappings[name] = mapping
+}
+
+func (c *CLI) mapColumns(e *vault.UnmappedColumnsError) (vault.ColumnMapping, error) {
+	c.showCSVPreview(e)
+
+	var names []string
+	for name, saved := range c.config.CSVMappings {
+		if vault.ColumnMapping(saved).Fits(e.Header) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mapping := vault.ColumnMapping(c.config.CSVMappings[name])
+		if ConfirmAction(fmt.Sprintf("Use saved mapping %s (%s)?", name, mapping)) {
+			return mapping, nil
+		}
+	}
+
+	mapping := make(vault.ColumnMapping)
+	fmt.Println("\nChoose the column for each field by number or name, or leave empty to skip it.")
+	for _, field := range vault.MappableFields {
+		for {
+			input := ReadInput(fmt.Sprintf("%s: ", field))
+			if input == "" {
+				break
+			}
+			column, ok := csvColumn(e.Header, input)
+			if !ok {
+				ShowError("No column %s", input)
+				continue
+			}
+			mapping[field] = column
+			break
+		}
+	}
+
+	if mapping["service"] == "" && mapping["username"] == "" && mapping["password"] == "" {
+		return nil, fmt.Errorf("at least one of service, username or password must be mapped")
+	}
+
+	if ConfirmAction("Save this mapping for future imports?") {
+		if name := ReadInput("Mapping name: "); name != "" {
+			c.saveMapping(name, mapping)
+			ShowSuccess("Saved mapping %s", name)
+		}
+	}
+
+	return mapping, nil
+}
+
+// showCSVPreview lists the columns with sample values. Columns that look like
+// secrets, or that a saved mapping uses for passwords or TOTP, are masked.
+func (c *CLI) showCSVPreview(e *vault.UnmappedColumnsError) {
+	secret := make(map[string]bool)
+	for _, saved := range c.config.CSVMappings {
+		for _, field := range []string{"password", "totp"} {
+			if column := saved[field]; column != "" {
+				secret[strings.ToLower(strings.TrimSpace(column))] = true
+			}
+		}
+	}
+
+	fmt.Println("\nColumns:")
+	for i, h := range e.Header {
+		masked := vault.IsSecretColumn(h) || secret[strings.ToLower(strings.TrimSpace(h))]
+		var samples []string
+		for _, row := range e.Preview {
+			if i < len(row) && row[i] != "" {
+				if masked {
+					samples = append(samples, "********")
+				} else {
+					samples = append(samples, row[i])
+				}
+			}
+		}
+		fmt.Printf("%d. %s", i+1, h)
+		if len(samples) > 0 {
+			fmt.Printf("  (e.g. %s)", strings.Join(samples, ", "))
+		}
+		fmt.Println()
+	}
+}
+
+func csvColumn(header []string, input string) (string, bool) {
+	if i, err := strconv.Atoi(input); err == nil {
+		if i < 1 || i > len(header) {
+			return "", false
+		}
+		return header[i-1], true
+	}
+	for _, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), input) {
+			return h, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"pw/vault"
+)
+
+func (c *CLI) handlePublicKeySharing() {
+	fmt.Println("\nPublic-Key Sharing:")
+	fmt.Println("1. Show my public key")
+	fmt.Println("2. Share entries with recipients")
+	fmt.Println("3. Import a shared file")
+	fmt.Println("4. Back to main menu")
+
+	switch ReadInput("\nEnter choice: ") {
+	case "1":
+		publicKey, err := c.vault.PublicKey()
+		if err != nil {
+			ShowError("Failed to load identity: %v", err)
+			return
+		}
+		fmt.Printf("\nYour public key (give this to teammates):\n%s\n", c.theme.HighlightStyle.Apply(publicKey))
+	case "2":
+		c.handleShareWithRecipients()
+	case "3":
+		c.handleImportShared()
+	case "4":
+		return
+	default:
+		ShowError("Invalid choice")
+	}
+}
+
+func (c *CLI) handleShareWithRecipients() {
+	filter, ok := c.readEntryFilter()
+	if !ok {
+		return
+	}
+
+	recipients := strings.Split(ReadInput("Enter recipient public keys (age1...), comma separated: "), ",")
+	parsed, err := vault.ParseRecipients(recipients)
+	if err != nil {
+		ShowError("%v", err)
+		return
+	}
+
+	filePath := ReadInput("Enter output file path: ")
+	if filePath == "" {
+		ShowError("File path cannot be empty.")
+		return
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		ShowError("Failed to create file: %v", err)
+		return
+	}
+
+	count, err := c.vault.ShareEntries(file, recipients, filter)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		ShowError("Sharing failed: %v", err)
+		return
+	}
+
+	ShowSuccess("Encrypted %d entries to %d recipients in %s", count, len(parsed), filePath)
+	ShowInfo("Recipients can import it here or decrypt it with: age -d -i key.txt %s", filePath)
+}
+
+func (c *CLI) handleImportShared() {
+	if !c.requireWritable() {
+		return
+	}
+
+	filePath := ReadInput("Enter path of the shared file: ")
+	file, err := os.Open(filePath)
+	if err != nil {
+		ShowError("Failed to open file: %v", err)
+		return
+	}
+	defer file.Close()
+
+	skipDuplicates := ConfirmAction("Skip duplicate entries?")
+	options := vault.ImportOptions{
+		SkipDuplicates: skipDuplicates,
+		UpdateExisting: !skipDuplicates && ConfirmAction("Update existing entries?"),
+		RequiredFields: []string{"service", "username"},
+	}
+
+	err = reviewImport(c.vault, options, false, func(options vault.ImportOptions) (vault.ImportResult, error) {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return vault.ImportResult{}, err
+		}
+		return c.vault.ImportShared(file, options)
+	})
+	if err != nil {
+		ShowError("Import failed: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"pw/config"
+	"pw/vault"
+)
+
+func OpenProfileVault(profile *config.VaultProfile, key string) (*vault.Vault, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be exactly 32 characters long")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(profile.VaultPath), 0700); err != nil {
+		return nil, err
+	}
+
+	return vault.NewVault(profile.VaultPath, []byte(key))
+}
+
+// legacyVaultPaths lists where versions before vault profiles kept the vault:
+// vault.dat in the working directory, or beside the executable.
+func legacyVaultPaths() []string {
+	var paths []string
+	if wd, err := os.Getwd(); err == nil {
+		paths = append(paths, filepath.Join(wd, "vault.dat"))
+	}
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(exe), "vault.dat"))
+	}
+	return paths
+}
+
+// MigrateLegacyVault looks for a vault left by an older version when the
+// profile's vault does not exist yet, and offers to copy it into place. The
+// old file is kept.
+func MigrateLegacyVault(profile *config.VaultProfile) error {
+	if _, err := os.Stat(profile.VaultPath); !os.IsNotExist(err) {
+		return nil
+	}
+
+	for _, legacy := range legacyVaultPaths() {
+		info, err := os.Stat(legacy)
+		if err != nil || !info.Mode().IsRegular() || filepath.Clean(legacy) == filepath.Clean(profile.VaultPath) {
+			continue
+		}
+
+		ShowInfo("Found a vault from an older version at %s. Vaults are now kept at %s.", legacy, profile.VaultPath)
+		if !ConfirmAction("Copy it there now?") {
+			fmt.Fprintf(output, "Starting with an empty vault. Copy %s to %s to use the old one.\n", legacy, profile.VaultPath)
+			return nil
+		}
+
+		data, err := os.ReadFile(legacy)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(profile.VaultPath), 0700); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(profile.VaultPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(profile.VaultPath)
+			return err
+		}
+		ShowSuccess("Copied the vault to %s. You can delete %s once it opens.", profile.VaultPath, legacy)
+		return nil
+	}
+	return nil
+}
+
+func (c *CLI) unlockedNames() []string {
+	names := make([]string, 0, len(c.unlocked))
+	for name := range c.unlocked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *CLI) chooseProfile(prompt string) (string, bool) {
+	names := c.config.ListProfiles()
+
+	fmt.Println("\nVault profiles:")
+	for i, name := range names {
+		status := "locked"
+		if _, ok := c.unlocked[name]; ok {
+			status = "unlocked"
+		}
+		if name == c.vaultName {
+			status = "current"
+		}
+		fmt.Printf("%d. %s (%s)\n", i+1, name, status)
+	}
+
+	choice, err := strconv.Atoi(ReadInput(prompt))
+	if err != nil || choice < 1 || choice > len(names) {
+		ShowError("Invalid choice")
+		return "", false
+	}
+	return names[choice-1], true
+}
+
+func (c *CLI) unlockProfile(name string) (*vault.Vault, error) {
+	if v, ok := c.unlocked[name]; ok {
+		return v, nil
+	}
+
+	profile, err := c.config.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var v *vault.Vault
+	if profile.Team {
+		identity, err := c.personalIdentity()
+		if err != nil {
+			return nil, err
+		}
+		v, err = vault.OpenTeamVault(profile.VaultPath, identity)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		v, err = OpenProfileVault(profile, ReadSecureInput(fmt.Sprintf("Enter the encryption key for %s: ", name)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.unlocked[name] = v
+	return v, nil
+}
+
+func (c *CLI) Use(name string) error {
+	v, err := c.unlockProfile(name)
+	if err != nil {
+		return err
+	}
+	c.vault = v
+	c.vaultName = name
+	return nil
+}
+
+func (c *CLI) handleSwitchVault() {
+	name, ok := c.chooseProfile("\nChoose vault to switch to: ")
+	if !ok {
+		return
+	}
+
+	if err := c.Use(name); err != nil {
+		ShowError("Failed to unlock vault: %v", err)
+		return
+	}
+	ShowSuccess("Switched to vault %s", name)
+}
+
+func (c *CLI) handleVaultProfiles() {
+	for {
+		fmt.Println("\nVault Profiles:")
+		fmt.Println("1. List profiles")
+		fmt.Println("2. Create profile")
+		fmt.Println("3. Rename profile")
+		fmt.Println("4. Delete profile")
+		fmt.Println("5. Set default profile")
+		fmt.Println("6. Back to main menu")
+
+		switch ReadInput("\nEnter choice: ") {
+		case "1":
+			for _, name := range c.config.ListProfiles() {
+				profile, _ := c.config.Profile(name)
+				marker := ""
+				if name == c.config.DefaultVault {
+					marker = " (default)"
+				}
+				fmt.Printf("%s%s: %s\n", name, marker, profile.VaultPath)
+			}
+		case "2":
+			name := ReadInput("Enter profile name: ")
+			path := ReadInput("Enter vault file path (leave empty for default): ")
+			profile, err := c.config.CreateProfile(name, path)
+			if err != nil {
+				ShowError("%v", err)
+				continue
+			}
+			profile.Team = vault.IsTeamVault(profile.VaultPath)
+			ShowSuccess("Created vault profile %s at %s", name, profile.VaultPath)
+		case "3":
+			oldName, ok := c.chooseProfile("\nChoose profile to rename: ")
+			if !ok {
+				continue
+			}
+			newName := ReadInput("Enter new name: ")
+			if err := c.config.RenameProfile(oldName, newName); err != nil {
+				ShowError("%v", err)
+				continue
+			}
+			if v, ok := c.unlocked[oldName]; ok {
+				delete(c.unlocked, oldName)
+				c.unlocked[newName] = v
+			}
+			if c.vaultName == oldName {
+				c.vaultName = newName
+			}
+			ShowSuccess("Renamed %s to %s", oldName, newName)
+		case "4":
+			name, ok := c.chooseProfile("\nChoose profile to delete: ")
+			if !ok {
+				continue
+			}
+			if name == c.vaultName {
+				ShowError("Cannot delete the vault currently in use")
+				continue
+			}
+			profile, _ := c.config.Profile(name)
+			if !ConfirmAction(fmt.Sprintf("Delete profile %s?", name)) {
+				continue
+			}
+			if err := c.config.DeleteProfile(name); err != nil {
+				ShowError("%v", err)
+				continue
+			}
+			delete(c.unlocked, name)
+			if ConfirmAction(fmt.Sprintf("Also delete the vault file %s?", profile.VaultPath)) {
+				if err := os.Remove(profile.VaultPath); err != nil && !os.IsNotExist(err) {
+					ShowError("Failed to delete vault file: %v", err)
+					continue
+				}
+			}
+			ShowSuccess("Deleted vault profile %s", name)
+		case "5":
+			name, ok := c.chooseProfile("\nChoose default profile: ")
+			if !ok {
+				continue
+			}
+			c.config.DefaultVault = name
+			ShowSuccess("Default vault set to %s", name)
+		case "6":
+			return
+		default:
+			ShowError("Invalid choice")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+
+	"pw/crypto"
+	"pw/vault"
+)
+
+func RecoverySplit(v *vault.Vault) error {
+	shares, err := strconv.Atoi(ReadInput("Number of shares to create: "))
+	if err != nil {
+		return fmt.Errorf("invalid number of shares")
+	}
+
+	threshold, err := strconv.Atoi(ReadInput("Shares required to unlock: "))
+	if err != nil {
+		return fmt.Errorf("invalid threshold")
+	}
+
+	fmt.Println("\nShare formats:")
+	fmt.Println("1. Text")
+	fmt.Println("2. Mnemonic words")
+	mnemonic := ReadInput("Choose format (1-2): ") == "2"
+
+	result, err := v.SplitRecoveryKey(shares, threshold)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nAny %d of the following %d shares can unlock this vault.\n", threshold, shares)
+	fmt.Println("Give each share to a different person and store it offline.")
+	for _, share := range result {
+		text := share.String()
+		if mnemonic {
+			text = share.Mnemonic()
+		}
+		fmt.Printf("\nShare %d:\n%s\n", share.X, text)
+	}
+
+	return nil
+}
+
+func RecoveryCombine() ([]byte, error) {
+	first, ok := readShare("Enter share 1 (empty to cancel): ")
+	if !ok {
+		return nil, fmt.Errorf("recovery cancelled")
+	}
+
+	shares := []crypto.Share{first}
+	for len(shares) < first.Threshold {
+		share, ok := readShare(fmt.Sprintf("Enter share %d of %d: ", len(shares)+1, first.Threshold))
+		if !ok {
+			return nil, fmt.Errorf("recovery cancelled")
+		}
+		if problem := shareConflict(shares, share); problem != "" {
+			ShowError("%s", problem)
+			continue
+		}
+		shares = append(shares, share)
+	}
+
+	return vault.RecoverKey(shares)
+}
+
+// readShare asks until a share parses. It reports false when the input is
+// empty.
+func readShare(prompt string) (crypto.Share, bool) {
+	for {
+		input := ReadInput(prompt)
+		if input == "" {
+			return crypto.Share{}, false
+		}
+		share, err := crypto.ParseShare(input)
+		if err == nil {
+			return share, true
+		}
+		ShowError("%v", err)
+	}
+}
+
+// shareConflict explains why share cannot be combined with the shares
+// already entered, or returns "" when it can.
+func shareConflict(shares []crypto.Share, share crypto.Share) string {
+	for _, s := range shares {
+		if s.Threshold != share.Threshold || len(s.Data) != len(share.Data) {
+			return "This share belongs to a different set."
+		}
+		if s.X == share.X {
+			return fmt.Sprintf("Share %d was already entered.", share.X)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pw/vault"
+)
+
+func (c *CLI) handlePasswordRequirements() {
+	fmt.Println("\nPassword Requirements:")
+	fmt.Println("1. Show requirements")
+	fmt.Println("2. Set requirements for a service")
+	fmt.Println("3. Set requirements for a folder")
+	fmt.Println("4. Check entries")
+	fmt.Println("5. Back to main menu")
+
+	switch ReadInput("\nEnter choice: ") {
+	case "1":
+		c.handleShowRequirements()
+	case "2":
+		c.handleSetRequirements(vault.ServiceScope)
+	case "3":
+		c.handleSetRequirements(vault.FolderScope)
+	case "4":
+		c.handleCheckCompliance()
+	case "5":
+		return
+	default:
+		ShowError("Invalid choice")
+	}
+}
+
+func (c *CLI) handleShowRequirements() {
+	list := c.vault.ListRequirements()
+	if len(list) == 0 {
+		ShowInfo("No password requirements are set.")
+		return
+	}
+
+	fmt.Println("\nService requirements take precedence over folder requirements.")
+	for _, r := range list {
+		fmt.Printf("- %s: %s\n", r.Source(), r.PasswordRequirements)
+	}
+}
+
+func (c *CLI) handleSetRequirements(scope vault.RequirementsScope) {
+	if !c.requireWritable() {
+		return
+	}
+
+	var name string
+	if scope == vault.ServiceScope {
+		name = ReadInput("Service name: ")
+	} else {
+		name = ReadInput("Folder (leave empty for all entries): ")
+	}
+	for _, r := range c.vault.ListRequirements() {
+		if r.Scope == scope && strings.EqualFold(r.Name, strings.Trim(strings.TrimSpace(name), "/")) {
+			fmt.Printf("Current requirements: %s\n", r.PasswordRequirements)
+		}
+	}
+
+	fmt.Println("Leave every field empty to remove the requirements.")
+	requirements, ok := readRequirements()
+	if !ok {
+		return
+	}
+
+	if err := c.vault.SetRequirements(scope, name, requirements); err != nil {
+		ShowError("Failed to set requirements: %v", err)
+		return
+	}
+	if requirements.IsZero() {
+		ShowSuccess("Requirements removed")
+	} else {
+		ShowSuccess("Requirements saved: %s", requirements)
+	}
+}
+
+func readRequirements() (vault.PasswordRequirements, bool) {
+	var r vault.PasswordRequirements
+	for _, f := range []struct {
+		prompt string
+		value  *int
+	}{
+		{"Minimum length (0 for none): ", &r.MinLength},
+		{"Maximum length (0 for none): ", &r.MaxLength},
+	} {
+		input := ReadInput(f.prompt)
+		if input == "" {
+			continue
+		}
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 0 {
+			ShowError("Invalid length %q", input)
+			return r, false
+		}
+		*f.value = n
+	}
+
+	r.RequireLower = ConfirmAction("Require a lowercase letter?")
+	r.RequireUpper = ConfirmAction("Require an uppercase letter?")
+	r.RequireDigit = ConfirmAction("Require a digit?")
+	r.RequireSymbol = ConfirmAction("Require a symbol?")
+	r.Forbidden = ReadInput("Forbidden characters (optional): ")
+
+	if err := r.Validate(); err != nil {
+		ShowError("Invalid requirements: %v", err)
+		return r, false
+	}
+	return r, true
+}
+
+func (c *CLI) handleCheckCompliance() {
+	found := 0
+	for _, entry := range c.vault.GetEntries() {
+		r, ok := c.vault.RequirementsFor(entry)
+		if !ok {
+			continue
+		}
+		if violations := r.Check(entry.Password); len(violations) > 0 {
+			found++
+			fmt.Printf("%d. %s (%s) - %s: %s\n", found, entry.Service, entry.Username, r.Source(), strings.Join(violations, ", "))
+		}
+	}
+
+	if found == 0 {
+		ShowSuccess("Every password meets its requirements.")
+	}
+}
+
+// ensureCompliant checks a typed password against the requirements of the
+// entry's service or folder. Unlike the strength check this cannot be
+// waived: the user types another password, or leaves it empty to generate
+// one that complies. It returns false when no password could be generated.
+func (c *CLI) ensureCompliant(entry *vault.Entry) bool {
+	r, ok := c.vault.RequirementsFor(*entry)
+	if !ok {
+		return true
+	}
+
+	for {
+		violations := r.Check(entry.Password)
+		if len(violations) == 0 {
+			return true
+		}
+		ShowError("The password does not meet the requirements of %s: %s", r.Source(), strings.Join(violations, ", "))
+		fmt.Printf("Requirements: %s\n", r.PasswordRequirements)
+
+		if password := ReadSecureInput("Enter another password (leave empty to generate): "); password != "" {
+			entry.Password = password
+			continue
+		}
+
+		password, err := c.generatePassword(*entry)
+		if err == nil && len(r.Check(password)) > 0 {
+			err = fmt.Errorf("the generator cannot meet these requirements")
+		}
+		if err != nil {
+			ShowError("Cannot generate a password: %v", err)
+			return false
+		}
+		fmt.Printf("Generated password: %s\n", c.theme.PasswordStyle.Apply(password))
+		entry.Password = password
+		return true
+	}
+}
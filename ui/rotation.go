@@ -0,0 +1,221 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pw/vault"
+)
+
+const defaultRotationWindowDays = 30
+
+func (c *CLI) handlePasswordRotation() {
+	fmt.Println("\nPassword Rotation:")
+	fmt.Println("1. Show entries due for rotation")
+	fmt.Println("2. Rotate overdue passwords")
+	fmt.Println("3. Set rotation interval for an entry")
+	fmt.Println("4. Set rotation interval for a folder")
+	fmt.Println("5. Back to main menu")
+
+	switch ReadInput("\nEnter choice: ") {
+	case "1":
+		c.handleShowRotationDue()
+	case "2":
+		c.handleRotatePasswords()
+	case "3":
+		c.handleEntryRotation()
+	case "4":
+		c.handleFolderRotation()
+	case "5":
+		return
+	default:
+		ShowError("Invalid choice")
+	}
+}
+
+func (c *CLI) handleShowRotationDue() {
+	days := defaultRotationWindowDays
+	if input := ReadInput(fmt.Sprintf("Show entries due within how many days (default %d): ", days)); input != "" {
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 0 {
+			ShowError("Invalid number of days")
+			return
+		}
+		days = n
+	}
+
+	due := c.vault.RotationDue(time.Duration(days) * 24 * time.Hour)
+	if len(due) == 0 {
+		ShowInfo("No passwords are due for rotation within %d days.", days)
+		return
+	}
+
+	fmt.Printf("\n%d passwords due for rotation:\n", len(due))
+	showRotationStatuses(due)
+}
+
+func showRotationStatuses(statuses []vault.RotationStatus) {
+	for i, s := range statuses {
+		state := "due"
+		if s.Overdue {
+			state = "overdue since"
+		}
+		policy := "expiry date"
+		if s.Days > 0 {
+			policy = fmt.Sprintf("every %d days", s.Days)
+		}
+		fmt.Printf("%d. %s (%s) - %s %s, %s\n", i+1, s.Entry.Service, s.Entry.Username,
+			state, s.DueAt.Format("2006-01-02"), policy)
+	}
+}
+
+func (c *CLI) handleRotatePasswords() {
+	if !c.requireWritable() {
+		return
+	}
+
+	var overdue []vault.RotationStatus
+	for _, s := range c.vault.RotationDue(0) {
+		if s.Overdue {
+			overdue = append(overdue, s)
+		}
+	}
+	if len(overdue) == 0 {
+		ShowInfo("No passwords are overdue for rotation.")
+		return
+	}
+
+	fmt.Printf("\n%d passwords overdue for rotation:\n", len(overdue))
+	showRotationStatuses(overdue)
+
+	selected, ok := readSelection(ReadInput("\nEntries to rotate (comma separated numbers or \"all\"): "), len(overdue))
+	if !ok {
+		ShowError("Invalid selection")
+		return
+	}
+
+	fmt.Println("\nOld passwords are kept in each entry's history. Remember to change them at the services too.")
+	for _, i := range selected {
+		s := overdue[i]
+		password, err := c.generatePassword(s.Entry)
+		if err != nil {
+			ShowError("Cannot generate a password for %s: %v", s.Entry.Service, err)
+			continue
+		}
+		if err := c.vault.RotatePassword(s.Index, password); err != nil {
+			ShowError("Failed to rotate %s: %v", s.Entry.Service, err)
+			continue
+		}
+		fmt.Printf("%s (%s): %s\n", s.Entry.Service, s.Entry.Username, password)
+	}
+}
+
+// readSelection parses "all" or comma separated 1-based numbers up to n into
+// 0-based indexes.
+func readSelection(input string, n int) ([]int, bool) {
+	if strings.EqualFold(strings.TrimSpace(input), "all") {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, true
+	}
+
+	seen := make(map[int]bool)
+	var selected []int
+	for _, part := range strings.Split(input, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || i < 1 || i > n {
+			return nil, false
+		}
+		if !seen[i-1] {
+			seen[i-1] = true
+			selected = append(selected, i-1)
+		}
+	}
+	return selected, len(selected) > 0
+}
+
+func readRotationDays(prompt string) (int, bool) {
+	days, err := strconv.Atoi(ReadInput(prompt))
+	if err != nil || days < 0 {
+		ShowError("Invalid number of days")
+		return 0, false
+	}
+	return days, true
+}
+
+func (c *CLI) handleEntryRotation() {
+	if !c.requireWritable() {
+		return
+	}
+
+	c.handleViewVault()
+	entries := c.vault.GetEntries()
+
+	idx, err := strconv.Atoi(ReadInput("\nEnter entry number: "))
+	if err != nil || idx < 1 || idx > len(entries) {
+		ShowError("Invalid entry number")
+		return
+	}
+
+	entry := entries[idx-1]
+	days, ok := readRotationDays(fmt.Sprintf("Rotate every how many days (current: %d, 0 to use the folder policy): ", entry.RotationDays))
+	if !ok {
+		return
+	}
+
+	entry.RotationDays = days
+	if err := c.vault.UpdateEntry(idx-1, entry); err != nil {
+		ShowError("Failed to update entry: %v", err)
+		return
+	}
+	if days == 0 {
+		ShowSuccess("%s now follows its folder's rotation policy", entry.Service)
+	} else {
+		ShowSuccess("%s will be due for rotation every %d days", entry.Service, days)
+	}
+}
+
+func (c *CLI) handleFolderRotation() {
+	if !c.requireWritable() {
+		return
+	}
+
+	policies := c.vault.FolderRotation()
+	if len(policies) > 0 {
+		folders := make([]string, 0, len(policies))
+		for folder := range policies {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+
+		fmt.Println("\nFolder rotation policies:")
+		for _, folder := range folders {
+			name := folder
+			if name == "" {
+				name = "(all entries)"
+			}
+			fmt.Printf("- %s: every %d days\n", name, policies[folder])
+		}
+	}
+
+	folder := ReadInput("\nFolder (leave empty for all entries): ")
+	days, ok := readRotationDays("Rotate every how many days (0 removes the policy): ")
+	if !ok {
+		return
+	}
+
+	if err := c.vault.SetFolderRotation(folder, days); err != nil {
+		ShowError("Failed to set rotation policy: %v", err)
+		return
+	}
+	if days == 0 {
+		ShowSuccess("Rotation policy removed")
+	} else {
+		ShowSuccess("Passwords will be due for rotation every %d days", days)
+	}
+}
@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"pw/vault"
+)
+
+func (c *CLI) handleShareEntries() {
+	fmt.Println("\nShare or Move Entries:")
+	fmt.Println("1. Export selected entries to a standalone vault")
+	fmt.Println("2. Merge a standalone vault into this vault")
+	fmt.Println("3. Move selected entries to another vault")
+	fmt.Println("4. Back to main menu")
+
+	switch ReadInput("\nEnter choice: ") {
+	case "1":
+		c.handleExportSubset()
+	case "2":
+		c.handleMergeVault()
+	case "3":
+		c.handleMoveEntries()
+	case "4":
+		return
+	default:
+		ShowError("Invalid choice")
+	}
+}
+
+func (c *CLI) readEntryFilter() (vault.EntryFilter, bool) {
+	fmt.Println("\nSelect entries (leave fields empty to ignore them):")
+	filter := vault.EntryFilter{
+		Query:  ReadInput("Search query: "),
+		Tags:   ParseTags(ReadInput("Tags, comma separated: ")),
+		Folder: ReadInput("Folder: "),
+	}
+
+	if err := filter.Validate(); err != nil {
+		ShowError("Invalid search: %v", err)
+		return filter, false
+	}
+
+	var ok bool
+	if filter.Since, ok = readDate("Created on or after (YYYY-MM-DD): "); !ok {
+		return filter, false
+	}
+	if filter.Until, ok = readDate("Created before (YYYY-MM-DD): "); !ok {
+		return filter, false
+	}
+
+	entries := c.vault.SelectEntries(filter)
+	if len(entries) == 0 {
+		ShowInfo("No entries match the selection.")
+		return filter, false
+	}
+
+	fmt.Printf("\n%d entries selected:\n", len(entries))
+	for _, entry := range entries {
+		fmt.Printf("- %s (%s)\n", entry.Service, entry.Username)
+	}
+
+	return filter, ConfirmAction("Continue with these entries?")
+}
+
+func readDate(prompt string) (time.Time, bool) {
+	input := ReadInput(prompt)
+	if input == "" {
+		return time.Time{}, true
+	}
+	date, err := time.ParseInLocation("2006-01-02", input, time.Local)
+	if err != nil {
+		ShowError("Invalid date %q, expected YYYY-MM-DD.", input)
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func readNewPassphrase(prompt string) (string, bool) {
+	passphrase := ReadSecureInput(prompt)
+	if passphrase == "" {
+		ShowError("Passphrase cannot be empty")
+		return "", false
+	}
+	if ReadSecureInput("Confirm passphrase: ") != passphrase {
+		ShowError("Passphrases do not match")
+		return "", false
+	}
+	return passphrase, true
+}
+
+func (c *CLI) handleExportSubset() {
+	filter, ok := c.readEntryFilter()
+	if !ok {
+		return
+	}
+
+	filePath := ReadInput("Enter file path for the new vault: ")
+	if filePath == "" {
+		ShowError("File path cannot be empty.")
+		return
+	}
+
+	passphrase, ok := readNewPassphrase("Enter a passphrase for the new vault: ")
+	if !ok {
+		return
+	}
+
+	count, err := c.vault.ExportVault(filePath, []byte(passphrase), filter)
+	if err != nil {
+		ShowError("Export failed: %v", err)
+		return
+	}
+	ShowSuccess("Wrote %d entries to %s", count, filePath)
+}
+
+func (c *CLI) handleMergeVault() {
+	if !c.requireWritable() {
+		return
+	}
+
+	filePath := ReadInput("Enter path of the vault to merge: ")
+	if filePath == "" {
+		ShowError("File path cannot be empty.")
+		return
+	}
+
+	passphrase := ReadSecureInput("Enter the passphrase for that vault: ")
+	if passphrase == "" {
+		ShowError("Passphrase cannot be empty.")
+		return
+	}
+
+	skipDuplicates := ConfirmAction("Skip duplicate entries?")
+	options := vault.ImportOptions{
+		SkipDuplicates: skipDuplicates,
+		UpdateExisting: !skipDuplicates && ConfirmAction("Update existing entries?"),
+		RequiredFields: []string{"service", "username"},
+	}
+
+	err := reviewImport(c.vault, options, false, func(options vault.ImportOptions) (vault.ImportResult, error) {
+		return c.vault.MergeVault(filePath, []byte(passphrase), options)
+	})
+	if err != nil {
+		ShowError("Merge failed: %v", err)
+	}
+}
+
+func (c *CLI) handleMoveEntries() {
+	if len(c.config.Vaults) < 2 {
+		ShowInfo("Create another vault profile first.")
+		return
+	}
+
+	filter, ok := c.readEntryFilter()
+	if !ok {
+		return
+	}
+
+	targetName, ok := c.chooseProfile("\nChoose destination vault: ")
+	if !ok {
+		return
+	}
+	if targetName == c.vaultName {
+		ShowError("Choose a different vault as the destination")
+		return
+	}
+
+	target, err := c.unlockProfile(targetName)
+	if err != nil {
+		ShowError("Failed to unlock vault: %v", err)
+		return
+	}
+
+	count, err := c.vault.MoveEntries(target, filter)
+	if err != nil {
+		ShowError("Move failed: %v", err)
+		return
+	}
+	ShowSuccess("Moved %d entries from %s to %s", count, c.vaultName, targetName)
+}
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"filippo.io/age"
+
+	"pw/vault"
+)
+
+func (c *CLI) personalIdentity() (*age.X25519Identity, error) {
+	if v, ok := c.unlocked[c.config.DefaultVault]; ok && !v.IsTeam() {
+		return v.Identity()
+	}
+	for _, name := range c.unlockedNames() {
+		if v := c.unlocked[name]; !v.IsTeam() {
+			return v.Identity()
+		}
+	}
+	return nil, fmt.Errorf("unlock a personal vault first to use its identity")
+}
+
+func (c *CLI) requireWritable() bool {
+	if c.vault.ReadOnly() {
+		ShowError("You have read-only access to vault %s", c.vaultName)
+		return false
+	}
+	return true
+}
+
+func (c *CLI) handleTeamVault() {
+	fmt.Println("\nTeam Vault:")
+	fmt.Println("1. Create team vault")
+	fmt.Println("2. List members")
+	fmt.Println("3. Add member")
+	fmt.Println("4. Remove member")
+	fmt.Println("5. Change member role")
+	fmt.Println("6. Back to main menu")
+
+	choice := ReadInput("\nEnter choice: ")
+	if choice == "1" {
+		c.handleCreateTeamVault()
+		return
+	}
+	if choice == "6" {
+		return
+	}
+
+	if !c.vault.IsTeam() {
+		ShowError("Vault %s is not a team vault. Switch to a team vault first.", c.vaultName)
+		return
+	}
+
+	switch choice {
+	case "2":
+		fmt.Printf("\nMembers of %s:\n", c.vaultName)
+		for i, m := range c.vault.Members() {
+			fmt.Printf("%d. %s (%s)\n   %s\n", i+1, m.Name, m.Role, m.PublicKey)
+		}
+	case "3":
+		if !c.requireWritable() {
+			return
+		}
+		name := ReadInput("Member name: ")
+		publicKey := ReadInput("Member public key (age1...): ")
+		role, err := vault.ParseRole(ReadInput("Role (editor/read-only): "))
+		if err != nil {
+			ShowError("%v", err)
+			return
+		}
+		if err := c.vault.AddMember(name, publicKey, role); err != nil {
+			ShowError("Failed to add member: %v", err)
+			return
+		}
+		ShowSuccess("Added %s as %s", name, role)
+	case "4":
+		if !c.requireWritable() {
+			return
+		}
+		name, ok := c.chooseMember("Choose member to remove: ")
+		if !ok {
+			return
+		}
+		if !ConfirmAction(fmt.Sprintf("Remove %s and rotate the vault key?", name)) {
+			return
+		}
+		if err := c.vault.RemoveMember(name); err != nil {
+			ShowError("Failed to remove member: %v", err)
+			return
+		}
+		ShowSuccess("Removed %s and rotated the data key", name)
+	case "5":
+		if !c.requireWritable() {
+			return
+		}
+		name, ok := c.chooseMember("Choose member: ")
+		if !ok {
+			return
+		}
+		role, err := vault.ParseRole(ReadInput("New role (editor/read-only): "))
+		if err != nil {
+			ShowError("%v", err)
+			return
+		}
+		if err := c.vault.SetMemberRole(name, role); err != nil {
+			ShowError("Failed to change role: %v", err)
+			return
+		}
+		ShowSuccess("%s is now %s", name, role)
+	default:
+		ShowError("Invalid choice")
+	}
+}
+
+func (c *CLI) chooseMember(prompt string) (string, bool) {
+	members := c.vault.Members()
+	for i, m := range members {
+		fmt.Printf("%d. %s (%s)\n", i+1, m.Name, m.Role)
+	}
+
+	choice, err := strconv.Atoi(ReadInput(prompt))
+	if err != nil || choice < 1 || choice > len(members) {
+		ShowError("Invalid choice")
+		return "", false
+	}
+	return members[choice-1].Name, true
+}
+
+func (c *CLI) handleCreateTeamVault() {
+	identity, err := c.personalIdentity()
+	if err != nil {
+		ShowError("%v", err)
+		return
+	}
+
+	name := ReadInput("Enter profile name for the team vault: ")
+	path := ReadInput("Enter vault file path (leave empty for default): ")
+	memberName := ReadInput("Your member name: ")
+	if memberName == "" {
+		ShowError("Member name cannot be empty")
+		return
+	}
+
+	profile, err := c.config.CreateProfile(name, path)
+	if err != nil {
+		ShowError("%v", err)
+		return
+	}
+	profile.Team = true
+
+	if err := os.MkdirAll(filepath.Dir(profile.VaultPath), 0700); err != nil {
+		c.config.DeleteProfile(name)
+		ShowError("Failed to create vault directory: %v", err)
+		return
+	}
+
+	v, err := vault.CreateTeamVault(profile.VaultPath, memberName, identity)
+	if err != nil {
+		c.config.DeleteProfile(name)
+		ShowError("Failed to create team vault: %v", err)
+		return
+	}
+
+	c.unlocked[name] = v
+	c.vault = v
+	c.vaultName = name
+	ShowSuccess("Created team vault %s at %s", name, profile.VaultPath)
+}
@@ -0,0 +1,394 @@
+package vault
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pw/security"
+)
+
+// DefaultMaxPasswordAge is how old a password can get before the audit
+// reports it.
+const DefaultMaxPasswordAge = 365 * 24 * time.Hour
+
+type AuditOptions struct {
+	// Breaches is used to look passwords up in breach data. The check is
+	// skipped when it is nil.
+	Breaches security.BreachChecker
+	// MaxPasswordAge defaults to DefaultMaxPasswordAge.
+	MaxPasswordAge time.Duration
+}
+
+// AuditReport is a full security review of a vault. Passwords only appear
+// masked, so the report can be shared.
+type AuditReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Score is the average of the entry scores, from 0 to 100.
+	Score  int    `json:"score"`
+	Rating string `json:"rating"`
+
+	TotalEntries          int       `json:"total_entries"`
+	UniqueServices        int       `json:"unique_services"`
+	UniqueUsernames       int       `json:"unique_usernames"`
+	AveragePasswordLength float64   `json:"average_password_length"`
+	OldestEntry           time.Time `json:"oldest_entry"`
+	NewestEntry           time.Time `json:"newest_entry"`
+
+	Strength AuditStrength `json:"strength"`
+
+	WeakPasswords   []AuditFinding `json:"weak_passwords"`
+	ReusedPasswords []AuditReuse   `json:"reused_passwords"`
+	SimilarFamilies []AuditFamily  `json:"similar_passwords"`
+	// BreachChecked is false when no breach data was available.
+	BreachChecked    bool            `json:"breach_checked"`
+	Breached         []BreachedEntry `json:"breached"`
+	OldPasswords     []AuditFinding  `json:"old_passwords"`
+	OverdueRotations []AuditFinding  `json:"overdue_rotations"`
+	// NonCompliant lists passwords that break the requirements of their
+	// service or folder.
+	NonCompliant     []AuditFinding `json:"non_compliant"`
+	MissingTwoFactor []AuditFinding `json:"missing_2fa"`
+	InsecureURLs     []AuditFinding `json:"insecure_urls"`
+	EmptyPasswords   []AuditFinding `json:"empty_passwords"`
+
+	Usernames       []UsernameCount `json:"usernames"`
+	EntriesPerMonth []MonthCount    `json:"entries_per_month"`
+}
+
+// AuditStrength counts entries per strength level.
+type AuditStrength struct {
+	VeryWeak   int `json:"very_weak"`
+	Weak       int `json:"weak"`
+	Medium     int `json:"medium"`
+	Strong     int `json:"strong"`
+	VeryStrong int `json:"very_strong"`
+}
+
+// AuditEntryRef names an entry without its password.
+type AuditEntryRef struct {
+	Service  string `json:"service"`
+	Username string `json:"username"`
+}
+
+// AuditReuse is one password shared by several entries. Group is a label
+// such as "R1" that tells groups apart without revealing anything about the
+// password.
+type AuditReuse struct {
+	Group   string          `json:"group"`
+	Entries []AuditEntryRef `json:"entries"`
+}
+
+// AuditFamily is a set of different passwords that are variations of each
+// other. Members are labelled "F1.1", "F1.2" and so on.
+type AuditFamily struct {
+	Group   string              `json:"group"`
+	Members []AuditFamilyMember `json:"members"`
+}
+
+type AuditFamilyMember struct {
+	Password string          `json:"password"`
+	Entries  []AuditEntryRef `json:"entries"`
+}
+
+// AuditFinding is an entry with a problem, and what the problem is.
+type AuditFinding struct {
+	Service  string `json:"service"`
+	Username string `json:"username"`
+	Folder   string `json:"folder,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+type MonthCount struct {
+	Month string `json:"month"`
+	Count int    `json:"count"`
+}
+
+// Points deducted from an entry's score of 100 for each problem it has.
+const (
+	emptyPasswordPenalty = 100
+	breachedPenalty      = 60
+	weakPenalty          = 40
+	reusedPenalty        = 30
+	nonCompliantPenalty  = 20
+	mediumPenalty        = 15
+	similarPenalty       = 15
+	overduePenalty       = 15
+	oldPasswordPenalty   = 10
+	insecureURLPenalty   = 10
+	missingTOTPPenalty   = 5
+)
+
+// auditRatings maps minimum scores to letter ratings.
+var auditRatings = []struct {
+	min    int
+	rating string
+}{
+	{90, "A"}, {80, "B"}, {70, "C"}, {60, "D"}, {0, "F"},
+}
+
+// Audit reviews every entry. It only fails when the breach lookup does.
+func (v *Vault) Audit(options AuditOptions) (AuditReport, error) {
+	maxAge := options.MaxPasswordAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxPasswordAge
+	}
+
+	stats := v.CalculateStatistics()
+	report := AuditReport{
+		GeneratedAt:           time.Now(),
+		TotalEntries:          stats.TotalEntries,
+		UniqueServices:        stats.UniqueServices,
+		UniqueUsernames:       stats.UniqueUsernames,
+		AveragePasswordLength: stats.AveragePasswordLen,
+		OldestEntry:           stats.OldestEntry,
+		NewestEntry:           stats.NewestEntry,
+	}
+
+	var breachCounts map[string]int
+	if options.Breaches != nil {
+		breached, counts, err := v.checkBreaches(options.Breaches)
+		if err != nil {
+			return report, err
+		}
+		report.BreachChecked = true
+		report.Breached = breached
+		breachCounts = counts
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	passwords := make(map[string][]string)
+	refs := make(map[string][]AuditEntryRef)
+	usernames := make(map[string]int)
+	months := make(map[string]int)
+	for _, e := range v.Entries {
+		passwords[e.Password] = append(passwords[e.Password], e.Service)
+		refs[e.Password] = append(refs[e.Password], AuditEntryRef{Service: e.Service, Username: e.Username})
+		usernames[e.Username]++
+		months[e.CreatedAt.Format("2006-01")]++
+	}
+	families := groupSimilarPasswords(passwords)
+	similar := make(map[string]bool)
+	for _, group := range families {
+		for _, p := range group {
+			similar[p] = true
+		}
+	}
+	report.ReusedPasswords = auditReuse(refs)
+	report.SimilarFamilies = auditFamilies(refs, families)
+
+	now := time.Now()
+	total := 0
+	for i, e := range v.Entries {
+		finding := AuditFinding{Service: e.Service, Username: e.Username, Folder: e.Folder}
+		penalty := 0
+
+		if e.Password == "" {
+			report.EmptyPasswords = append(report.EmptyPasswords, finding)
+			penalty += emptyPasswordPenalty
+		} else {
+			strength := security.AnalyzePassword(e.Password, e.Service, e.Username)
+			switch strength.Level {
+			case security.VeryWeak:
+				report.Strength.VeryWeak++
+			case security.Weak:
+				report.Strength.Weak++
+			case security.Medium:
+				report.Strength.Medium++
+			case security.Strong:
+				report.Strength.Strong++
+			default:
+				report.Strength.VeryStrong++
+			}
+
+			switch strength.Level {
+			case security.VeryWeak, security.Weak:
+				weak := finding
+				weak.Detail = strength.Level.String()
+				if strength.Warning != "" {
+					weak.Detail += ": " + strength.Warning
+				}
+				report.WeakPasswords = append(report.WeakPasswords, weak)
+				penalty += weakPenalty
+			case security.Medium:
+				penalty += mediumPenalty
+			}
+
+			if len(passwords[e.Password]) > 1 {
+				penalty += reusedPenalty
+			}
+			if similar[e.Password] {
+				penalty += similarPenalty
+			}
+			if breachCounts[e.Password] > 0 {
+				penalty += breachedPenalty
+			}
+		}
+
+		changed := e.PasswordChanged()
+		if !changed.IsZero() && now.Sub(changed) > maxAge {
+			old := finding
+			old.Detail = "last changed " + changed.Format("2006-01-02")
+			report.OldPasswords = append(report.OldPasswords, old)
+			penalty += oldPasswordPenalty
+		}
+
+		if status, ok := v.rotationStatus(i, e, now); ok && status.Overdue {
+			overdue := finding
+			overdue.Detail = "due " + status.DueAt.Format("2006-01-02")
+			report.OverdueRotations = append(report.OverdueRotations, overdue)
+			penalty += overduePenalty
+		}
+
+		if r, ok := v.requirementsFor(e); ok {
+			if violations := r.Check(e.Password); len(violations) > 0 {
+				nonCompliant := finding
+				nonCompliant.Detail = r.Source() + ": " + strings.Join(violations, ", ")
+				report.NonCompliant = append(report.NonCompliant, nonCompliant)
+				penalty += nonCompliantPenalty
+			}
+		}
+
+		if e.TOTP == "" {
+			report.MissingTwoFactor = append(report.MissingTwoFactor, finding)
+			penalty += missingTOTPPenalty
+		}
+
+		insecure := false
+		for _, u := range e.URLs {
+			if isInsecureURL(u) {
+				f := finding
+				f.Detail = u
+				report.InsecureURLs = append(report.InsecureURLs, f)
+				insecure = true
+			}
+		}
+		if insecure {
+			penalty += insecureURLPenalty
+		}
+
+		total += max(0, 100-penalty)
+	}
+
+	report.Score = 100
+	if len(v.Entries) > 0 {
+		report.Score = total / len(v.Entries)
+	}
+	for _, r := range auditRatings {
+		if report.Score >= r.min {
+			report.Rating = r.rating
+			break
+		}
+	}
+
+	report.Usernames = getTopUsernameCounts(usernames, len(usernames))
+	for month, count := range months {
+		report.EntriesPerMonth = append(report.EntriesPerMonth, MonthCount{Month: month, Count: count})
+	}
+	sort.Slice(report.EntriesPerMonth, func(i, j int) bool {
+		return report.EntriesPerMonth[i].Month < report.EntriesPerMonth[j].Month
+	})
+
+	report.normalize()
+	return report, nil
+}
+
+// auditReuse groups the entries sharing a password, largest group first.
+// Empty passwords are reported separately.
+func auditReuse(refs map[string][]AuditEntryRef) []AuditReuse {
+	var groups [][]AuditEntryRef
+	for password, entries := range refs {
+		if password != "" && len(entries) > 1 {
+			groups = append(groups, entries)
+		}
+	}
+	sortEntryGroups(groups)
+
+	reuse := make([]AuditReuse, len(groups))
+	for i, entries := range groups {
+		reuse[i] = AuditReuse{Group: "R" + strconv.Itoa(i+1), Entries: entries}
+	}
+	return reuse
+}
+
+func auditFamilies(refs map[string][]AuditEntryRef, families [][]string) []AuditFamily {
+	var groups [][][]AuditEntryRef
+	for _, family := range families {
+		var members [][]AuditEntryRef
+		for _, p := range family {
+			members = append(members, refs[p])
+		}
+		sortEntryGroups(members)
+		groups = append(groups, members)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i]) != len(groups[j]) {
+			return len(groups[i]) > len(groups[j])
+		}
+		return groups[i][0][0].Service < groups[j][0][0].Service
+	})
+
+	result := make([]AuditFamily, len(groups))
+	for i, members := range groups {
+		family := AuditFamily{Group: "F" + strconv.Itoa(i+1)}
+		for j, entries := range members {
+			family.Members = append(family.Members, AuditFamilyMember{
+				Password: family.Group + "." + strconv.Itoa(j+1),
+				Entries:  entries,
+			})
+		}
+		result[i] = family
+	}
+	return result
+}
+
+// sortEntryGroups orders groups largest first, then by their first service,
+// so the labels do not depend on the passwords.
+func sortEntryGroups(groups [][]AuditEntryRef) {
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i]) != len(groups[j]) {
+			return len(groups[i]) > len(groups[j])
+		}
+		return groups[i][0].Service < groups[j][0].Service
+	})
+}
+
+// normalize turns missing lists into empty ones, so JSON consumers always
+// see arrays.
+func (r *AuditReport) normalize() {
+	r.WeakPasswords = nonNil(r.WeakPasswords)
+	r.ReusedPasswords = nonNil(r.ReusedPasswords)
+	r.SimilarFamilies = nonNil(r.SimilarFamilies)
+	r.Breached = nonNil(r.Breached)
+	r.OldPasswords = nonNil(r.OldPasswords)
+	r.OverdueRotations = nonNil(r.OverdueRotations)
+	r.NonCompliant = nonNil(r.NonCompliant)
+	r.MissingTwoFactor = nonNil(r.MissingTwoFactor)
+	r.InsecureURLs = nonNil(r.InsecureURLs)
+	r.EmptyPasswords = nonNil(r.EmptyPasswords)
+	r.Usernames = nonNil(r.Usernames)
+	r.EntriesPerMonth = nonNil(r.EntriesPerMonth)
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func isInsecureURL(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(u.Scheme, "http") {
+		return false
+	}
+	host := u.Hostname()
+	return host != "localhost" && host != "127.0.0.1" && host != "::1"
+}
@@ -0,0 +1,196 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"pw/security"
+)
+
+type fakeBreaches map[string]int
+
+func (f fakeBreaches) Lookup(hash [sha1.Size]byte) (int, error) {
+	for password, count := range f {
+		if security.PasswordHash(password) == hash {
+			return count, nil
+		}
+	}
+	return 0, nil
+}
+
+func findingServices(findings []AuditFinding) string {
+	services := make([]string, len(findings))
+	for i, f := range findings {
+		services[i] = f.Service
+	}
+	return strings.Join(services, ",")
+}
+
+// auditEntries each have the problems their service names, and "Clean" has
+// none.
+func auditEntries() []Entry {
+	now := time.Now()
+	recent := now.AddDate(0, -1, 0)
+	const totp = "JBSWY3DPEHPK3PXP"
+	return []Entry{
+		{Service: "Clean", Password: "kV9#qL2!xZ7@mW4$", TOTP: totp, URLs: []string{"https://clean.example"}, CreatedAt: recent},
+		{Service: "Weak", Password: "password1", TOTP: totp, CreatedAt: recent},
+		{Service: "Reused", Username: "a", Password: "Tr4nsit-Gl0ve-Harbor", TOTP: totp, CreatedAt: recent},
+		{Service: "Reused", Username: "b", Password: "Tr4nsit-Gl0ve-Harbor", TOTP: totp, CreatedAt: recent},
+		{Service: "Family", Password: "Quokka#2023!Blue", TOTP: totp, CreatedAt: recent},
+		{Service: "Family", Username: "x", Password: "Quokka#2024!Blue", TOTP: totp, CreatedAt: recent},
+		{Service: "Empty", TOTP: totp, CreatedAt: recent},
+		{Service: "Old", Password: "Vq8$wR3^eY6&tU1*", TOTP: totp, CreatedAt: now.AddDate(-2, 0, 0)},
+		{Service: "Overdue", Password: "Lp5!nB8@cX2#zM9%", TOTP: totp, CreatedAt: now.AddDate(0, 0, -40), RotationDays: 30},
+		{Service: "Bank", Password: "Hj4&kD7*sF1^gA3!", TOTP: totp, CreatedAt: recent},
+		{Service: "NoTOTP", Password: "Wz6%xC9$vN2@bQ5#", CreatedAt: recent},
+		{Service: "Insecure", Password: "Rt7^yU4&iO1*pE8!", TOTP: totp, CreatedAt: recent,
+			URLs: []string{"http://router.example", "http://localhost:8080", "https://ok.example"}},
+		{Service: "Breached", Password: "Gm3@hK6#jL9$kZ2%", TOTP: totp, CreatedAt: recent},
+	}
+}
+
+func TestAuditFindings(t *testing.T) {
+	v := newTestVault(t, auditEntries()...)
+	if err := v.SetRequirements(ServiceScope, "bank", PasswordRequirements{MinLength: 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := v.Audit(AuditOptions{Breaches: fakeBreaches{"Gm3@hK6#jL9$kZ2%": 42, "password1": 1000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tt := range map[string]struct {
+		findings []AuditFinding
+		want     string
+	}{
+		"weak":         {report.WeakPasswords, "Weak"},
+		"empty":        {report.EmptyPasswords, "Empty"},
+		"old":          {report.OldPasswords, "Old"},
+		"overdue":      {report.OverdueRotations, "Overdue"},
+		"noncompliant": {report.NonCompliant, "Bank"},
+		"missing 2fa":  {report.MissingTwoFactor, "NoTOTP"},
+		"insecure":     {report.InsecureURLs, "Insecure"},
+	} {
+		if got := findingServices(tt.findings); got != tt.want {
+			t.Errorf("%s = %q, want %q", name, got, tt.want)
+		}
+	}
+	if d := report.InsecureURLs[0].Detail; d != "http://router.example" {
+		t.Errorf("insecure URL detail = %q", d)
+	}
+	if d := report.NonCompliant[0].Detail; d != "service bank: shorter than 20 characters" {
+		t.Errorf("requirements detail = %q", d)
+	}
+
+	if !report.BreachChecked || len(report.Breached) != 2 ||
+		report.Breached[0].Service != "Weak" || report.Breached[1].Service != "Breached" {
+		t.Errorf("breached = %+v", report.Breached)
+	}
+	if len(report.ReusedPasswords) != 1 || report.ReusedPasswords[0].Group != "R1" || len(report.ReusedPasswords[0].Entries) != 2 {
+		t.Errorf("reused = %+v", report.ReusedPasswords)
+	}
+	if len(report.SimilarFamilies) != 1 || len(report.SimilarFamilies[0].Members) != 2 ||
+		report.SimilarFamilies[0].Members[1].Password != "F1.2" {
+		t.Errorf("families = %+v", report.SimilarFamilies)
+	}
+	if report.TotalEntries != 13 || report.Strength.VeryWeak+report.Strength.Weak == 0 {
+		t.Errorf("totals = %d entries, strength %+v", report.TotalEntries, report.Strength)
+	}
+	if report.Score <= 0 || report.Score >= 100 || report.Rating == "" {
+		t.Errorf("score %d (%s)", report.Score, report.Rating)
+	}
+}
+
+func TestAuditScore(t *testing.T) {
+	clean := newTestVault(t, auditEntries()[0])
+	report, err := clean.Audit(AuditOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Score != 100 || report.Rating != "A" || report.BreachChecked {
+		t.Errorf("clean vault: score %d (%s), breach checked %v", report.Score, report.Rating, report.BreachChecked)
+	}
+	if report.WeakPasswords == nil || report.Breached == nil || report.EntriesPerMonth == nil {
+		t.Error("empty lists should not be nil")
+	}
+
+	// Weak (40), breached (60) and without TOTP (5): the score stops at 0.
+	bad := newTestVault(t, Entry{Service: "Bad", Password: "password1", CreatedAt: time.Now()})
+	report, err = bad.Audit(AuditOptions{Breaches: fakeBreaches{"password1": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Score != 0 || report.Rating != "F" {
+		t.Errorf("bad vault: score %d (%s)", report.Score, report.Rating)
+	}
+
+	empty := newTestVault(t)
+	if report, _ := empty.Audit(AuditOptions{}); report.Score != 100 {
+		t.Errorf("empty vault score = %d", report.Score)
+	}
+}
+
+func TestWriteAuditReport(t *testing.T) {
+	entries := append(auditEntries(), Entry{Service: "<script>a|b*c</script>", Password: "Zx4!Cv7@Bn1#Mq8$", CreatedAt: time.Now()})
+	v := newTestVault(t, entries...)
+	report, err := v.Audit(AuditOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(format AuditFormat) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := WriteAuditReport(&buf, report, format); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, e := range entries {
+			if e.Password != "" && strings.Contains(out, e.Password) {
+				t.Errorf("%s report contains the password of %s", format, e.Service)
+			}
+		}
+		return out
+	}
+
+	md := render(MarkdownAudit)
+	for _, want := range []string{
+		fmt.Sprintf("**Score: %d/100 (%s)**", report.Score, report.Rating),
+		"## Reused Passwords",
+		"| R1 | 2 | Reused (a), Reused (b) |",
+		"| F1 | F1.1 | Family |",
+		"Not checked: no breach data is configured.",
+		`<script>a\|b\*c</script>`,
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown is missing %q", want)
+		}
+	}
+
+	html := render(HTMLAudit)
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;a|b*c&lt;/script&gt;") {
+		t.Error("HTML report does not escape service names")
+	}
+	if !strings.Contains(html, `class="score rating-`+report.Rating+`"`) {
+		t.Error("HTML report has no score")
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(render(JSONAudit)), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if breached, ok := decoded["breached"].([]interface{}); !ok || len(breached) != 0 {
+		t.Errorf("JSON breached = %v, want an empty array", decoded["breached"])
+	}
+
+	if err := WriteAuditReport(&bytes.Buffer{}, report, "pdf"); err == nil {
+		t.Error("accepted an unknown format")
+	}
+}
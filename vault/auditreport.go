@@ -0,0 +1,249 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type AuditFormat string
+
+const (
+	MarkdownAudit AuditFormat = "markdown"
+	// HTMLAudit is a standalone page with inline styles.
+	HTMLAudit AuditFormat = "html"
+	JSONAudit AuditFormat = "json"
+)
+
+// auditSection is a titled table of the report. Empty is shown instead of
+// the table when there are no rows.
+type auditSection struct {
+	Title   string
+	Note    string
+	Empty   string
+	Headers []string
+	Rows    [][]string
+}
+
+// WriteAuditReport renders report in the given format.
+func WriteAuditReport(w io.Writer, report AuditReport, format AuditFormat) error {
+	switch format {
+	case MarkdownAudit:
+		return writeAuditMarkdown(w, report)
+	case HTMLAudit:
+		return auditTemplate.Execute(w, struct {
+			AuditReport
+			Sections []auditSection
+		}{report, report.sections()})
+	case JSONAudit:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	default:
+		return fmt.Errorf("unsupported audit format: %s", format)
+	}
+}
+
+func findingRows(findings []AuditFinding) [][]string {
+	rows := make([][]string, 0, len(findings))
+	for _, f := range findings {
+		rows = append(rows, []string{f.Service, f.Username, f.Folder, f.Detail})
+	}
+	return rows
+}
+
+func reportDate(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
+func (r AuditReport) sections() []auditSection {
+	findingHeaders := []string{"Service", "Username", "Folder", "Details"}
+	itoa := strconv.Itoa
+
+	sections := []auditSection{
+		{
+			Title:   "Overview",
+			Headers: []string{"Metric", "Value"},
+			Rows: [][]string{
+				{"Entries", itoa(r.TotalEntries)},
+				{"Unique services", itoa(r.UniqueServices)},
+				{"Unique usernames", itoa(r.UniqueUsernames)},
+				{"Average password length", fmt.Sprintf("%.1f", r.AveragePasswordLength)},
+				{"Oldest entry", reportDate(r.OldestEntry)},
+				{"Newest entry", reportDate(r.NewestEntry)},
+			},
+		},
+		{
+			Title:   "Password Strength",
+			Note:    "Empty passwords are not counted.",
+			Headers: []string{"Level", "Entries"},
+			Rows: [][]string{
+				{"Very strong", itoa(r.Strength.VeryStrong)},
+				{"Strong", itoa(r.Strength.Strong)},
+				{"Medium", itoa(r.Strength.Medium)},
+				{"Weak", itoa(r.Strength.Weak)},
+				{"Very weak", itoa(r.Strength.VeryWeak)},
+			},
+		},
+		{
+			Title:   "Weak Passwords",
+			Empty:   "No weak passwords.",
+			Headers: findingHeaders,
+			Rows:    findingRows(r.WeakPasswords),
+		},
+	}
+
+	reused := auditSection{
+		Title:   "Reused Passwords",
+		Note:    "Entries in the same group share one password.",
+		Empty:   "No password is used more than once.",
+		Headers: []string{"Group", "Entries", "Used by"},
+	}
+	for _, p := range r.ReusedPasswords {
+		reused.Rows = append(reused.Rows, []string{p.Group, itoa(len(p.Entries)), entryList(p.Entries)})
+	}
+
+	similar := auditSection{
+		Title:   "Similar Passwords",
+		Note:    "Different passwords that are variations of each other. Each password has its own label.",
+		Empty:   "No similar passwords.",
+		Headers: []string{"Family", "Password", "Used by"},
+	}
+	for _, f := range r.SimilarFamilies {
+		for _, m := range f.Members {
+			similar.Rows = append(similar.Rows, []string{f.Group, m.Password, entryList(m.Entries)})
+		}
+	}
+
+	breached := auditSection{
+		Title:   "Breached Passwords",
+		Empty:   "No passwords were found in the breach data.",
+		Headers: []string{"Service", "Username", "Times seen"},
+	}
+	if !r.BreachChecked {
+		breached.Empty = "Not checked: no breach data is configured."
+	}
+	for _, b := range r.Breached {
+		breached.Rows = append(breached.Rows, []string{b.Service, b.Username, itoa(b.Count)})
+	}
+
+	usernames := auditSection{
+		Title:   "Usernames",
+		Empty:   "No entries.",
+		Headers: []string{"Username", "Entries"},
+	}
+	for _, u := range r.Usernames {
+		usernames.Rows = append(usernames.Rows, []string{u.Username, itoa(u.Count)})
+	}
+
+	months := auditSection{
+		Title:   "Entries per Month",
+		Empty:   "No entries.",
+		Headers: []string{"Month", "Entries created"},
+	}
+	for _, m := range r.EntriesPerMonth {
+		months.Rows = append(months.Rows, []string{m.Month, itoa(m.Count)})
+	}
+
+	return append(sections,
+		reused,
+		similar,
+		breached,
+		auditSection{Title: "Old Passwords", Empty: "No old passwords.", Headers: findingHeaders, Rows: findingRows(r.OldPasswords)},
+		auditSection{Title: "Overdue Rotations", Empty: "No passwords are overdue for rotation.", Headers: findingHeaders, Rows: findingRows(r.OverdueRotations)},
+		auditSection{Title: "Password Requirements", Note: "Passwords that break the requirements of their service or folder.", Empty: "Every password meets its requirements.", Headers: findingHeaders, Rows: findingRows(r.NonCompliant)},
+		auditSection{Title: "Missing Two-Factor Authentication", Note: "Entries without a TOTP secret.", Empty: "Every entry has a TOTP secret.", Headers: findingHeaders, Rows: findingRows(r.MissingTwoFactor)},
+		auditSection{Title: "Insecure URLs", Note: "URLs using http:// send the password unencrypted.", Empty: "No insecure URLs.", Headers: findingHeaders, Rows: findingRows(r.InsecureURLs)},
+		auditSection{Title: "Empty Passwords", Empty: "No empty passwords.", Headers: findingHeaders, Rows: findingRows(r.EmptyPasswords)},
+		usernames,
+		months,
+	)
+}
+
+// markdownCellEscaper keeps masked passwords and names from being read as
+// markup or breaking the table.
+var markdownCellEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "\n", " ", "\r", "",
+)
+
+func writeAuditMarkdown(w io.Writer, r AuditReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Vault Audit\n\nGenerated %s\n\n", r.GeneratedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "**Score: %d/100 (%s)**\n", r.Score, r.Rating)
+
+	for _, s := range r.sections() {
+		fmt.Fprintf(&b, "\n## %s\n\n", s.Title)
+		if s.Note != "" {
+			fmt.Fprintf(&b, "%s\n\n", s.Note)
+		}
+		if len(s.Rows) == 0 {
+			fmt.Fprintf(&b, "%s\n", s.Empty)
+			continue
+		}
+
+		b.WriteString("| " + strings.Join(s.Headers, " | ") + " |\n")
+		b.WriteString(strings.Repeat("| --- ", len(s.Headers)) + "|\n")
+		for _, row := range s.Rows {
+			for _, cell := range row {
+				b.WriteString("| " + markdownCellEscaper.Replace(cell) + " ")
+			}
+			b.WriteString("|\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var auditTemplate = template.Must(template.New("audit").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Vault Audit</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 960px; color: #222; }
+h1 { margin-bottom: 0; }
+.generated { color: #666; }
+.score { font-size: 1.5em; font-weight: bold; margin: 1em 0; }
+.rating-A, .rating-B { color: #1a7f37; }
+.rating-C, .rating-D { color: #9a6700; }
+.rating-F { color: #cf222e; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; }
+th { background: #f6f8fa; }
+.empty, .note { color: #666; }
+</style>
+</head>
+<body>
+<h1>Vault Audit</h1>
+<p class="generated">Generated {{.GeneratedAt.Format "2006-01-02 15:04:05"}}</p>
+<p class="score rating-{{.Rating}}">Score: {{.Score}}/100 ({{.Rating}})</p>
+{{range .Sections}}
+<h2>{{.Title}}</h2>
+{{if .Note}}<p class="note">{{.Note}}</p>{{end}}
+{{if .Rows}}<table>
+<tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>{{else}}<p class="empty">{{.Empty}}</p>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+func entryList(entries []AuditEntryRef) string {
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Service
+		if e.Username != "" {
+			names[i] += " (" + e.Username + ")"
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,205 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type bitwardenExport struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	Type         int              `json:"type"`
+	Name         string           `json:"name"`
+	Notes        string           `json:"notes"`
+	FolderID     string           `json:"folderId"`
+	Favorite     bool             `json:"favorite"`
+	Fields       []bitwardenField `json:"fields"`
+	Login        *bitwardenLogin  `json:"login"`
+	CreationDate string           `json:"creationDate"`
+	RevisionDate string           `json:"revisionDate"`
+}
+
+type bitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+type bitwardenLogin struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	TOTP     string `json:"totp"`
+	URIs     []struct {
+		URI string `json:"uri"`
+	} `json:"uris"`
+}
+
+const (
+	bitwardenLoginItem      = 1
+	bitwardenSecureNoteItem = 2
+	bitwardenCardItem       = 3
+	bitwardenIdentityItem   = 4
+
+	bitwardenHiddenField = 1
+)
+
+// isBitwardenJSON reports whether content, the start of a JSON object that
+// may be cut off, has a top-level "encrypted" or "items" key. Keys inside
+// entries, and the same words in values, do not count.
+func isBitwardenJSON(content string) bool {
+	dec := json.NewDecoder(strings.NewReader(content))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return false
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		if key == "encrypted" || key == "items" {
+			return true
+		}
+		if err := skipJSONValue(dec); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func parseBitwardenJSON(r io.Reader, result *ImportResult) ([]Entry, error) {
+	var export bitwardenExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("encrypted Bitwarden exports are not supported, export as unencrypted JSON")
+	}
+
+	folders := make(map[string]string)
+	for _, f := range export.Folders {
+		folders[f.ID] = f.Name
+	}
+
+	var entries []Entry
+	for _, item := range export.Items {
+		if reason := bitwardenSkipReason(item.Type); reason != "" {
+			result.reject(item.Name, reason)
+			continue
+		}
+
+		entry := Entry{
+			Service:   item.Name,
+			Notes:     item.Notes,
+			Folder:    folders[item.FolderID],
+			CreatedAt: time.Now(),
+		}
+		if item.Favorite {
+			entry.Tags = append(entry.Tags, "favorite")
+		}
+		if t, err := time.Parse(time.RFC3339, item.CreationDate); err == nil {
+			entry.CreatedAt = t
+		}
+
+		if item.Login != nil {
+			entry.Username = item.Login.Username
+			entry.Password = item.Login.Password
+			entry.TOTP = item.Login.TOTP
+			for _, u := range item.Login.URIs {
+				if u.URI != "" {
+					entry.URLs = append(entry.URLs, u.URI)
+				}
+			}
+		}
+
+		for _, f := range item.Fields {
+			entry.Fields = append(entry.Fields, CustomField{
+				Name:   f.Name,
+				Value:  f.Value,
+				Hidden: f.Type == bitwardenHiddenField,
+			})
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func bitwardenSkipReason(itemType int) string {
+	switch itemType {
+	case bitwardenLoginItem, bitwardenSecureNoteItem:
+		return ""
+	case bitwardenCardItem:
+		return "card items are not supported"
+	case bitwardenIdentityItem:
+		return "identity items are not supported"
+	default:
+		return fmt.Sprintf("unsupported item type %d", itemType)
+	}
+}
+
+func bitwardenCSVEntry(row csvRow) (Entry, string) {
+	entry := Entry{
+		Service:  row.get("name"),
+		Username: row.get("login_username"),
+		Password: row.get("login_password"),
+		Notes:    row.get("notes"),
+		TOTP:     row.get("login_totp"),
+		Folder:   row.get("folder"),
+	}
+	switch itemType := row.get("type"); itemType {
+	case "login", "note", "":
+	default:
+		return entry, itemType + " items are not supported"
+	}
+
+	if entry.Folder == "" {
+		entry.Folder = row.get("collections")
+	}
+	if row.get("favorite") == "1" {
+		entry.Tags = append(entry.Tags, "favorite")
+	}
+	for _, uri := range strings.Split(row.get("login_uri"), ",") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			entry.URLs = append(entry.URLs, uri)
+		}
+	}
+	for _, line := range strings.Split(row.get("fields"), "\n") {
+		if name, value, ok := strings.Cut(line, ": "); ok {
+			entry.Fields = append(entry.Fields, CustomField{Name: name, Value: value})
+		}
+	}
+
+	return entry, ""
+}
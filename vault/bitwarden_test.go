@@ -0,0 +1,63 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsBitwardenJSON(t *testing.T) {
+	longFolders := `{"encrypted": false, "folders": [` + strings.Repeat(`{"id": "1", "name": "folder"},`, 50)
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"bitwarden", `{"encrypted": false, "folders": [], "items": []}`, true},
+		{"items only", `{"items": [{"type": 1}]}`, true},
+		{"password protected", `{"encrypted": true, "passwordProtected": true, "salt": "x"}`, true},
+		{"cut off", longFolders, true},
+		{"value mentions items", `{"service": "Shop", "notes": "\"items\": 3", "password": "x"}`, false},
+		{"nested key", `{"service": "Shop", "fields": [{"name": "encrypted", "value": "items"}], "x": {"items": 1}}`, false},
+		{"ndjson", "{\"service\": \"a\", \"notes\": \"encrypted\"}\n{\"service\": \"b\"}", false},
+		{"not json", `{not json`, false},
+	}
+	for _, tt := range tests {
+		if got := isBitwardenJSON(tt.content); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBitwardenSecureNotes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bitwarden.json")
+	data := `{"encrypted": false, "folders": [], "items": [
+		{"type": 1, "name": "GitHub", "login": {"username": "alice", "password": "pw"}},
+		{"type": 2, "name": "Wifi codes", "notes": "guest: 1234"},
+		{"type": 3, "name": "Visa"}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	format, err := DetectImportFormat(path)
+	if err != nil || format != BitwardenJSON {
+		t.Fatalf("format = %q, %v", format, err)
+	}
+	if !format.HasSecureNotes() {
+		t.Fatal("Bitwarden JSON should allow secure notes")
+	}
+
+	v := newTestVault(t)
+	result, err := v.Import(path, ImportOptions{Format: format, RequiredFields: []string{"service"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Added) != 2 || len(result.Rejected) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(v.Entries) != 2 || v.Entries[1].Service != "Wifi codes" || v.Entries[1].Notes != "guest: 1234" {
+		t.Errorf("entries = %+v", v.Entries)
+	}
+}
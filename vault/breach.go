@@ -0,0 +1,53 @@
+package vault
+
+import (
+	"sort"
+
+	"pw/security"
+)
+
+// BreachedEntry is an entry whose password appears in breach data Count
+// times.
+type BreachedEntry struct {
+	Service  string `json:"service"`
+	Username string `json:"username"`
+	Count    int    `json:"count"`
+}
+
+// CheckBreaches looks every distinct password up once and returns the
+// compromised entries, most exposed first.
+func (v *Vault) CheckBreaches(checker security.BreachChecker) ([]BreachedEntry, error) {
+	breached, _, err := v.checkBreaches(checker)
+	return breached, err
+}
+
+// checkBreaches also returns the breach count of every password it looked up.
+func (v *Vault) checkBreaches(checker security.BreachChecker) ([]BreachedEntry, map[string]int, error) {
+	entries := v.GetEntries()
+
+	counts := make(map[string]int)
+	var breached []BreachedEntry
+	for _, e := range entries {
+		if e.Password == "" {
+			continue
+		}
+
+		count, ok := counts[e.Password]
+		if !ok {
+			var err error
+			if count, err = checker.Lookup(security.PasswordHash(e.Password)); err != nil {
+				return nil, nil, err
+			}
+			counts[e.Password] = count
+		}
+
+		if count > 0 {
+			breached = append(breached, BreachedEntry{Service: e.Service, Username: e.Username, Count: count})
+		}
+	}
+
+	sort.SliceStable(breached, func(i, j int) bool {
+		return breached[i].Count > breached[j].Count
+	})
+	return breached, counts, nil
+}
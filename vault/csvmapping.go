@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// MappableFields lists the entry fields a CSV column can be mapped to.
+// The names match the CSV export's headers, so an export reads back in
+// with no mapping.
+var MappableFields = []string{"service", "username", "password", "notes", "folder", "tags", "urls", "totp", "created_at", "expires_at"}
+
+// fieldAliases are older names for mappable fields, still accepted in
+// headers and saved mappings.
+var fieldAliases = map[string]string{"url": "urls"}
+
+const csvPreviewRows = 3
+
+// ColumnMapping maps entry fields to the CSV header that holds them.
+type ColumnMapping map[string]string
+
+// UnmappedColumnsError is returned when none of a CSV file's headers can be
+// matched to entry fields. It carries the header and the first rows so the
+// caller can ask the user for a mapping.
+type UnmappedColumnsError struct {
+	Header  []string
+	Preview [][]string
+}
+
+func (e *UnmappedColumnsError) Error() string {
+	return fmt.Sprintf("unrecognized CSV columns: %s", strings.Join(e.Header, ", "))
+}
+
+// ParseColumnMapping reads a mapping such as "service=Title,username=Login".
+func ParseColumnMapping(spec string) (ColumnMapping, error) {
+	mapping := make(ColumnMapping)
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %q, expected field=column", pair)
+		}
+		field = canonicalField(strings.ToLower(strings.TrimSpace(field)))
+		if !isMappableField(field) {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(MappableFields, ", "))
+		}
+		mapping[field] = strings.TrimSpace(column)
+	}
+
+	if len(mapping) == 0 {
+		return nil, fmt.Errorf("mapping is empty")
+	}
+	return mapping, nil
+}
+
+func (m ColumnMapping) String() string {
+	columns := make(map[string]string, len(m))
+	for field, column := range m {
+		columns[canonicalField(field)] = column
+	}
+
+	var pairs []string
+	for _, field := range MappableFields {
+		if column, ok := columns[field]; ok {
+			pairs = append(pairs, field+"="+column)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Fits reports whether every column the mapping refers to is in header.
+func (m ColumnMapping) Fits(header []string) bool {
+	_, err := m.indexes(header)
+	return err == nil
+}
+
+func (m ColumnMapping) indexes(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, h := range header {
+		columns[normalizeHeader(h)] = i
+	}
+
+	indexes := make(map[string]int)
+	for field, column := range m {
+		i, ok := columns[normalizeHeader(column)]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found in CSV header", column)
+		}
+		indexes[canonicalField(field)] = i
+	}
+	return indexes, nil
+}
+
+// IsSecretColumn reports whether a CSV header looks like it names a password
+// or another secret, so previews can hide its values.
+func IsSecretColumn(header string) bool {
+	for _, word := range strings.FieldsFunc(normalizeHeader(header), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		switch word {
+		case "pin", "key", "otp", "totp", "cvv", "cvc", "code":
+			return true
+		}
+		for _, part := range []string{"pass", "pwd", "secret", "token", "otpauth"} {
+			if strings.Contains(word, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func canonicalField(field string) string {
+	if name, ok := fieldAliases[field]; ok {
+		return name
+	}
+	return field
+}
+
+func isMappableField(field string) bool {
+	for _, f := range MappableFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultColumns matches pwvault's own CSV export, where each header is
+// named after the field it holds.
+func defaultColumns(header []string) map[string]int {
+	indexes := make(map[string]int)
+	for i, h := range header {
+		if name := canonicalField(normalizeHeader(h)); isMappableField(name) {
+			indexes[name] = i
+		}
+	}
+	return indexes
+}
+
+func unmappedColumns(header []string, reader *csv.Reader) error {
+	e := &UnmappedColumnsError{Header: header}
+	for len(e.Preview) < csvPreviewRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		e.Preview = append(e.Preview, record)
+	}
+	return e
+}
@@ -0,0 +1,111 @@
+package vault
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMappingOverridesCSVProfile(t *testing.T) {
+	// The header matches the LastPass profile, which reads the service from
+	// "name". The mapping takes it from "extra" instead.
+	path := filepath.Join(t.TempDir(), "export.csv")
+	data := "url,username,password,extra,name,grouping\nhttps://a.example,alice,pw1,Service A,ignored,Work\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	mapping, err := ParseColumnMapping("service=extra,username=username,password=password,folder=grouping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := newTestVault(t)
+	if _, err := v.Import(path, ImportOptions{Format: AutoDetect, Mapping: mapping}); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Entries) != 1 {
+		t.Fatalf("imported %d entries, want 1", len(v.Entries))
+	}
+	if e := v.Entries[0]; e.Service != "Service A" || e.Username != "alice" || e.Password != "pw1" || e.Folder != "Work" {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestIsSecretColumn(t *testing.T) {
+	tests := map[string]bool{
+		"Password":       true,
+		"login_password": true,
+		"Passwort":       true,
+		"PWD":            true,
+		"API Key":        true,
+		"Secret":         true,
+		"PIN":            true,
+		"otpauth":        true,
+		"TOTP":           true,
+		"Title":          false,
+		"Login":          false,
+		"URL":            false,
+		"Shipping":       false,
+		"Keyboard":       false,
+	}
+	for header, want := range tests {
+		if got := IsSecretColumn(header); got != want {
+			t.Errorf("IsSecretColumn(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+func TestCSVExportRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	want := Entry{
+		Service:   "GitHub",
+		Username:  "alice",
+		Password:  "gh-pass",
+		Notes:     "notes",
+		Folder:    "Work/Dev",
+		Tags:      []string{"dev", "work"},
+		URLs:      []string{"https://github.com", "https://gist.github.com/a;b"},
+		TOTP:      "otpauth://totp/GitHub?secret=ABC",
+		CreatedAt: created,
+		ExpiresAt: created.AddDate(1, 0, 0),
+	}
+	v := newTestVault(t, want)
+
+	var buf bytes.Buffer
+	if err := v.ExportTo(&buf, ExportOptions{Format: CSVFormat, Fields: ExportFields, Plaintext: true}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "export.csv")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := newTestVault(t)
+	if _, err := imported.Import(path, ImportOptions{Format: AutoDetect}); err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Entries) != 1 {
+		t.Fatalf("imported %d entries, want 1", len(imported.Entries))
+	}
+	got := imported.Entries[0]
+	got.CreatedAt, got.ExpiresAt = got.CreatedAt.UTC(), got.ExpiresAt.UTC()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestURLColumnAlias(t *testing.T) {
+	mapping, err := ParseColumnMapping("service=Site,url=Address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping["urls"] != "Address" {
+		t.Errorf("mapping = %v, want url stored as urls", mapping)
+	}
+	if columns := defaultColumns([]string{"service", "URL"}); columns["urls"] != 1 {
+		t.Errorf("defaultColumns = %v, want the url header read as urls", columns)
+	}
+}
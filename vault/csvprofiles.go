@@ -0,0 +1,282 @@
+package vault
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVProfile describes the CSV layout written by another password manager
+// or browser. A file matches a profile when its header contains every
+// column in Signature.
+type CSVProfile struct {
+	Format    ImportFormat
+	Name      string
+	Signature []string
+	entry     func(row csvRow) (Entry, string)
+}
+
+// Profiles are checked in order, so more specific signatures come first.
+var csvProfiles = []CSVProfile{
+	{
+		Format:    BitwardenCSV,
+		Name:      "Bitwarden CSV",
+		Signature: []string{"login_username", "login_password"},
+		entry:     bitwardenCSVEntry,
+	},
+	{
+		Format:    OnePasswordCSV,
+		Name:      "1Password CSV",
+		Signature: []string{"title", "url", "username", "password", "otpauth", "favorite", "archived"},
+		entry:     onePasswordCSVEntry,
+	},
+	{
+		Format:    LastPassCSV,
+		Name:      "LastPass CSV",
+		Signature: []string{"url", "username", "password", "extra", "name", "grouping"},
+		entry:     lastPassCSVEntry,
+	},
+	{
+		Format:    FirefoxCSV,
+		Name:      "Firefox CSV",
+		Signature: []string{"url", "username", "password", "httprealm", "formactionorigin"},
+		entry:     firefoxCSVEntry,
+	},
+	{
+		Format:    AppleCSV,
+		Name:      "Apple Passwords CSV",
+		Signature: []string{"title", "url", "username", "password"},
+		entry:     appleCSVEntry,
+	},
+	{
+		Format:    ChromeCSV,
+		Name:      "Chrome CSV",
+		Signature: []string{"name", "url", "username", "password"},
+		entry:     chromeCSVEntry,
+	},
+}
+
+func CSVProfiles() []CSVProfile {
+	return csvProfiles
+}
+
+func csvProfileFor(format ImportFormat) (CSVProfile, bool) {
+	for _, p := range csvProfiles {
+		if p.Format == format {
+			return p, true
+		}
+	}
+	return CSVProfile{}, false
+}
+
+func detectCSVProfile(header []string) (CSVProfile, bool) {
+	columns := make(map[string]bool)
+	for _, h := range header {
+		columns[normalizeHeader(h)] = true
+	}
+
+	for _, p := range csvProfiles {
+		matched := true
+		for _, col := range p.Signature {
+			if !columns[col] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return p, true
+		}
+	}
+	return CSVProfile{}, false
+}
+
+func normalizeHeader(h string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+}
+
+type csvRow struct {
+	index  map[string]int
+	record []string
+}
+
+func (r csvRow) get(name string) string {
+	if i, ok := r.index[name]; ok && i < len(r.record) {
+		return r.record[i]
+	}
+	return ""
+}
+
+func parseProfileCSV(r io.Reader, profile CSVProfile, result *ImportResult) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	for i, field := range header {
+		index[normalizeHeader(field)] = i
+	}
+
+	var entries []Entry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry, reason := profile.entry(csvRow{index: index, record: record})
+		if reason != "" {
+			label := entry.Service
+			if label == "" {
+				label = fmt.Sprintf("row %d", line)
+			}
+			result.reject(label, reason)
+			continue
+		}
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = time.Now()
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func serviceFromURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+func chromeCSVEntry(row csvRow) (Entry, string) {
+	entry := Entry{
+		Service:  row.get("name"),
+		Username: row.get("username"),
+		Password: row.get("password"),
+		Notes:    row.get("note"),
+	}
+	if u := row.get("url"); u != "" {
+		entry.URLs = []string{u}
+		if entry.Service == "" {
+			entry.Service = serviceFromURL(u)
+		}
+	}
+	if entry.Service == "" {
+		return entry, "row has no name or URL"
+	}
+	if entry.Password == "" {
+		return entry, "row has no password"
+	}
+	return entry, ""
+}
+
+func firefoxCSVEntry(row csvRow) (Entry, string) {
+	u := row.get("url")
+	entry := Entry{
+		Service:  serviceFromURL(u),
+		Username: row.get("username"),
+		Password: row.get("password"),
+	}
+	if u == "" {
+		return entry, "row has no URL"
+	}
+	if entry.Password == "" {
+		return entry, "row has no password"
+	}
+	entry.URLs = []string{u}
+
+	// Firefox writes times as milliseconds since the Unix epoch.
+	if ms, err := strconv.ParseInt(row.get("timecreated"), 10, 64); err == nil && ms > 0 {
+		entry.CreatedAt = time.UnixMilli(ms)
+	}
+	return entry, ""
+}
+
+// LastPass marks secure notes with this placeholder URL.
+const lastPassNoteURL = "http://sn"
+
+func lastPassCSVEntry(row csvRow) (Entry, string) {
+	entry := Entry{
+		Service:  row.get("name"),
+		Username: row.get("username"),
+		Password: row.get("password"),
+		Notes:    row.get("extra"),
+		TOTP:     row.get("totp"),
+		Folder:   strings.ReplaceAll(row.get("grouping"), "\\", "/"),
+	}
+	u := row.get("url")
+	if u != "" && u != lastPassNoteURL {
+		entry.URLs = []string{u}
+		if entry.Service == "" {
+			entry.Service = serviceFromURL(u)
+		}
+	}
+	if row.get("fav") == "1" {
+		entry.Tags = append(entry.Tags, "favorite")
+	}
+	if entry.Service == "" {
+		return entry, "row has no name or URL"
+	}
+	if u == lastPassNoteURL && strings.HasPrefix(entry.Notes, "NoteType:") {
+		return entry, "structured secure notes are not supported"
+	}
+	return entry, ""
+}
+
+func appleCSVEntry(row csvRow) (Entry, string) {
+	entry := Entry{
+		Service:  row.get("title"),
+		Username: row.get("username"),
+		Password: row.get("password"),
+		Notes:    row.get("notes"),
+		TOTP:     row.get("otpauth"),
+	}
+	if u := row.get("url"); u != "" {
+		entry.URLs = []string{u}
+		if entry.Service == "" {
+			entry.Service = serviceFromURL(u)
+		}
+	}
+	if entry.Service == "" {
+		return entry, "row has no title or URL"
+	}
+	if entry.Password == "" {
+		return entry, "row has no password"
+	}
+	return entry, ""
+}
+
+func onePasswordCSVEntry(row csvRow) (Entry, string) {
+	entry, reason := appleCSVEntry(row)
+	if strings.EqualFold(row.get("archived"), "true") {
+		return entry, "item is archived"
+	}
+	if strings.EqualFold(row.get("favorite"), "true") {
+		entry.Tags = append(entry.Tags, "favorite")
+	}
+	entry.Tags = append(entry.Tags, splitTags(row.get("tags"))...)
+	return entry, reason
+}
+
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
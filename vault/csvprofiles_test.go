@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The files in testdata use the headers each application writes.
+func TestImportCSVProfiles(t *testing.T) {
+	tests := []struct {
+		file     string
+		format   ImportFormat
+		services []string
+		rejected []RejectedRecord
+		check    func(t *testing.T, entries []Entry)
+	}{
+		{
+			file:     "chrome.csv",
+			format:   ChromeCSV,
+			services: []string{"GitHub", "example.org"},
+			rejected: []RejectedRecord{{Record: "No password", Reason: "row has no password"}},
+			check: func(t *testing.T, entries []Entry) {
+				if e := entries[1]; e.Username != "bob" || e.Notes != "multi\nline" || e.URLs[0] != "https://www.example.org/signin" {
+					t.Errorf("entry = %+v", e)
+				}
+			},
+		},
+		{
+			file:     "firefox.csv",
+			format:   FirefoxCSV,
+			services: []string{"mozilla.org"},
+			rejected: []RejectedRecord{{Record: "accounts.example.com", Reason: "row has no password"}},
+			check: func(t *testing.T, entries []Entry) {
+				if e := entries[0]; e.Password != "moz-pass" || !e.CreatedAt.Equal(time.UnixMilli(1700000000000)) {
+					t.Errorf("entry = %+v", e)
+				}
+			},
+		},
+		{
+			file:     "lastpass.csv",
+			format:   LastPassCSV,
+			services: []string{"Mail", "Door"},
+			rejected: []RejectedRecord{{Record: "Visa", Reason: "structured secure notes are not supported"}},
+			check: func(t *testing.T, entries []Entry) {
+				mail := entries[0]
+				if mail.Folder != "Personal/Email" || mail.TOTP != "JBSWY3DPEHPK3PXP" || mail.Notes != "main inbox" ||
+					!reflect.DeepEqual(mail.Tags, []string{"favorite"}) {
+					t.Errorf("mail = %+v", mail)
+				}
+				if door := entries[1]; door.URLs != nil || door.Notes != "Door code 4321" {
+					t.Errorf("door = %+v", door)
+				}
+			},
+		},
+		{
+			file:     "apple.csv",
+			format:   AppleCSV,
+			services: []string{"appleid.apple.com (alice@icloud.com)", "shop.example.com"},
+			check: func(t *testing.T, entries []Entry) {
+				if e := entries[0]; e.TOTP == "" || e.Username != "alice@icloud.com" {
+					t.Errorf("entry = %+v", e)
+				}
+			},
+		},
+		{
+			file:     "1password.csv",
+			format:   OnePasswordCSV,
+			services: []string{"Dropbox"},
+			rejected: []RejectedRecord{{Record: "Old account", Reason: "item is archived"}},
+			check: func(t *testing.T, entries []Entry) {
+				if e := entries[0]; !reflect.DeepEqual(e.Tags, []string{"favorite", "work", "cloud"}) || e.Notes != "shared folder" {
+					t.Errorf("entry = %+v", e)
+				}
+			},
+		},
+		{
+			file:     "bitwarden.csv",
+			format:   BitwardenCSV,
+			services: []string{"GitLab", "Wifi"},
+			rejected: []RejectedRecord{{Record: "Visa", Reason: "card items are not supported"}},
+			check: func(t *testing.T, entries []Entry) {
+				gitlab := entries[0]
+				if gitlab.Folder != "Work" || !reflect.DeepEqual(gitlab.URLs, []string{"https://gitlab.com", "https://gitlab.example.com"}) ||
+					!reflect.DeepEqual(gitlab.Fields, []CustomField{{Name: "Recovery", Value: "1234"}}) {
+					t.Errorf("gitlab = %+v", gitlab)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join("testdata", tt.file)
+			format, err := DetectImportFormat(path)
+			if err != nil || format != tt.format {
+				t.Fatalf("format = %q, %v, want %q", format, err, tt.format)
+			}
+
+			v := newTestVault(t)
+			result, err := v.Import(path, ImportOptions{Format: format})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Rejected, tt.rejected) {
+				t.Errorf("rejected = %+v, want %+v", result.Rejected, tt.rejected)
+			}
+			var services []string
+			for _, e := range v.Entries {
+				services = append(services, e.Service)
+			}
+			if !reflect.DeepEqual(services, tt.services) {
+				t.Fatalf("services = %q, want %q", services, tt.services)
+			}
+			tt.check(t, v.Entries)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package vault
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DefaultEnvKeyTemplate names dotenv variables after the entry's service and
+// username, e.g. GITHUB_ALICE.
+const DefaultEnvKeyTemplate = "{service}_{username}"
+
+var (
+	envInvalidChars = regexp.MustCompile(`[^A-Z0-9]+`)
+	envPlainValue   = regexp.MustCompile(`^[A-Za-z0-9_./:@+-]*$`)
+)
+
+// envKey renders template for an entry. {service}, {username} and {folder}
+// are replaced, and the result is upper-cased with every run of other
+// characters turned into a single underscore.
+func envKey(template string, e Entry) string {
+	if template == "" {
+		template = DefaultEnvKeyTemplate
+	}
+	key := strings.NewReplacer(
+		"{service}", e.Service,
+		"{username}", e.Username,
+		"{folder}", e.Folder,
+	).Replace(template)
+
+	key = strings.Trim(envInvalidChars.ReplaceAllString(strings.ToUpper(key), "_"), "_")
+	if key == "" {
+		key = "ENTRY"
+	}
+	if key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
+}
+
+func envValue(value string) string {
+	if envPlainValue.MatchString(value) {
+		return value
+	}
+	return `"` + strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"$", `\$`,
+		"`", "\\`",
+		"\n", `\n`,
+		"\r", `\r`,
+	).Replace(value) + `"`
+}
+
+// exportDotenv writes one KEY=password line per entry. Keys that collide get
+// a numeric suffix.
+func exportDotenv(w io.Writer, entries []Entry, template string) error {
+	used := make(map[string]bool)
+	for _, e := range entries {
+		name := envKey(template, e)
+		key := name
+		for i := 2; used[key]; i++ {
+			key = fmt.Sprintf("%s_%d", name, i)
+		}
+		used[key] = true
+
+		if _, err := fmt.Fprintf(w, "%s=%s\n", key, envValue(e.Password)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
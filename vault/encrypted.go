@@ -0,0 +1,59 @@
+package vault
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// Encrypted exports are age files protected by a passphrase, so they can
+// also be opened with the age command line tool.
+const ageHeader = "age-encryption.org/v1"
+
+func isEncryptedExport(prefix []byte) bool {
+	return bytes.HasPrefix(prefix, []byte(ageHeader)) || bytes.HasPrefix(prefix, []byte(armor.Header))
+}
+
+func encryptExport(w io.Writer, passphrase string) (io.WriteCloser, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return age.Encrypt(w, recipient)
+}
+
+// decryptExport reads the whole encrypted export into memory, since the
+// importers need to seek to detect the format inside it.
+func decryptExport(r io.Reader, passphrase string) (*bytes.Reader, error) {
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(r)
+	var src io.Reader = buffered
+	if start, _ := buffered.Peek(len(armor.Header)); string(start) == armor.Header {
+		src = armor.NewReader(buffered)
+	}
+
+	decrypted, err := age.Decrypt(src, identity)
+	if err != nil {
+		var wrong *age.NoIdentityMatchError
+		if errors.As(err, &wrong) || strings.Contains(err.Error(), "incorrect passphrase") {
+			return nil, fmt.Errorf("incorrect passphrase")
+		}
+		return nil, fmt.Errorf("cannot decrypt export: %v", err)
+	}
+
+	data, err := io.ReadAll(decrypted)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
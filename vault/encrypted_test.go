@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// cheapExport encrypts data like encryptExport with a low scrypt work
+// factor, so the test does not spend a second on each file.
+func cheapExport(t *testing.T, data, passphrase string, armored bool) []byte {
+	t.Helper()
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient.SetWorkFactor(10)
+
+	var buf bytes.Buffer
+	var out io.WriteCloser = nopCloser{&buf}
+	if armored {
+		out = armor.NewWriter(&buf)
+	}
+	w, err := age.Encrypt(out, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(data))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestEncryptedExportRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Service: "GitHub", Username: "alice", Password: "gh-pass", Notes: "work account", Folder: "Work"},
+		{Service: "Bank", Username: "bob", Password: "bank-pass"},
+	}
+	v := newTestVault(t, entries...)
+	path := filepath.Join(t.TempDir(), "backup.age")
+
+	if err := v.Export(path, ExportOptions{Format: JSONFormat, IncludePassword: true, IncludeNotes: true}); !errors.Is(err, ErrPlaintextExport) {
+		t.Errorf("unencrypted export err = %v, want ErrPlaintextExport", err)
+	}
+	if err := v.Export(path, ExportOptions{Format: JSONFormat, Encrypt: true}); err == nil {
+		t.Error("encrypted export without a passphrase")
+	}
+
+	options := ExportOptions{
+		Format:     JSONFormat,
+		Fields:     []string{"service", "username", "password", "notes", "folder"},
+		Encrypt:    true,
+		Passphrase: "export pass",
+	}
+	if err := v.Export(path, options); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), ageHeader) || strings.Contains(string(data), "gh-pass") {
+		t.Fatalf("export is not an age file: %.40q", data)
+	}
+
+	restored := newTestVault(t)
+	if _, err := restored.Import(path, ImportOptions{Format: AutoDetect, Passphrase: "export pass"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.Entries) != 2 {
+		t.Fatalf("got %d entries", len(restored.Entries))
+	}
+	for i, want := range entries {
+		got := restored.Entries[i]
+		if got.Service != want.Service || got.Username != want.Username || got.Password != want.Password ||
+			got.Notes != want.Notes || got.Folder != want.Folder {
+			t.Errorf("entry %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestDecryptExport(t *testing.T) {
+	const data = `[{"service": "GitHub", "password": "gh-pass"}]`
+	for _, armored := range []bool{false, true} {
+		file := cheapExport(t, data, "right", armored)
+		if !isEncryptedExport(file) {
+			t.Errorf("armored %v: not recognised as an encrypted export", armored)
+		}
+
+		decrypted, err := decryptExport(bytes.NewReader(file), "right")
+		if err != nil {
+			t.Fatalf("armored %v: %v", armored, err)
+		}
+		plain, _ := io.ReadAll(decrypted)
+		if string(plain) != data {
+			t.Errorf("armored %v: decrypted %q", armored, plain)
+		}
+
+		if _, err := decryptExport(bytes.NewReader(file), "wrong"); err == nil || err.Error() != "incorrect passphrase" {
+			t.Errorf("armored %v: wrong passphrase err = %v", armored, err)
+		}
+	}
+
+	if isEncryptedExport([]byte(`{"service": "x"}`)) {
+		t.Error("JSON recognised as an encrypted export")
+	}
+	if _, err := decryptExport(strings.NewReader("age-encryption.org/v1\n-> broken"), "right"); err == nil {
+		t.Error("decrypted a damaged file")
+	}
+}
+
+func TestImportEncryptedDetectsInnerFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.csv.age")
+	file := cheapExport(t, "service,username,password\nGitHub,alice,gh-pass\n", "right", true)
+	if err := os.WriteFile(path, file, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := newTestVault(t)
+	if _, err := v.Import(path, ImportOptions{Format: AutoDetect, Passphrase: "wrong"}); err == nil {
+		t.Error("imported with the wrong passphrase")
+	}
+	result, err := v.Import(path, ImportOptions{Format: AutoDetect, Passphrase: "right"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"GitHub (alice)"}) || v.Entries[0].Password != "gh-pass" {
+		t.Errorf("result = %+v, entries = %+v", result, v.Entries)
+	}
+}
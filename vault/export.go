@@ -1,9 +1,12 @@
 package vault
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -15,127 +18,322 @@ const (
 	JSONFormat ExportFormat = "json"
 	CSVFormat  ExportFormat = "csv"
 	TextFormat ExportFormat = "txt"
+	KDBXFormat ExportFormat = "kdbx"
+	PassFormat ExportFormat = "pass"
+	YAMLFormat ExportFormat = "yaml"
+	// NDJSONFormat writes one JSON object per line.
+	NDJSONFormat ExportFormat = "ndjson"
+	// DotenvFormat writes each password as a KEY=value line.
+	DotenvFormat ExportFormat = "env"
 )
 
+// ExportFields lists the fields that can be selected for JSON, CSV and text
+// exports, in their default order.
+var ExportFields = []string{"service", "username", "password", "notes", "folder", "tags", "urls", "totp", "created_at", "expires_at"}
+
 type ExportOptions struct {
 	Format          ExportFormat
 	IncludePassword bool
 	IncludeNotes    bool
 	IncludeTime     bool
+	// Fields selects and orders the exported fields. When empty the
+	// Include options decide.
+	Fields []string
+	Filter EntryFilter
+	// EnvKeyTemplate names the variables of a dotenv export. It defaults
+	// to DefaultEnvKeyTemplate.
+	EnvKeyTemplate string
+	Passphrase     string
+	// Encrypt wraps the export in an age file protected by Passphrase.
+	Encrypt bool
+	// Plaintext must be set to write an unencrypted export.
+	Plaintext bool
+	// GPGRecipients and GPGCommand are used for pass exports, which are
+	// encrypted per entry with gpg.
+	GPGRecipients []string
+	GPGCommand    string
 }
 
+var ErrPlaintextExport = errors.New("refusing to write an unencrypted export without explicit confirmation")
+
 func (v *Vault) Export(filePath string, options ExportOptions) error {
-	file, err := os.Create(filePath)
+	if options.Format == PassFormat {
+		return v.exportPassStore(filePath, options)
+	}
+	if err := options.check(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	switch options.Format {
-	case JSONFormat:
-		return v.exportJSON(file, options)
-	case CSVFormat:
-		return v.exportCSV(file, options)
-	case TextFormat:
-		return v.exportText(file, options)
-	default:
-		return fmt.Errorf("unsupported export format: %s", options.Format)
+	// OpenFile keeps the mode of an existing file.
+	if err := file.Chmod(0600); err != nil {
+		return err
 	}
+
+	return v.ExportTo(file, options)
 }
 
-func (v *Vault) exportJSON(file *os.File, options ExportOptions) error {
-	type exportEntry struct {
-		Service   string     `json:"service"`
-		Username  string     `json:"username"`
-		Password  string     `json:"password,omitempty"`
-		Notes     string     `json:"notes,omitempty"`
-		CreatedAt *time.Time `json:"created_at,omitempty"`
+// ExportTo writes the entries matching options.Filter to w one at a time,
+// so large vaults can be piped straight into other tools.
+func (v *Vault) ExportTo(w io.Writer, options ExportOptions) error {
+	if options.Format == PassFormat {
+		return fmt.Errorf("pass exports are written to a directory")
+	}
+	if err := options.check(); err != nil {
+		return err
 	}
 
-	entries := make([]exportEntry, 0, len(v.Entries))
-	for _, e := range v.Entries {
-		entry := exportEntry{
-			Service:  e.Service,
-			Username: e.Username,
-		}
+	entries := v.SelectEntries(options.Filter)
+	if !options.encrypted() {
+		return writeExport(w, entries, options)
+	}
 
-		if options.IncludePassword {
-			entry.Password = e.Password
+	encrypted, err := encryptExport(w, options.Passphrase)
+	if err != nil {
+		return err
+	}
+	if err := writeExport(encrypted, entries, options); err != nil {
+		return err
+	}
+	return encrypted.Close()
+}
+
+// KDBX files are encrypted by the format itself.
+func (o ExportOptions) encrypted() bool {
+	return o.Encrypt && o.Format != KDBXFormat
+}
+
+func (o ExportOptions) check() error {
+	if !o.encrypted() && !o.Plaintext && o.Format != KDBXFormat {
+		return ErrPlaintextExport
+	}
+	if o.encrypted() && o.Passphrase == "" {
+		return fmt.Errorf("a passphrase is required for an encrypted export")
+	}
+	_, err := o.fields()
+	return err
+}
+
+func (o ExportOptions) fields() ([]string, error) {
+	if len(o.Fields) == 0 {
+		fields := []string{"service", "username"}
+		if o.IncludePassword {
+			fields = append(fields, "password")
 		}
-		if options.IncludeNotes {
-			entry.Notes = e.Notes
+		if o.IncludeNotes {
+			fields = append(fields, "notes")
 		}
-		if options.IncludeTime {
-			entry.CreatedAt = &e.CreatedAt
+		if o.IncludeTime {
+			fields = append(fields, "created_at")
 		}
+		return fields, nil
+	}
 
-		entries = append(entries, entry)
+	fields := make([]string, 0, len(o.Fields))
+	for _, f := range o.Fields {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !isExportField(f) {
+			return nil, fmt.Errorf("unknown export field %q, expected one of %s", f, strings.Join(ExportFields, ", "))
+		}
+		fields = append(fields, f)
 	}
+	return fields, nil
+}
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(entries)
+func isExportField(field string) bool {
+	for _, f := range ExportFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
 }
 
-func (v *Vault) exportCSV(file *os.File, options ExportOptions) error {
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+func writeExport(w io.Writer, entries []Entry, options ExportOptions) error {
+	fields, err := options.fields()
+	if err != nil {
+		return err
+	}
 
-	header := []string{"Service", "Username"}
-	if options.IncludePassword {
-		header = append(header, "Password")
+	switch options.Format {
+	case JSONFormat:
+		return exportJSON(w, entries, fields)
+	case NDJSONFormat:
+		return exportNDJSON(w, entries, fields)
+	case YAMLFormat:
+		return exportYAML(w, entries, fields)
+	case DotenvFormat:
+		return exportDotenv(w, entries, options.EnvKeyTemplate)
+	case CSVFormat:
+		return exportCSV(w, entries, fields)
+	case TextFormat:
+		return exportText(w, entries, fields)
+	case KDBXFormat:
+		return exportKeePass(w, entries, options)
+	default:
+		return fmt.Errorf("unsupported export format: %s", options.Format)
 	}
-	if options.IncludeNotes {
-		header = append(header, "Notes")
+}
+
+func exportValue(e Entry, field string) interface{} {
+	switch field {
+	case "service":
+		return e.Service
+	case "username":
+		return e.Username
+	case "password":
+		return e.Password
+	case "notes":
+		return e.Notes
+	case "folder":
+		return e.Folder
+	case "tags":
+		return append([]string{}, e.Tags...)
+	case "urls":
+		return append([]string{}, e.URLs...)
+	case "totp":
+		return e.TOTP
+	case "created_at":
+		return formatExportTime(e.CreatedAt)
+	case "expires_at":
+		return formatExportTime(e.ExpiresAt)
 	}
-	if options.IncludeTime {
-		header = append(header, "Created At")
+	return ""
+}
+
+func exportString(e Entry, field string) string {
+	switch value := exportValue(e, field).(type) {
+	case []string:
+		return strings.Join(value, ", ")
+	case string:
+		return value
 	}
+	return ""
+}
 
-	if err := writer.Write(header); err != nil {
-		return err
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
+	return t.Format(time.RFC3339)
+}
 
-	for _, e := range v.Entries {
-		record := []string{e.Service, e.Username}
-		if options.IncludePassword {
-			record = append(record, e.Password)
+// exportObject encodes one entry as a JSON object with its keys in field
+// order, which encoding/json cannot do for maps.
+func exportObject(e Entry, fields []string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
 		}
-		if options.IncludeNotes {
-			record = append(record, e.Notes)
+		value, err := json.Marshal(exportValue(e, field))
+		if err != nil {
+			return nil, err
 		}
-		if options.IncludeTime {
-			record = append(record, e.CreatedAt.Format(time.RFC3339))
+		fmt.Fprintf(&buf, "%q:", field)
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func exportJSON(w io.Writer, entries []Entry, fields []string) error {
+	if len(entries) == 0 {
+		_, err := io.WriteString(w, "[]\n")
+		return err
+	}
+
+	if _, err := io.WriteString(w, "[\n  "); err != nil {
+		return err
+	}
+	for i, e := range entries {
+		object, err := exportObject(e, fields)
+		if err != nil {
+			return err
 		}
 
-		if err := writer.Write(record); err != nil {
+		var indented bytes.Buffer
+		if i > 0 {
+			indented.WriteString(",\n  ")
+		}
+		if err := json.Indent(&indented, object, "  ", "  "); err != nil {
+			return err
+		}
+		if _, err := w.Write(indented.Bytes()); err != nil {
 			return err
 		}
 	}
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
 
+func exportNDJSON(w io.Writer, entries []Entry, fields []string) error {
+	for _, e := range entries {
+		object, err := exportObject(e, fields)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(object, '\n')); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (v *Vault) exportText(file *os.File, options ExportOptions) error {
-	var sb strings.Builder
+func exportCSV(w io.Writer, entries []Entry, fields []string) error {
+	writer := csv.NewWriter(w)
 
-	for _, e := range v.Entries {
-		sb.WriteString(fmt.Sprintf("Service: %s\n", e.Service))
-		sb.WriteString(fmt.Sprintf("Username: %s\n", e.Username))
+	if err := writer.Write(fields); err != nil {
+		return err
+	}
 
-		if options.IncludePassword {
-			sb.WriteString(fmt.Sprintf("Password: %s\n", e.Password))
-		}
-		if options.IncludeNotes && e.Notes != "" {
-			sb.WriteString(fmt.Sprintf("Notes: %s\n", e.Notes))
+	record := make([]string, len(fields))
+	for _, e := range entries {
+		for i, field := range fields {
+			record[i] = exportString(e, field)
 		}
-		if options.IncludeTime {
-			sb.WriteString(fmt.Sprintf("Created: %s\n", e.CreatedAt.Format(time.RFC3339)))
+		if err := writer.Write(record); err != nil {
+			return err
 		}
-
-		sb.WriteString("\n")
 	}
 
-	_, err := file.WriteString(sb.String())
-	return err
+	writer.Flush()
+	return writer.Error()
+}
+
+var exportLabels = map[string]string{
+	"service":    "Service",
+	"username":   "Username",
+	"password":   "Password",
+	"notes":      "Notes",
+	"folder":     "Folder",
+	"tags":       "Tags",
+	"urls":       "URLs",
+	"totp":       "TOTP",
+	"created_at": "Created",
+	"expires_at": "Expires",
+}
+
+func exportText(w io.Writer, entries []Entry, fields []string) error {
+	for _, e := range entries {
+		for _, field := range fields {
+			value := exportString(e, field)
+			if value == "" && field != "service" && field != "username" {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s: %s\n", exportLabels[field], value); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,123 @@
+package vault
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// exportEntries covers values that need quoting or escaping in YAML and
+// dotenv files.
+var exportEntries = []Entry{
+	{
+		Service: "GitHub", Username: "alice", Password: "plain-Pass_1.2@x/y:z+",
+		Folder: "Work/Dev", Tags: []string{"dev", "work"}, URLs: []string{"https://github.com"},
+		CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+	},
+	{
+		Service: "Bank: main", Username: "bob", Password: `p@ss "word" $HOME \ end`,
+		Notes: "line one\nline two # not a comment", TOTP: "JBSWY3DPEHPK3PXP",
+		CreatedAt: time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC),
+	},
+	{Service: "yes", Username: "null", Password: "0123", Notes: " leading space", Tags: []string{"-", "*"}},
+	{Service: "Ünïcode café", Password: "пароль 🔑", Folder: "Home"},
+	{Service: "GitHub", Username: "alice", Password: "back`tick'quote\r\n"},
+	{Service: "2fa backup", Password: ""},
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs:\n--- got\n%s\n--- want\n%s", name, got, want)
+	}
+}
+
+func TestExportYAMLGolden(t *testing.T) {
+	options := ExportOptions{Format: YAMLFormat, Fields: ExportFields, Plaintext: true}
+	var buf bytes.Buffer
+	if err := writeExport(&buf, exportEntries, options); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "export.yaml", buf.Bytes())
+
+	// The YAML reads back with every value unchanged.
+	entries, err := parseYAML(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(exportEntries) {
+		t.Fatalf("read back %d entries", len(entries))
+	}
+	for i, want := range exportEntries {
+		got := entries[i]
+		if got.Service != want.Service || got.Username != want.Username || got.Password != want.Password ||
+			got.Notes != want.Notes || got.Folder != want.Folder || got.TOTP != want.TOTP ||
+			strings.Join(got.Tags, ",") != strings.Join(want.Tags, ",") || strings.Join(got.URLs, ",") != strings.Join(want.URLs, ",") {
+			t.Errorf("entry %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	buf.Reset()
+	if err := writeExport(&buf, nil, options); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("empty export = %q", buf.String())
+	}
+}
+
+func TestExportDotenvGolden(t *testing.T) {
+	tests := []struct {
+		golden   string
+		template string
+	}{
+		{"export.env", ""},
+		{"export-folder.env", "{folder}_{service}"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		options := ExportOptions{Format: DotenvFormat, EnvKeyTemplate: tt.template, Plaintext: true}
+		if err := writeExport(&buf, exportEntries, options); err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, tt.golden, buf.Bytes())
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	entry := Entry{Service: "my-app.io", Username: "Alice Smith", Folder: "Work/Prod"}
+	tests := []struct {
+		template string
+		entry    Entry
+		want     string
+	}{
+		{"", entry, "MY_APP_IO_ALICE_SMITH"},
+		{"{folder}_{service}", entry, "WORK_PROD_MY_APP_IO"},
+		{"DB_{username}_PASSWORD", entry, "DB_ALICE_SMITH_PASSWORD"},
+		{"", Entry{Service: "2fa"}, "_2FA"},
+		{"", Entry{Service: "--"}, "ENTRY"},
+		{"{folder}", Entry{Service: "x"}, "ENTRY"},
+		{"", Entry{Service: "Café"}, "CAF"},
+	}
+	for _, tt := range tests {
+		if got := envKey(tt.template, tt.entry); got != tt.want {
+			t.Errorf("envKey(%q, %q) = %q, want %q", tt.template, tt.entry.Service, got, tt.want)
+		}
+	}
+}
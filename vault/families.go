@@ -0,0 +1,169 @@
+package vault
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// PasswordFamily groups different passwords that are variations of each
+// other, such as "Summer2023!" and "Summer2024!". Passwords are masked.
+type PasswordFamily struct {
+	// BaseWord is the masked word the passwords share, if any.
+	BaseWord string         `json:"base_word,omitempty"`
+	Members  []FamilyMember `json:"members"`
+}
+
+type FamilyMember struct {
+	Password string   `json:"password"`
+	Services []string `json:"services"`
+}
+
+// minBaseWordLength keeps short fragments like "abc" from joining unrelated
+// passwords.
+const minBaseWordLength = 4
+
+var baseWordSubstitutions = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "@", "a", "$", "s", "5", "s", "7", "t",
+)
+
+// baseWord returns the longest run of letters in password after undoing
+// common substitutions, lower-cased.
+func baseWord(password string) string {
+	normalized := baseWordSubstitutions.Replace(strings.ToLower(password))
+
+	best := ""
+	for _, word := range strings.FieldsFunc(normalized, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if len([]rune(word)) > len([]rune(best)) {
+			best = word
+		}
+	}
+	if len([]rune(best)) < minBaseWordLength {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// similarPasswords reports whether two passwords are a small edit apart:
+// at most a quarter of the shorter one, and at least one character.
+func similarPasswords(a, b []rune) bool {
+	limit := max(1, min(len(a), len(b))/4)
+	if len(a)-len(b) > limit || len(b)-len(a) > limit {
+		return false
+	}
+	return levenshtein(a, b) <= limit
+}
+
+// groupSimilarPasswords returns the groups of two or more distinct passwords
+// that share a base word or are a small edit apart, directly or through
+// other members.
+func groupSimilarPasswords(passwords map[string][]string) [][]string {
+	distinct := make([]string, 0, len(passwords))
+	for p := range passwords {
+		if p != "" {
+			distinct = append(distinct, p)
+		}
+	}
+	sort.Strings(distinct)
+
+	parent := make([]int, len(distinct))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		parent[find(a)] = find(b)
+	}
+
+	runes := make([][]rune, len(distinct))
+	byBase := make(map[string]int)
+	for i, p := range distinct {
+		runes[i] = []rune(p)
+		base := baseWord(p)
+		if base == "" {
+			continue
+		}
+		if j, ok := byBase[base]; ok {
+			union(i, j)
+		} else {
+			byBase[base] = i
+		}
+	}
+	for i := range distinct {
+		for j := i + 1; j < len(distinct); j++ {
+			if find(i) != find(j) && similarPasswords(runes[i], runes[j]) {
+				union(i, j)
+			}
+		}
+	}
+
+	byRoot := make(map[int][]string)
+	var roots []int
+	for i, p := range distinct {
+		root := find(i)
+		if _, ok := byRoot[root]; !ok {
+			roots = append(roots, root)
+		}
+		byRoot[root] = append(byRoot[root], p)
+	}
+
+	var groups [][]string
+	for _, root := range roots {
+		if len(byRoot[root]) >= 2 {
+			groups = append(groups, byRoot[root])
+		}
+	}
+	return groups
+}
+
+func findPasswordFamilies(passwords map[string][]string) []PasswordFamily {
+	var families []PasswordFamily
+	for _, group := range groupSimilarPasswords(passwords) {
+		base := baseWord(group[0])
+		family := PasswordFamily{BaseWord: maskPassword(base)}
+		for _, p := range group {
+			if b := baseWord(p); b != base || b == "" {
+				family.BaseWord = ""
+			}
+			family.Members = append(family.Members, FamilyMember{
+				Password: maskPassword(p),
+				Services: passwords[p],
+			})
+		}
+		families = append(families, family)
+	}
+
+	sort.Slice(families, func(i, j int) bool {
+		if len(families[i].Members) != len(families[j].Members) {
+			return len(families[i].Members) > len(families[j].Members)
+		}
+		return families[i].Members[0].Services[0] < families[j].Members[0].Services[0]
+	})
+	return families
+}
@@ -0,0 +1,100 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBaseWord(t *testing.T) {
+	tests := []struct{ password, want string }{
+		{"Summer2023!", "summer"},
+		{"5umm3r!!", "summer"},
+		{"P@$$w0rd", "password"},
+		{"Qz8!wq", ""},
+		{"kx7#Lp2q", ""},
+		{"blue-Sky-forever", "forever"},
+		{"Ünïcode9", "ünïcode"},
+	}
+	for _, tt := range tests {
+		if got := baseWord(tt.password); got != tt.want {
+			t.Errorf("baseWord(%q) = %q, want %q", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarPasswords(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"kx7#Lp2q", "kx7#Lp2r", true},
+		{"kx7#Lp2q", "kx7#Lp2", true},
+		{"kx7#Lp2q", "kx7#Lp", false},
+		{"abc", "abd", true},
+		{"abc", "xyz", false},
+		{"пароль1", "пароль2", true},
+	}
+	for _, tt := range tests {
+		if got := similarPasswords([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("similarPasswords(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFindPasswordFamilies(t *testing.T) {
+	passwords := map[string][]string{
+		"Summer2023!":   {"Mail"},
+		"Summer2024!":   {"Bank", "Shop"},
+		"5umm3r!!":      {"Forum"},
+		"kx7#Lp2q":      {"Router"},
+		"kx7#Lp2r":      {"NAS"},
+		"correct-horse": {"Wiki"},
+		"":              {"Empty"},
+	}
+
+	want := []PasswordFamily{
+		{
+			BaseWord: "su**er",
+			Members: []FamilyMember{
+				{Password: "5u****!!", Services: []string{"Forum"}},
+				{Password: "Su*******3!", Services: []string{"Mail"}},
+				{Password: "Su*******4!", Services: []string{"Bank", "Shop"}},
+			},
+		},
+		{
+			Members: []FamilyMember{
+				{Password: "kx****2q", Services: []string{"Router"}},
+				{Password: "kx****2r", Services: []string{"NAS"}},
+			},
+		},
+	}
+	if got := findPasswordFamilies(passwords); !reflect.DeepEqual(got, want) {
+		t.Errorf("families = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestGroupSimilarPasswordsChains(t *testing.T) {
+	// a and c are too far apart, but both are close to b.
+	passwords := map[string][]string{"Qz8!wq": {"A"}, "Qz8!wr": {"B"}, "Qz8!xr": {"C"}}
+	groups := groupSimilarPasswords(passwords)
+	if len(groups) != 1 || len(groups[0]) != 3 {
+		t.Errorf("groups = %q", groups)
+	}
+}
+
+func TestMaskPassword(t *testing.T) {
+	tests := []struct{ password, want string }{
+		{"", "****"},
+		{"abcd", "****"},
+		{"abcde", "ab*de"},
+		{"пароль🔑", "па***ь🔑"},
+		{"日本語です", "日本*です"},
+	}
+	for _, tt := range tests {
+		got := maskPassword(tt.password)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("maskPassword(%q) = %q, want %q", tt.password, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package vault
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scores for how well a search term matches a field. Higher is better and
+// zero is no match.
+const (
+	exactScore      = 100
+	prefixScore     = 90
+	wordPrefixScore = 80
+	substringScore  = 70
+	// Subsequence matches score between these two, depending on how
+	// closely the letters sit together.
+	minSubsequenceScore = 30
+	maxSubsequenceScore = 60
+	typoScore           = 20
+)
+
+// fuzzyScore rates how well term matches text. Both are lower-case. Without
+// fuzzy only substrings match; with it, so do abbreviations like "gh" for
+// "github" and small typos like "gihtub".
+func fuzzyScore(term, text string, fuzzy bool) int {
+	switch {
+	case term == "":
+		return 0
+	case text == term:
+		return exactScore
+	case strings.HasPrefix(text, term):
+		return prefixScore
+	case hasWordPrefix(text, term):
+		return wordPrefixScore
+	case strings.Contains(text, term):
+		return substringScore
+	case !fuzzy:
+		return 0
+	}
+
+	if score := subsequenceScore([]rune(term), []rune(text)); score > 0 {
+		return score
+	}
+	if distance := typoDistance(term, text); distance > 0 {
+		return typoScore / distance
+	}
+	return 0
+}
+
+func isWordStart(text []rune, i int) bool {
+	return i == 0 || !isWordRune(text[i-1])
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func hasWordPrefix(text, term string) bool {
+	runes := []rune(text)
+	for i := range runes {
+		if isWordStart(runes, i) && strings.HasPrefix(string(runes[i:]), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// subsequenceScore finds term's letters in order in text, starting at the
+// beginning of a word, and rates the tightest such match.
+func subsequenceScore(term, text []rune) int {
+	if len(term) < 2 {
+		return 0
+	}
+
+	best := 0
+	for start := range text {
+		if text[start] != term[0] || !isWordStart(text, start) {
+			continue
+		}
+		j, end := 1, start
+		for i := start + 1; i < len(text) && j < len(term); i++ {
+			if text[i] == term[j] {
+				j++
+				end = i
+			}
+		}
+		if j < len(term) {
+			break
+		}
+		span := end - start + 1
+		score := minSubsequenceScore + (maxSubsequenceScore-minSubsequenceScore)*len(term)/span
+		best = max(best, score)
+	}
+	return best
+}
+
+// typoDistance returns the smallest edit distance between term and a word of
+// text, or the start of one, when it is small enough to be a typo: one edit
+// for terms of four letters or more, two from eight. Words must start with
+// the same letter as term. It returns 0 when there is no such word.
+func typoDistance(term, text string) int {
+	query := []rune(term)
+	allowed := 0
+	switch {
+	case len(query) >= 8:
+		allowed = 2
+	case len(query) >= 4:
+		allowed = 1
+	default:
+		return 0
+	}
+
+	best := 0
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) }) {
+		w := []rune(word)
+		if w[0] != query[0] {
+			continue
+		}
+		candidates := [][]rune{w}
+		if len(w) > len(query) {
+			candidates = append(candidates, w[:len(query)])
+		}
+		for _, c := range candidates {
+			if d := editDistance(query, c); d <= allowed && (best == 0 || d < best) {
+				best = d
+			}
+		}
+	}
+	return best
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent letters that turn a into b.
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
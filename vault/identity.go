@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+func (v *Vault) Identity() (*age.X25519Identity, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.identity != "" {
+		return age.ParseX25519Identity(v.identity)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		return nil, err
+	}
+
+	v.identity = identity.String()
+	if err := v.Save(); err != nil {
+		v.identity = ""
+		return nil, err
+	}
+	return identity, nil
+}
+
+func (v *Vault) PublicKey() (string, error) {
+	identity, err := v.Identity()
+	if err != nil {
+		return "", err
+	}
+	return identity.Recipient().String(), nil
+}
+
+// ParseRecipients parses age public keys, skipping blank and repeated ones.
+func ParseRecipients(keys []string) ([]age.Recipient, error) {
+	recipients := make([]age.Recipient, 0, len(keys))
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		recipient, err := age.ParseX25519Recipient(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key %q: %v", key, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("at least one recipient is required")
+	}
+	return recipients, nil
+}
+
+func (v *Vault) ShareEntries(w io.Writer, recipientKeys []string, filter EntryFilter) (int, error) {
+	recipients, err := ParseRecipients(recipientKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	entries := v.SelectEntries(filter)
+	if len(entries) == 0 {
+		return 0, fmt.Errorf("no entries match the selection")
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	armored := armor.NewWriter(w)
+	encrypted, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := encrypted.Write(data); err != nil {
+		return 0, err
+	}
+	if err := encrypted.Close(); err != nil {
+		return 0, err
+	}
+	if err := armored.Close(); err != nil {
+		return 0, err
+	}
+
+	return len(entries), nil
+}
+
+func (v *Vault) ImportShared(r io.Reader, options ImportOptions) (ImportResult, error) {
+	var result ImportResult
+
+	identity, err := v.Identity()
+	if err != nil {
+		return result, err
+	}
+
+	buffered := bufio.NewReader(r)
+	var src io.Reader = buffered
+	if start, _ := buffered.Peek(len(armor.Header)); string(start) == armor.Header {
+		src = armor.NewReader(buffered)
+	}
+
+	decrypted, err := age.Decrypt(src, identity)
+	if err != nil {
+		return result, fmt.Errorf("cannot decrypt shared file: %v", err)
+	}
+
+	var entries []Entry
+	if err := json.NewDecoder(decrypted).Decode(&entries); err != nil {
+		return result, err
+	}
+
+	err = v.processImportedEntries(entries, options, &result)
+	return result, err
+}
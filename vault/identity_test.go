@@ -0,0 +1,74 @@
+package vault
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestParseRecipients(t *testing.T) {
+	first, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := first.Recipient().String(), second.Recipient().String()
+
+	recipients, err := ParseRecipients(strings.Split(" "+a+" , ,"+a+","+b+",", ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recipients) != 2 {
+		t.Errorf("got %d recipients, want 2", len(recipients))
+	}
+
+	if _, err := ParseRecipients([]string{" ", ""}); err == nil {
+		t.Error("accepted a list without keys")
+	}
+	if _, err := ParseRecipients([]string{a, "age1notakey"}); err == nil || !strings.Contains(err.Error(), "age1notakey") {
+		t.Errorf("err = %v, want the invalid key named", err)
+	}
+}
+
+func TestShareEntriesWithRecipients(t *testing.T) {
+	sender := newTestVault(t,
+		Entry{Service: "GitHub", Username: "alice", Password: "gh-pass", Folder: "Work", TOTP: "JBSWY3DPEHPK3PXP"},
+		Entry{Service: "Bank", Username: "bob", Password: "bank-pass"},
+	)
+	receiver := newTestVault(t)
+	outsider := newTestVault(t)
+	key, err := receiver.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	count, err := sender.ShareEntries(&buf, []string{key}, EntryFilter{Folder: "Work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("shared %d entries, want 1", count)
+	}
+	shared := buf.Bytes()
+
+	if _, err := outsider.ImportShared(bytes.NewReader(shared), ImportOptions{}); err == nil {
+		t.Error("a vault that is not a recipient decrypted the file")
+	}
+
+	result, err := receiver.ImportShared(bytes.NewReader(shared), ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Added) != 1 || len(receiver.Entries) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	if got := receiver.Entries[0]; got.Service != "GitHub" || got.Password != "gh-pass" || got.TOTP != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("entry = %+v", got)
+	}
+}
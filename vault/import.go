@@ -1,102 +1,334 @@
 package vault
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"strings"
 	"time"
+
+	"pw/kdbx"
 )
 
 type ImportFormat string
 
 const (
-	AutoDetect ImportFormat = "auto"
-	JSONImport ImportFormat = "json"
-	CSVImport  ImportFormat = "csv"
+	AutoDetect      ImportFormat = "auto"
+	JSONImport      ImportFormat = "json"
+	CSVImport       ImportFormat = "csv"
+	BitwardenJSON   ImportFormat = "bitwarden-json"
+	BitwardenCSV    ImportFormat = "bitwarden-csv"
+	KeePassImport   ImportFormat = "kdbx"
+	ChromeCSV       ImportFormat = "chrome-csv"
+	FirefoxCSV      ImportFormat = "firefox-csv"
+	LastPassCSV     ImportFormat = "lastpass-csv"
+	AppleCSV        ImportFormat = "apple-csv"
+	OnePasswordCSV  ImportFormat = "1password-csv"
+	OnePasswordPUX  ImportFormat = "1pux"
+	EncryptedImport ImportFormat = "age"
+	PassImport      ImportFormat = "pass"
+	YAMLImport      ImportFormat = "yaml"
 )
 
+// HasSecureNotes reports whether the format can hold secure notes, which
+// have no username.
+func (f ImportFormat) HasSecureNotes() bool {
+	switch f {
+	case BitwardenJSON, BitwardenCSV, OnePasswordPUX:
+		return true
+	}
+	return false
+}
+
 type ImportOptions struct {
 	Format          ImportFormat
 	SkipDuplicates  bool
 	UpdateExisting  bool
 	RequiredFields  []string
 	DefaultPassword string
+	Passphrase      string
+	KeyFile         string
+	Mapping         ColumnMapping
+	DryRun          bool
+	GPGCommand      string
+}
+
+// ImportResult reports what an import changed, or would change for a dry run.
+type ImportResult struct {
+	DryRun     bool
+	Added      []string
+	Updated    []UpdatedRecord
+	Duplicates []string
+	Rejected   []RejectedRecord
+
+	// staged keeps a dry run's parsed entries for ApplyImport.
+	staged *stagedImport
+}
+
+type stagedImport struct {
+	entries  []Entry
+	options  ImportOptions
+	rejected []RejectedRecord
+}
+
+type UpdatedRecord struct {
+	Record  string
+	Changes []string
+}
+
+type RejectedRecord struct {
+	Record string
+	Reason string
+}
+
+func (r ImportResult) Imported() int {
+	return len(r.Added) + len(r.Updated)
 }
 
-func (v *Vault) Import(filePath string, options ImportOptions) error {
+func (r *ImportResult) reject(record, reason string) {
+	r.Rejected = append(r.Rejected, RejectedRecord{Record: record, Reason: reason})
+}
+
+// importSource is satisfied by both the opened file and a decrypted export
+// held in memory.
+type importSource interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+}
+
+func (v *Vault) Import(filePath string, options ImportOptions) (ImportResult, error) {
+	var result ImportResult
+
+	if options.Format == PassImport || (options.Format == AutoDetect && isPassStore(filePath)) {
+		entries, err := parsePassStore(filePath, options, &result)
+		if err != nil {
+			return result, err
+		}
+		err = v.processImportedEntries(entries, options, &result)
+		return result, err
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
-		return err
+		return result, err
 	}
 	defer file.Close()
 
+	var src importSource = file
 	format := options.Format
 	if format == AutoDetect {
-		format, err = detectFormat(file)
-		if err != nil {
-			return err
+		if format, err = detectSource(src); err != nil {
+			return result, err
 		}
-		_, err = file.Seek(0, 0)
-		if err != nil {
-			return err
+	}
+	if format == EncryptedImport {
+		if src, err = decryptExport(file, options.Passphrase); err != nil {
+			return result, err
 		}
+		if format, err = detectSource(src); err != nil {
+			return result, err
+		}
+	}
+
+	// A column mapping names the CSV columns explicitly, so it takes
+	// precedence over a profile recognised from the header.
+	if _, isProfile := csvProfileFor(format); isProfile && options.Mapping != nil {
+		format = CSVImport
 	}
 
+	var entries []Entry
 	switch format {
 	case JSONImport:
-		return v.importJSON(file, options)
+		entries, err = parseJSON(src)
+	case YAMLImport:
+		entries, err = parseYAML(src)
 	case CSVImport:
-		return v.importCSV(file, options)
+		entries, err = parseCSV(src, options)
+	case BitwardenJSON:
+		entries, err = parseBitwardenJSON(src, &result)
+	case KeePassImport:
+		entries, err = parseKeePass(src, options, &result)
+	case OnePasswordPUX:
+		entries, err = parseOnePUX(src, &result)
 	default:
-		return fmt.Errorf("unsupported import format: %s", format)
+		profile, ok := csvProfileFor(format)
+		if !ok {
+			return result, fmt.Errorf("unsupported import format: %s", format)
+		}
+		entries, err = parseProfileCSV(src, profile, &result)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	err = v.processImportedEntries(entries, options, &result)
+	return result, err
+}
+
+func DetectImportFormat(filePath string) (ImportFormat, error) {
+	if isPassStore(filePath) {
+		return PassImport, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	return detectFormat(file)
+}
+
+// detectSource detects the format and rewinds src for the parser.
+func detectSource(src importSource) (ImportFormat, error) {
+	format, err := detectFormat(src)
+	if err != nil {
+		return "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
 	}
+	return format, nil
 }
 
-func detectFormat(file *os.File) (ImportFormat, error) {
+func detectFormat(r io.Reader) (ImportFormat, error) {
 	buf := make([]byte, 1024)
-	n, err := file.Read(buf)
-	if err != nil && err != io.EOF {
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return "", err
 	}
 
+	if kdbx.IsKDBX(buf[:n]) {
+		return KeePassImport, nil
+	}
+	if isZip(buf[:n]) {
+		return OnePasswordPUX, nil
+	}
+	if isEncryptedExport(buf[:n]) {
+		return EncryptedImport, nil
+	}
+
 	content := string(buf[:n])
 	content = strings.TrimSpace(content)
 
+	if strings.HasPrefix(content, "{") && isBitwardenJSON(content) {
+		return BitwardenJSON, nil
+	}
+
 	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
 		return JSONImport, nil
 	}
 
+	if strings.HasPrefix(content, "---") || strings.HasPrefix(content, "- ") {
+		return YAMLImport, nil
+	}
+
 	if strings.Contains(content, ",") {
+		header, err := csv.NewReader(strings.NewReader(content)).Read()
+		if err == nil {
+			if profile, ok := detectCSVProfile(header); ok {
+				return profile.Format, nil
+			}
+		}
 		return CSVImport, nil
 	}
 
 	return "", fmt.Errorf("unable to detect file format")
 }
 
-func (v *Vault) importJSON(file *os.File, options ImportOptions) error {
-	var entries []Entry
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&entries); err != nil {
-		return err
+// importedEntry accepts both full entries and the records written by the
+// JSON, NDJSON and YAML exports, whose timestamps are strings.
+type importedEntry struct {
+	Entry   `yaml:",inline"`
+	Created string `json:"created_at" yaml:"created_at"`
+	Expires string `json:"expires_at" yaml:"expires_at"`
+}
+
+func importedEntries(records []importedEntry) ([]Entry, error) {
+	entries := make([]Entry, 0, len(records))
+	for _, r := range records {
+		entry := r.Entry
+		for _, t := range []struct {
+			value string
+			dst   *time.Time
+		}{{r.Created, &entry.CreatedAt}, {r.Expires, &entry.ExpiresAt}} {
+			if t.value == "" {
+				continue
+			}
+			parsed, err := time.Parse(time.RFC3339, t.value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid time %q", entryLabel(entry), t.value)
+			}
+			*t.dst = parsed
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseJSON reads an array of entries, or a stream of entry objects such as
+// an NDJSON export.
+func parseJSON(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
 	}
 
-	return v.processImportedEntries(entries, options)
+	var records []importedEntry
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, err
+		}
+		return importedEntries(records)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var record importedEntry
+		if err := decoder.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return importedEntries(records)
 }
 
-func (v *Vault) importCSV(file *os.File, options ImportOptions) error {
-	reader := csv.NewReader(file)
+func parseCSV(r io.Reader, options ImportOptions) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
 
 	header, err := reader.Read()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	indexMap := make(map[string]int)
-	for i, field := range header {
-		indexMap[strings.ToLower(strings.TrimSpace(field))] = i
+	var indexes map[string]int
+	if options.Mapping != nil {
+		if indexes, err = options.Mapping.indexes(header); err != nil {
+			return nil, err
+		}
+	} else {
+		indexes = defaultColumns(header)
+		_, hasService := indexes["service"]
+		_, hasUsername := indexes["username"]
+		_, hasPassword := indexes["password"]
+		if !hasService && !hasUsername && !hasPassword {
+			return nil, unmappedColumns(header, reader)
+		}
+	}
+
+	get := func(record []string, field string) (string, bool) {
+		if i, ok := indexes[field]; ok && i < len(record) {
+			return record[i], true
+		}
+		return "", false
 	}
 
 	var entries []Entry
@@ -106,76 +338,191 @@ func (v *Vault) importCSV(file *os.File, options ImportOptions) error {
 			break
 		}
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		entry := Entry{CreatedAt: time.Now()}
+		entry.Service, _ = get(record, "service")
+		entry.Username, _ = get(record, "username")
+		entry.Notes, _ = get(record, "notes")
+		entry.Folder, _ = get(record, "folder")
+		entry.TOTP, _ = get(record, "totp")
 
-		if i, ok := indexMap["service"]; ok && i < len(record) {
-			entry.Service = record[i]
-		}
-		if i, ok := indexMap["username"]; ok && i < len(record) {
-			entry.Username = record[i]
-		}
-		if i, ok := indexMap["password"]; ok && i < len(record) {
-			entry.Password = record[i]
+		if password, ok := get(record, "password"); ok {
+			entry.Password = password
 		} else {
 			entry.Password = options.DefaultPassword
 		}
-		if i, ok := indexMap["notes"]; ok && i < len(record) {
-			entry.Notes = record[i]
+		// The export joins URLs with commas. Semicolons are left alone, as
+		// URLs can contain them.
+		if urls, _ := get(record, "urls"); urls != "" {
+			for _, u := range strings.Split(urls, ",") {
+				if u = strings.TrimSpace(u); u != "" {
+					entry.URLs = append(entry.URLs, u)
+				}
+			}
+		}
+		if tags, _ := get(record, "tags"); tags != "" {
+			entry.Tags = splitTags(tags)
 		}
-		if i, ok := indexMap["created_at"]; ok && i < len(record) {
-			if t, err := time.Parse(time.RFC3339, record[i]); err == nil {
+		if createdAt, _ := get(record, "created_at"); createdAt != "" {
+			if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 				entry.CreatedAt = t
 			}
 		}
+		if expiresAt, _ := get(record, "expires_at"); expiresAt != "" {
+			if t, err := time.Parse(time.RFC3339, expiresAt); err == nil {
+				entry.ExpiresAt = t
+			}
+		}
 
 		entries = append(entries, entry)
 	}
 
-	return v.processImportedEntries(entries, options)
+	return entries, nil
 }
 
-func (v *Vault) processImportedEntries(entries []Entry, options ImportOptions) error {
+// processImportedEntries stages the import on a copy of the entries, so a
+// dry run reports exactly what a real import would do, including duplicates
+// within the imported records themselves.
+func (v *Vault) processImportedEntries(entries []Entry, options ImportOptions, result *ImportResult) error {
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+	result.DryRun = options.DryRun
+	if options.DryRun {
+		result.staged = &stagedImport{
+			entries:  entries,
+			options:  options,
+			rejected: append([]RejectedRecord(nil), result.Rejected...),
+		}
+	}
+
+	now := time.Now()
+	staged := v.GetEntries()
 	for _, entry := range entries {
-		if !validateRequiredFields(entry, options.RequiredFields) {
+		label := entryLabel(entry)
+
+		if missing := missingRequiredField(entry, options.RequiredFields); missing != "" {
+			result.reject(label, "missing "+missing)
 			continue
 		}
 
-		existing := v.findEntry(entry.Service, entry.Username)
-		if existing != nil {
+		if i := findEntry(staged, entry.Service, entry.Username); i >= 0 {
 			if options.SkipDuplicates {
+				result.Duplicates = append(result.Duplicates, label)
 				continue
 			}
 			if options.UpdateExisting {
-				*existing = entry
+				changes := changedFields(staged[i], entry)
+				if len(changes) == 0 {
+					result.Duplicates = append(result.Duplicates, label)
+					continue
+				}
+				staged[i] = mergeImported(staged[i], entry, now)
+				result.Updated = append(result.Updated, UpdatedRecord{Record: label, Changes: changes})
 				continue
 			}
 		}
 
-		v.Entries = append(v.Entries, entry)
+		staged = append(staged, entry)
+		result.Added = append(result.Added, label)
+	}
+
+	if options.DryRun || result.Imported() == 0 {
+		return nil
+	}
+
+	v.mu.Lock()
+	v.Entries = staged
+	v.mu.Unlock()
+	return v.Save()
+}
+
+// ApplyImport applies the entries a dry run read, without reading the source
+// again. Duplicates are found against the vault as it is now.
+func (v *Vault) ApplyImport(preview ImportResult) (ImportResult, error) {
+	if preview.staged == nil {
+		return ImportResult{}, fmt.Errorf("only a dry run can be applied")
+	}
+
+	result := ImportResult{Rejected: append([]RejectedRecord(nil), preview.staged.rejected...)}
+	options := preview.staged.options
+	options.DryRun = false
+	err := v.processImportedEntries(preview.staged.entries, options, &result)
+	return result, err
+}
+
+func findEntry(entries []Entry, service, username string) int {
+	for i := range entries {
+		if entries[i].Service == service && entries[i].Username == username {
+			return i
+		}
+	}
+	return -1
+}
+
+func entryLabel(entry Entry) string {
+	if entry.Username == "" {
+		return entry.Service
+	}
+	return fmt.Sprintf("%s (%s)", entry.Service, entry.Username)
+}
+
+// mergeImported updates existing with the fields changedFields compares. The
+// history, rotation settings and creation time stay, and a changed password
+// is recorded in the history.
+func mergeImported(existing, imported Entry, now time.Time) Entry {
+	merged := existing
+	merged.Notes = imported.Notes
+	merged.Folder = imported.Folder
+	merged.Tags = imported.Tags
+	merged.URLs = imported.URLs
+	merged.TOTP = imported.TOTP
+	merged.Fields = imported.Fields
+	merged.Attachments = imported.Attachments
+	merged.ExpiresAt = imported.ExpiresAt
+	merged.History = append([]PasswordHistory(nil), existing.History...)
+	changePassword(&merged, imported.Password, now)
+	return merged
+}
+
+func changedFields(old, updated Entry) []string {
+	var changes []string
+	check := func(name string, changed bool) {
+		if changed {
+			changes = append(changes, name)
+		}
 	}
 
-	return nil
+	check("password", old.Password != updated.Password)
+	check("notes", old.Notes != updated.Notes)
+	check("folder", old.Folder != updated.Folder)
+	check("tags", !reflect.DeepEqual(old.Tags, updated.Tags))
+	check("urls", !reflect.DeepEqual(old.URLs, updated.URLs))
+	check("totp", old.TOTP != updated.TOTP)
+	check("fields", !reflect.DeepEqual(old.Fields, updated.Fields))
+	check("attachments", !reflect.DeepEqual(old.Attachments, updated.Attachments))
+	check("expiry", !old.ExpiresAt.Equal(updated.ExpiresAt))
+	return changes
 }
 
-func validateRequiredFields(entry Entry, requiredFields []string) bool {
+func missingRequiredField(entry Entry, requiredFields []string) string {
 	for _, field := range requiredFields {
 		switch strings.ToLower(field) {
 		case "service":
 			if entry.Service == "" {
-				return false
+				return "service"
 			}
 		case "username":
 			if entry.Username == "" {
-				return false
+				return "username"
 			}
 		case "password":
 			if entry.Password == "" {
-				return false
+				return "password"
 			}
 		}
 	}
-	return true
+	return ""
 }
@@ -0,0 +1,96 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyImportUsesDryRunEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.csv")
+	data := "service,username,password\nGitHub,alice,pw1\nBank,bob,pw2\nNoUser,,pw3\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := newTestVault(t, Entry{Service: "Bank", Username: "bob", Password: "old"})
+	options := ImportOptions{Format: AutoDetect, UpdateExisting: true, RequiredFields: []string{"service", "username"}, DryRun: true}
+	preview, err := v.Import(path, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Entries) != 1 || v.Entries[0].Password != "old" {
+		t.Fatalf("dry run changed the vault: %+v", v.Entries)
+	}
+
+	// The file is gone, so applying must not read it again.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	result, err := v.ApplyImport(preview)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DryRun || len(result.Added) != 1 || len(result.Updated) != 1 || len(result.Rejected) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if len(v.Entries) != 2 || v.Entries[0].Password != "pw2" || v.Entries[1].Service != "GitHub" {
+		t.Errorf("entries = %+v", v.Entries)
+	}
+
+	if _, err := v.ApplyImport(result); err == nil {
+		t.Error("applied a result that was not a dry run")
+	}
+}
+
+func TestImportUpdateKeepsEntryState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.csv")
+	data := "service,username,password,notes\nBank,bob,new-pass,moved banks\nMail,carol,mail-pass,second inbox\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	changed := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	v := newTestVault(t,
+		Entry{
+			Service: "Bank", Username: "bob", Password: "old-pass",
+			History:           []PasswordHistory{{Password: "first-pass", ChangedAt: changed}},
+			CreatedAt:         created,
+			PasswordChangedAt: changed,
+			RotationDays:      90,
+			Preset:            "strong",
+		},
+		Entry{Service: "Mail", Username: "carol", Password: "mail-pass", CreatedAt: created, PasswordChangedAt: changed},
+	)
+
+	before := time.Now()
+	result, err := v.Import(path, ImportOptions{Format: AutoDetect, UpdateExisting: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Updated) != 2 || len(result.Added) != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	bank := v.Entries[0]
+	if bank.Password != "new-pass" || bank.Notes != "moved banks" {
+		t.Errorf("bank = %+v", bank)
+	}
+	if len(bank.History) != 2 || bank.History[0].Password != "first-pass" || bank.History[1].Password != "old-pass" {
+		t.Errorf("history = %+v", bank.History)
+	}
+	if bank.PasswordChangedAt.Before(before) {
+		t.Errorf("PasswordChangedAt = %v, want the import time", bank.PasswordChangedAt)
+	}
+	if !bank.CreatedAt.Equal(created) || bank.RotationDays != 90 || bank.Preset != "strong" {
+		t.Errorf("bank lost its state: %+v", bank)
+	}
+
+	// Only the notes changed, so the password history stays empty.
+	mail := v.Entries[1]
+	if mail.Notes != "second inbox" || len(mail.History) != 0 || !mail.PasswordChangedAt.Equal(changed) {
+		t.Errorf("mail = %+v", mail)
+	}
+}
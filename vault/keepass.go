@@ -0,0 +1,236 @@
+package vault
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"pw/kdbx"
+)
+
+func parseKeePass(r io.Reader, options ImportOptions, result *ImportResult) ([]Entry, error) {
+	creds := kdbx.Credentials{Password: options.Passphrase}
+	if options.KeyFile != "" {
+		keyFile, err := os.ReadFile(options.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		creds.KeyFile = keyFile
+	}
+
+	db, err := kdbx.Open(r, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	var walk func(g *kdbx.Group, path []string)
+	walk = func(g *kdbx.Group, path []string) {
+		if g != db.Root && g.UUID == db.RecycleBinUUID {
+			for _, e := range g.Entries {
+				result.reject(e.Get("Title"), "entry is in the recycle bin")
+			}
+			return
+		}
+
+		folder := strings.Join(path, "/")
+		for _, e := range g.Entries {
+			entries = append(entries, keePassEntry(e, folder))
+		}
+		for _, child := range g.Groups {
+			walk(child, append(append([]string{}, path...), child.Name))
+		}
+	}
+	walk(db.Root, nil)
+
+	return entries, nil
+}
+
+func keePassEntry(e *kdbx.Entry, folder string) Entry {
+	entry := Entry{
+		Service:   e.Get("Title"),
+		Username:  e.Get("UserName"),
+		Password:  e.Get("Password"),
+		Notes:     e.Get("Notes"),
+		Folder:    folder,
+		Tags:      e.Tags,
+		CreatedAt: e.Created,
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if e.Expires {
+		entry.ExpiresAt = e.ExpiryTime
+	}
+	if url := e.Get("URL"); url != "" {
+		entry.URLs = append(entry.URLs, url)
+	}
+
+	for _, f := range e.Fields {
+		switch {
+		case kdbx.IsStandardField(f.Key):
+		case f.Key == "otp":
+			entry.TOTP = f.Value
+		case isKeePassURLKey(f.Key):
+			entry.URLs = append(entry.URLs, f.Value)
+		default:
+			entry.Fields = append(entry.Fields, CustomField{Name: f.Key, Value: f.Value, Hidden: f.Protected})
+		}
+	}
+
+	for _, a := range e.Attachments {
+		entry.Attachments = append(entry.Attachments, Attachment{Name: a.Name, Data: a.Data})
+	}
+
+	history := append([]*kdbx.Entry{}, e.History...)
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Modified.Before(history[j].Modified)
+	})
+	for i, h := range history {
+		password := h.Get("Password")
+		next := entry.Password
+		if i+1 < len(history) {
+			next = history[i+1].Get("Password")
+		}
+		if password != "" && password != next {
+			entry.History = append(entry.History, PasswordHistory{Password: password, ChangedAt: h.Modified})
+		}
+	}
+
+	return entry
+}
+
+func exportKeePass(w io.Writer, entries []Entry, options ExportOptions) error {
+	if options.Passphrase == "" {
+		return fmt.Errorf("a passphrase is required for KDBX export")
+	}
+
+	root := &kdbx.Group{UUID: kdbx.NewUUID(), Name: "pwvault"}
+	groups := map[string]*kdbx.Group{"": root}
+
+	var groupFor func(folder string) *kdbx.Group
+	groupFor = func(folder string) *kdbx.Group {
+		if g, ok := groups[folder]; ok {
+			return g
+		}
+		parent, name := "", folder
+		if i := strings.LastIndex(folder, "/"); i >= 0 {
+			parent, name = folder[:i], folder[i+1:]
+		}
+		g := &kdbx.Group{UUID: kdbx.NewUUID(), Name: name}
+		p := groupFor(parent)
+		p.Groups = append(p.Groups, g)
+		groups[folder] = g
+		return g
+	}
+
+	for _, e := range entries {
+		folder := strings.Trim(e.Folder, "/")
+		g := groupFor(folder)
+		g.Entries = append(g.Entries, keePassExportEntry(e, options))
+	}
+
+	db := &kdbx.Database{Name: "pwvault", Root: root}
+	return kdbx.Write(w, db, kdbx.Credentials{Password: options.Passphrase})
+}
+
+func keePassExportEntry(e Entry, options ExportOptions) *kdbx.Entry {
+	password := ""
+	if options.IncludePassword {
+		password = e.Password
+	}
+	notes := ""
+	if options.IncludeNotes {
+		notes = e.Notes
+	}
+	url := ""
+	if len(e.URLs) > 0 {
+		url = e.URLs[0]
+	}
+
+	out := &kdbx.Entry{
+		UUID:     kdbx.NewUUID(),
+		Tags:     e.Tags,
+		Created:  e.CreatedAt,
+		Modified: e.CreatedAt,
+		Fields: []kdbx.Field{
+			{Key: "Title", Value: e.Service},
+			{Key: "UserName", Value: e.Username},
+			{Key: "Password", Value: password, Protected: true},
+			{Key: "URL", Value: url},
+			{Key: "Notes", Value: notes},
+		},
+	}
+	if !e.ExpiresAt.IsZero() {
+		out.Expires = true
+		out.ExpiryTime = e.ExpiresAt
+	}
+
+	for i, u := range e.URLs[min(1, len(e.URLs)):] {
+		key := "KP2A_URL"
+		if i > 0 {
+			key = fmt.Sprintf("KP2A_URL_%d", i)
+		}
+		out.Fields = append(out.Fields, kdbx.Field{Key: key, Value: u})
+	}
+	if e.TOTP != "" {
+		out.Fields = append(out.Fields, kdbx.Field{Key: "otp", Value: e.TOTP, Protected: true})
+	}
+	used := make(map[string]bool)
+	for _, f := range out.Fields {
+		used[f.Key] = true
+	}
+	for _, f := range e.Fields {
+		key := keePassFieldKey(f.Name, used)
+		used[key] = true
+		out.Fields = append(out.Fields, kdbx.Field{Key: key, Value: f.Value, Protected: f.Hidden})
+	}
+	for _, a := range e.Attachments {
+		out.Attachments = append(out.Attachments, kdbx.Attachment{Name: a.Name, Data: a.Data})
+	}
+
+	if options.IncludePassword {
+		for _, h := range e.History {
+			out.History = append(out.History, &kdbx.Entry{
+				UUID:     out.UUID,
+				Created:  e.CreatedAt,
+				Modified: h.ChangedAt,
+				Fields: []kdbx.Field{
+					{Key: "Title", Value: e.Service},
+					{Key: "UserName", Value: e.Username},
+					{Key: "Password", Value: h.Password, Protected: true},
+				},
+			})
+		}
+	}
+
+	return out
+}
+
+// isKeePassURLKey reports the extra URL fields KeePass2Android and KeePassXC
+// write: KP2A_URL, KP2A_URL_1 and so on.
+func isKeePassURLKey(key string) bool {
+	return key == "KP2A_URL" || strings.HasPrefix(key, "KP2A_URL_")
+}
+
+// keePassFieldKey names a custom field so that it neither replaces a key the
+// entry already has nor reads back as a standard field, TOTP or URL.
+func keePassFieldKey(name string, used map[string]bool) string {
+	if name == "" {
+		name = "Field"
+	}
+	reserved := func(key string) bool {
+		return used[key] || kdbx.IsStandardField(key) || key == "otp" || isKeePassURLKey(key)
+	}
+	if !reserved(name) {
+		return name
+	}
+	for n := 2; ; n++ {
+		if key := fmt.Sprintf("%s (%d)", name, n); !reserved(key) {
+			return key
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKeePassExportRenamesReservedFields(t *testing.T) {
+	entry := Entry{
+		Service:  "GitHub",
+		Username: "alice",
+		Password: "s3cret",
+		URLs:     []string{"https://github.com", "https://gist.github.com"},
+		TOTP:     "otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP",
+		Fields: []CustomField{
+			{Name: "Title", Value: "custom title"},
+			{Name: "otp", Value: "123456", Hidden: true},
+			{Name: "KP2A_URL", Value: "not a url"},
+			{Name: "PIN", Value: "1111", Hidden: true},
+			{Name: "PIN", Value: "2222", Hidden: true},
+			{Name: "", Value: "unnamed"},
+		},
+	}
+
+	var buf bytes.Buffer
+	options := ExportOptions{Passphrase: "export pass", IncludePassword: true, IncludeNotes: true}
+	if err := exportKeePass(&buf, []Entry{entry}, options); err != nil {
+		t.Fatal(err)
+	}
+
+	var result ImportResult
+	entries, err := parseKeePass(&buf, ImportOptions{Passphrase: "export pass"}, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries", len(entries))
+	}
+	got := entries[0]
+	if got.Service != "GitHub" || got.Username != "alice" || got.Password != "s3cret" || got.TOTP != entry.TOTP {
+		t.Errorf("entry = %+v", got)
+	}
+	if !reflect.DeepEqual(got.URLs, entry.URLs) {
+		t.Errorf("URLs = %v, want %v", got.URLs, entry.URLs)
+	}
+	want := []CustomField{
+		{Name: "Title (2)", Value: "custom title"},
+		{Name: "otp (2)", Value: "123456", Hidden: true},
+		{Name: "KP2A_URL (2)", Value: "not a url"},
+		{Name: "PIN", Value: "1111", Hidden: true},
+		{Name: "PIN (2)", Value: "2222", Hidden: true},
+		{Name: "Field", Value: "unnamed"},
+	}
+	if !reflect.DeepEqual(got.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", got.Fields, want)
+	}
+}
+
+func TestKeePassImportKeePassXCLayout(t *testing.T) {
+	file, err := os.Open(filepath.Join("..", "kdbx", "testdata", "keepassxc.kdbx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var result ImportResult
+	entries, err := parseKeePass(file, ImportOptions{Passphrase: "correct horse battery staple"}, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries: %+v", len(entries), entries)
+	}
+	if len(result.Rejected) != 1 || result.Rejected[0].Record != "Old forum" {
+		t.Errorf("rejected = %+v", result.Rejected)
+	}
+
+	mail := entries[0]
+	if mail.Service != "Fastmail" || mail.Folder != "" || mail.Password != "Tr0ub4dor&3-ÄÖÜ" {
+		t.Errorf("mail = %+v", mail)
+	}
+	if !reflect.DeepEqual(mail.URLs, []string{"https://www.fastmail.com", "https://app.fastmail.com"}) {
+		t.Errorf("URLs = %v", mail.URLs)
+	}
+	if mail.TOTP == "" || !reflect.DeepEqual(mail.Fields, []CustomField{{Name: "Recovery email", Value: "backup@example.org"}}) {
+		t.Errorf("TOTP %q, fields %+v", mail.TOTP, mail.Fields)
+	}
+	if len(mail.History) != 1 || mail.History[0].Password != "first-password" {
+		t.Errorf("history = %+v", mail.History)
+	}
+	if bank := entries[1]; bank.Service != "Bank" || bank.Folder != "Banking" {
+		t.Errorf("bank = %+v", bank)
+	}
+}
@@ -0,0 +1,217 @@
+package vault
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 1PUX exports are zip archives with the item data in export.data.
+const onePUXDataFile = "export.data"
+
+type onePUXExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePUXItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePUXItem struct {
+	CategoryUUID string `json:"categoryUuid"`
+	State        string `json:"state"`
+	FavIndex     int    `json:"favIndex"`
+	CreatedAt    int64  `json:"createdAt"`
+	Details      struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Name        string `json:"name"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Title  string `json:"title"`
+			Fields []struct {
+				Title string                     `json:"title"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+		PasswordHistory []struct {
+			Value string `json:"value"`
+			Time  int64  `json:"time"`
+		} `json:"passwordHistory"`
+	} `json:"details"`
+	Overview struct {
+		Title string   `json:"title"`
+		URL   string   `json:"url"`
+		Tags  []string `json:"tags"`
+		URLs  []struct {
+			URL string `json:"url"`
+		} `json:"urls"`
+	} `json:"overview"`
+}
+
+const (
+	onePasswordLogin      = "001"
+	onePasswordSecureNote = "003"
+	onePasswordPassword   = "005"
+)
+
+var onePasswordCategories = map[string]string{
+	"002": "credit card",
+	"004": "identity",
+	"006": "document",
+	"100": "software license",
+	"101": "bank account",
+	"102": "database",
+	"103": "driver license",
+	"104": "outdoor license",
+	"105": "membership",
+	"106": "passport",
+	"107": "reward program",
+	"108": "social security number",
+	"109": "wireless router",
+	"110": "server",
+	"111": "SSH key",
+	"112": "API credential",
+	"113": "medical record",
+}
+
+func isZip(prefix []byte) bool {
+	return len(prefix) >= 4 && string(prefix[:4]) == "PK\x03\x04"
+}
+
+func parseOnePUX(src importSource, result *ImportResult) ([]Entry, error) {
+	size, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	archive, err := zip.NewReader(src, size)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := archive.Open(onePUXDataFile)
+	if err != nil {
+		return nil, fmt.Errorf("not a 1Password export: %v", err)
+	}
+	defer data.Close()
+
+	var export onePUXExport
+	if err := json.NewDecoder(data).Decode(&export); err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, account := range export.Accounts {
+		for _, v := range account.Vaults {
+			for _, item := range v.Items {
+				if reason := onePUXSkipReason(item); reason != "" {
+					result.reject(item.Overview.Title, reason)
+					continue
+				}
+				entries = append(entries, onePUXEntry(item, v.Attrs.Name))
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+func onePUXSkipReason(item onePUXItem) string {
+	if item.State == "archived" {
+		return "item is archived"
+	}
+
+	switch item.CategoryUUID {
+	case onePasswordLogin, onePasswordSecureNote, onePasswordPassword:
+		return ""
+	}
+	if name, ok := onePasswordCategories[item.CategoryUUID]; ok {
+		return name + " items are not supported"
+	}
+	return fmt.Sprintf("unsupported item category %s", item.CategoryUUID)
+}
+
+func onePUXEntry(item onePUXItem, vaultName string) Entry {
+	entry := Entry{
+		Service:   item.Overview.Title,
+		Password:  item.Details.Password,
+		Notes:     item.Details.NotesPlain,
+		Folder:    vaultName,
+		Tags:      item.Overview.Tags,
+		CreatedAt: time.Now(),
+	}
+	if item.CreatedAt > 0 {
+		entry.CreatedAt = time.Unix(item.CreatedAt, 0)
+	}
+	if item.FavIndex > 0 {
+		entry.Tags = append(entry.Tags, "favorite")
+	}
+
+	for _, f := range item.Details.LoginFields {
+		switch f.Designation {
+		case "username":
+			entry.Username = f.Value
+		case "password":
+			entry.Password = f.Value
+		}
+	}
+
+	if item.Overview.URL != "" {
+		entry.URLs = append(entry.URLs, item.Overview.URL)
+	}
+	for _, u := range item.Overview.URLs {
+		if u.URL != "" && u.URL != item.Overview.URL {
+			entry.URLs = append(entry.URLs, u.URL)
+		}
+	}
+
+	for _, section := range item.Details.Sections {
+		for _, f := range section.Fields {
+			for kind, raw := range f.Value {
+				var value string
+				if json.Unmarshal(raw, &value) != nil || value == "" {
+					continue
+				}
+
+				switch {
+				case kind == "totp" && entry.TOTP == "":
+					entry.TOTP = value
+				case kind == "url":
+					entry.URLs = append(entry.URLs, value)
+				default:
+					name := f.Title
+					if section.Title != "" {
+						name = strings.TrimSpace(section.Title + " " + name)
+					}
+					entry.Fields = append(entry.Fields, CustomField{
+						Name:   name,
+						Value:  value,
+						Hidden: kind == "concealed",
+					})
+				}
+			}
+		}
+	}
+
+	for _, h := range item.Details.PasswordHistory {
+		if h.Value != "" && h.Value != entry.Password {
+			entry.History = append(entry.History, PasswordHistory{Password: h.Value, ChangedAt: time.Unix(h.Time, 0)})
+		}
+	}
+	sort.Slice(entry.History, func(i, j int) bool {
+		return entry.History[i].ChangedAt.Before(entry.History[j].ChangedAt)
+	})
+
+	return entry
+}
@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestImportOnePUX(t *testing.T) {
+	path := filepath.Join("testdata", "onepassword.1pux")
+	format, err := DetectImportFormat(path)
+	if err != nil || format != OnePasswordPUX {
+		t.Fatalf("format = %q, %v", format, err)
+	}
+
+	v := newTestVault(t)
+	result, err := v.Import(path, ImportOptions{Format: format, RequiredFields: []string{"service"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantRejected := []RejectedRecord{
+		{Record: "Visa", Reason: "credit card items are not supported"},
+		{Record: "Old forum", Reason: "item is archived"},
+	}
+	if !reflect.DeepEqual(result.Rejected, wantRejected) {
+		t.Errorf("rejected = %+v", result.Rejected)
+	}
+	if len(v.Entries) != 3 {
+		t.Fatalf("got %d entries: %+v", len(v.Entries), v.Entries)
+	}
+
+	github := v.Entries[0]
+	if github.Service != "GitHub" || github.Username != "alice" || github.Password != "gh-pass" || github.Folder != "Personal" {
+		t.Errorf("github = %+v", github)
+	}
+	if !reflect.DeepEqual(github.URLs, []string{"https://github.com", "https://gist.github.com"}) {
+		t.Errorf("URLs = %v", github.URLs)
+	}
+	if !reflect.DeepEqual(github.Tags, []string{"dev", "favorite"}) {
+		t.Errorf("tags = %v", github.Tags)
+	}
+	if github.TOTP != "otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP" {
+		t.Errorf("TOTP = %q", github.TOTP)
+	}
+	// The email field holds an object rather than a string and is skipped.
+	if !reflect.DeepEqual(github.Fields, []CustomField{{Name: "Recovery code", Value: "1234-5678", Hidden: true}}) {
+		t.Errorf("fields = %+v", github.Fields)
+	}
+	wantHistory := []PasswordHistory{
+		{Password: "older-gh-pass", ChangedAt: time.Unix(1692000000, 0)},
+		{Password: "old-gh-pass", ChangedAt: time.Unix(1695000000, 0)},
+	}
+	if !reflect.DeepEqual(github.History, wantHistory) {
+		t.Errorf("history = %+v", github.History)
+	}
+	if !github.CreatedAt.Equal(time.Unix(1690000000, 0)) {
+		t.Errorf("created = %v", github.CreatedAt)
+	}
+
+	if note := v.Entries[1]; note.Service != "Wifi codes" || note.Notes != "guest: 1234" || note.Password != "" {
+		t.Errorf("note = %+v", note)
+	}
+	if router := v.Entries[2]; router.Service != "Router" || router.Password != "router-pass" || router.Folder != "Shared" {
+		t.Errorf("router = %+v", router)
+	}
+}
@@ -0,0 +1,277 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A pass store keeps one encrypted file per entry, named after the entry's
+// folder and service: <store>/<folder>/<service>.gpg. The first line of each
+// file is the password and the following lines hold "key: value" metadata.
+const (
+	passExtension = ".gpg"
+	passIDFile    = ".gpg-id"
+)
+
+// GPG runs the gpg command, or any command that accepts the same arguments,
+// such as a wrapper script with its own keyring or a fake used in tests.
+type GPG struct {
+	Command string
+}
+
+func (g GPG) run(stdin io.Reader, args ...string) ([]byte, error) {
+	fields := strings.Fields(g.Command)
+	if len(fields) == 0 {
+		fields = []string{"gpg"}
+	}
+
+	cmd := exec.Command(fields[0], append(fields[1:], args...)...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %v: %s", fields[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %v", fields[0], err)
+	}
+	return out, nil
+}
+
+func (g GPG) Decrypt(path string) ([]byte, error) {
+	return g.run(nil, "--quiet", "--batch", "--decrypt", path)
+}
+
+func (g GPG) Encrypt(plain []byte, recipients []string) ([]byte, error) {
+	args := []string{"--quiet", "--batch", "--yes", "--encrypt"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	return g.run(bytes.NewReader(plain), args...)
+}
+
+func isPassStore(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func parsePassStore(root string, options ImportOptions, result *ImportResult) ([]Entry, error) {
+	gpg := GPG{Command: options.GPGCommand}
+
+	var paths []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), passExtension) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var entries []Entry
+	for _, path := range paths {
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(strings.TrimSuffix(rel, passExtension))
+
+		plain, err := gpg.Decrypt(path)
+		if err != nil {
+			result.reject(rel, err.Error())
+			continue
+		}
+
+		entry, metadata := parsePassEntry(string(plain))
+		entry.Folder, entry.Service = splitPassPath(rel)
+
+		// A file holding only a password, below at least one directory, is
+		// taken to follow the <site>/<login>.gpg convention. Our exports
+		// always write a login line, so they are read back as written.
+		if entry.Folder != "" && !metadata {
+			entry.Username = entry.Service
+			entry.Folder, entry.Service = splitPassPath(entry.Folder)
+		}
+		if info, err := os.Stat(path); err == nil {
+			entry.CreatedAt = info.ModTime()
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func splitPassPath(rel string) (folder, name string) {
+	if i := strings.LastIndex(rel, "/"); i >= 0 {
+		return rel[:i], rel[i+1:]
+	}
+	return "", rel
+}
+
+// parsePassEntry reads a pass file. It also reports whether the file had any
+// "key: value" or otpauth lines.
+func parsePassEntry(content string) (Entry, bool) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	entry := Entry{Password: lines[0]}
+	metadata := false
+
+	var notes []string
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "otpauth://") {
+			entry.TOTP = line
+			metadata = true
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.Contains(key, " ") || strings.HasPrefix(value, "//") {
+			notes = append(notes, line)
+			continue
+		}
+		value = strings.TrimSpace(value)
+		metadata = true
+
+		switch strings.ToLower(key) {
+		case "login", "username", "user":
+			entry.Username = value
+		case "url", "website":
+			entry.URLs = append(entry.URLs, value)
+		case "otpauth", "totp":
+			entry.TOTP = value
+		case "tags":
+			entry.Tags = splitTags(value)
+		default:
+			entry.Fields = append(entry.Fields, CustomField{Name: key, Value: value})
+		}
+	}
+
+	entry.Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+	return entry, metadata
+}
+
+func formatPassEntry(e Entry, options ExportOptions) string {
+	var sb strings.Builder
+	if options.IncludePassword {
+		sb.WriteString(e.Password)
+	}
+	sb.WriteString("\n")
+
+	// The login line is written even when empty, so the file is not taken
+	// for a <site>/<login>.gpg entry on import.
+	fmt.Fprintf(&sb, "login: %s\n", e.Username)
+	for _, u := range e.URLs {
+		fmt.Fprintf(&sb, "url: %s\n", u)
+	}
+	if len(e.Tags) > 0 {
+		fmt.Fprintf(&sb, "tags: %s\n", strings.Join(e.Tags, ", "))
+	}
+	if e.TOTP != "" {
+		sb.WriteString(e.TOTP + "\n")
+	}
+	for _, f := range e.Fields {
+		fmt.Fprintf(&sb, "%s: %s\n", strings.ReplaceAll(f.Name, " ", "_"), f.Value)
+	}
+	if options.IncludeNotes && e.Notes != "" {
+		sb.WriteString(e.Notes + "\n")
+	}
+
+	return sb.String()
+}
+
+func (v *Vault) exportPassStore(root string, options ExportOptions) error {
+	recipients := options.GPGRecipients
+	if len(recipients) == 0 {
+		data, err := os.ReadFile(filepath.Join(root, passIDFile))
+		if err != nil {
+			return fmt.Errorf("no GPG recipients given and no %s in %s", passIDFile, root)
+		}
+		recipients = strings.Fields(string(data))
+	}
+
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return err
+	}
+	idPath := filepath.Join(root, passIDFile)
+	if _, err := os.Stat(idPath); os.IsNotExist(err) {
+		if err := os.WriteFile(idPath, []byte(strings.Join(recipients, "\n")+"\n"), 0600); err != nil {
+			return err
+		}
+	}
+
+	// Every path is chosen before anything is written, so an export never
+	// replaces a file already in the store.
+	entries := v.SelectEntries(options.Filter)
+	paths := make([]string, len(entries))
+	used := make(map[string]bool)
+	for i, e := range entries {
+		name := passName(e)
+		rel := name
+		for n := 2; used[rel]; n++ {
+			rel = fmt.Sprintf("%s-%d", name, n)
+		}
+		used[rel] = true
+
+		paths[i] = filepath.Join(root, filepath.FromSlash(rel)+passExtension)
+		if _, err := os.Lstat(paths[i]); err == nil {
+			return fmt.Errorf("%s already exists in the store; export to an empty directory or remove it first", rel+passExtension)
+		}
+	}
+
+	gpg := GPG{Command: options.GPGCommand}
+	for i, e := range entries {
+		encrypted, err := gpg.Encrypt([]byte(formatPassEntry(e, options)), recipients)
+		if err != nil {
+			return fmt.Errorf("%s: %v", paths[i], err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(paths[i]), 0700); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(paths[i], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(encrypted)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// passName builds the store path for an entry from its folder and service,
+// keeping path separators out of the service name.
+func passName(e Entry) string {
+	service := strings.NewReplacer("/", "-", "\\", "-").Replace(e.Service)
+	if service == "" || service == "." || service == ".." {
+		service = "entry"
+	}
+
+	var parts []string
+	for _, p := range strings.Split(e.Folder, "/") {
+		if p = strings.TrimSpace(p); p != "" && p != "." && p != ".." {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(append(parts, service), "/")
+}
@@ -0,0 +1,175 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeGPG stands in for gpg. It "encrypts" by prepending a line naming the
+// recipients, and "decrypts" by checking and removing that line.
+const fakeGPG = `#!/bin/sh
+mode= recipients= file=
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--encrypt) mode=encrypt ;;
+	--decrypt) mode=decrypt ;;
+	--recipient) shift; recipients="$recipients $1" ;;
+	-*) ;;
+	*) file=$1 ;;
+	esac
+	shift
+done
+case $mode in
+encrypt) echo "fake-gpg$recipients"; cat ;;
+decrypt) head -n 1 "$file" | grep -q '^fake-gpg' || { echo "not encrypted" >&2; exit 2; }; tail -n +2 "$file" ;;
+*) exit 2 ;;
+esac
+`
+
+func writeFakeGPG(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake gpg is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "gpg")
+	if err := os.WriteFile(path, []byte(fakeGPG), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestVault(t *testing.T, entries ...Entry) *Vault {
+	t.Helper()
+	v, err := NewVault(filepath.Join(t.TempDir(), "vault.dat"), []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Entries = entries
+	return v
+}
+
+func TestPassStoreRoundTrip(t *testing.T) {
+	gpg := writeFakeGPG(t)
+	store := filepath.Join(t.TempDir(), "store")
+	entries := []Entry{
+		{Service: "GitHub", Username: "alice", Password: "gh-pass", Folder: "Work/Dev",
+			URLs: []string{"https://github.com"}, Tags: []string{"dev", "work"},
+			Fields: []CustomField{{Name: "Recovery", Value: "1234"}}, Notes: "first line\nsecond line"},
+		{Service: "Router", Password: "admin-pass", Folder: "Home", URLs: []string{"http://192.168.1.1"}},
+		{Service: "Bank", Username: "bob", Password: "bank-pass"},
+		// Only a password, in a folder: must not be read as <site>/<login>.
+		{Service: "Printer", Password: "printer-pass", Folder: "Home/Office"},
+	}
+
+	v := newTestVault(t, entries...)
+	options := ExportOptions{
+		Format:          PassFormat,
+		IncludePassword: true,
+		IncludeNotes:    true,
+		GPGRecipients:   []string{"alice@example.com", "bob@example.com"},
+		GPGCommand:      gpg,
+	}
+	if err := v.Export(store, options); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := os.ReadFile(filepath.Join(store, passIDFile))
+	if err != nil || string(id) != "alice@example.com\nbob@example.com\n" {
+		t.Errorf(".gpg-id = %q, %v", id, err)
+	}
+	data, err := os.ReadFile(filepath.Join(store, "Work", "Dev", "GitHub.gpg"))
+	if err != nil || !strings.HasPrefix(string(data), "fake-gpg alice@example.com bob@example.com\ngh-pass\n") {
+		t.Errorf("GitHub.gpg = %q, %v", data, err)
+	}
+
+	imported := newTestVault(t)
+	if _, err := imported.Import(store, ImportOptions{Format: PassImport, GPGCommand: gpg}); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Entry)
+	for _, e := range imported.Entries {
+		got[e.Service] = e
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("imported %d entries, want %d", len(got), len(entries))
+	}
+	for _, want := range entries {
+		e := got[want.Service]
+		if e.Username != want.Username || e.Password != want.Password || e.Folder != want.Folder || e.Notes != want.Notes {
+			t.Errorf("%s: got %+v", want.Service, e)
+		}
+		if !reflect.DeepEqual(e.URLs, want.URLs) || !reflect.DeepEqual(e.Tags, want.Tags) || !reflect.DeepEqual(e.Fields, want.Fields) {
+			t.Errorf("%s: metadata %v %v %v, want %v %v %v", want.Service, e.URLs, e.Tags, e.Fields, want.URLs, want.Tags, want.Fields)
+		}
+	}
+}
+
+func TestPassExportKeepsExistingFiles(t *testing.T) {
+	gpg := writeFakeGPG(t)
+	store := t.TempDir()
+	existing := filepath.Join(store, "Mail", "Gmail.gpg")
+	if err := os.MkdirAll(filepath.Dir(existing), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("fake-gpg\nold-pass\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := newTestVault(t,
+		Entry{Service: "Amazon", Password: "new-amazon"},
+		Entry{Service: "Gmail", Folder: "Mail", Password: "new-gmail"},
+	)
+	err := v.Export(store, ExportOptions{Format: PassFormat, IncludePassword: true, GPGRecipients: []string{"alice"}, GPGCommand: gpg})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("err = %v, want an already exists error", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "fake-gpg\nold-pass\n" {
+		t.Errorf("existing file was replaced: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(store, "Amazon.gpg")); !os.IsNotExist(err) {
+		t.Errorf("Amazon.gpg was written before the export failed")
+	}
+}
+
+func TestPassStoreLoginFromPath(t *testing.T) {
+	gpg := writeFakeGPG(t)
+	store := t.TempDir()
+	files := map[string]string{
+		"Sites/example.com/carol.gpg": "carol-pass\n",
+		"Sites/forum.gpg":             "forum-pass\nurl: https://forum.example\n",
+		"wifi.gpg":                    "wifi-pass\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(store, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("fake-gpg\n"+content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v := newTestVault(t)
+	if _, err := v.Import(store, ImportOptions{Format: PassImport, GPGCommand: gpg}); err != nil {
+		t.Fatal(err)
+	}
+
+	type key struct{ folder, service, username string }
+	got := make(map[key]bool)
+	for _, e := range v.Entries {
+		got[key{e.Folder, e.Service, e.Username}] = true
+	}
+	for _, want := range []key{
+		{"Sites", "example.com", "carol"},
+		{"Sites", "forum", ""},
+		{"", "wifi", ""},
+	} {
+		if !got[want] {
+			t.Errorf("missing entry %+v in %+v", want, got)
+		}
+	}
+}
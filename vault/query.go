@@ -0,0 +1,475 @@
+package vault
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a parsed search query. Words match service, username, tags,
+// folder, URLs and notes, fuzzily. A word can be scoped to one field with
+// field:value, where the value may use * and ? wildcards and quotes keep
+// spaces and turn fuzzy matching off:
+//
+//	git user:alice service:git* tag:prod -tag:old
+//	(bank OR card) AND NOT folder:archive
+//	notes:"recovery codes" created:>2024-01-01 expires:<=2025-06
+//
+// Terms are combined with AND unless joined by OR, and - or NOT negates a
+// term. Dates compare whole days, months or years.
+type Query struct {
+	root queryNode
+}
+
+// queryNode matches an entry and scores how well it matches.
+type queryNode interface {
+	match(entry Entry) (int, bool)
+}
+
+// Fields that text terms can be scoped to, with aliases, and how much a match
+// in each counts towards an entry's rank.
+var textFields = map[string]string{
+	"service":  "service",
+	"user":     "username",
+	"username": "username",
+	"tag":      "tag",
+	"folder":   "folder",
+	"url":      "url",
+	"notes":    "notes",
+	"note":     "notes",
+}
+
+var fieldWeights = map[string]int{
+	"service":  4,
+	"username": 3,
+	"tag":      2,
+	"folder":   2,
+	"url":      1,
+	"notes":    1,
+}
+
+var dateFields = map[string]func(Entry) time.Time{
+	"created": func(e Entry) time.Time { return e.CreatedAt },
+	"changed": Entry.PasswordChanged,
+	"expires": func(e Entry) time.Time { return e.ExpiresAt },
+}
+
+// ParseQuery parses a search query. An empty query matches every entry.
+func ParseQuery(query string) (*Query, error) {
+	return parseQuery(query, true)
+}
+
+// ParseFilterQuery parses a query without fuzzy matching, so words only match
+// as substrings or wildcards. Filters that export or move entries use it, as
+// a near miss there would select secrets the user did not ask for.
+func ParseFilterQuery(query string) (*Query, error) {
+	return parseQuery(query, false)
+}
+
+func parseQuery(query string, fuzzy bool) (*Query, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return &Query{}, nil
+	}
+
+	p := &queryParser{tokens: tokens, fuzzy: fuzzy}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return &Query{root: root}, nil
+}
+
+// Match reports whether entry matches the query and how relevant it is.
+func (q *Query) Match(entry Entry) (int, bool) {
+	if q.root == nil {
+		return 0, true
+	}
+	return q.root.match(entry)
+}
+
+// SearchEntries returns the entries matching query, most relevant first.
+func (v *Vault) SearchEntries(query string) ([]Entry, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	type result struct {
+		entry Entry
+		score int
+	}
+	var results []result
+	for _, entry := range v.Entries {
+		if score, ok := q.Match(entry); ok {
+			results = append(results, result{entry, score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	entries := make([]Entry, len(results))
+	for i, r := range results {
+		entries[i] = r.entry
+	}
+	return entries, nil
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	andToken
+	orToken
+	notToken
+	openToken
+	closeToken
+)
+
+type queryToken struct {
+	kind   tokenKind
+	text   string
+	quoted bool
+}
+
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		switch c := runes[i]; {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, queryToken{kind: openToken, text: "("})
+			i++
+			continue
+		case c == ')':
+			tokens = append(tokens, queryToken{kind: closeToken, text: ")"})
+			i++
+			continue
+		case c == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, queryToken{kind: notToken, text: "-"})
+			i++
+			continue
+		}
+
+		var word strings.Builder
+		quoted, inQuotes := false, false
+		for ; i < len(runes); i++ {
+			c := runes[i]
+			if c == '"' {
+				quoted, inQuotes = true, !inQuotes
+				continue
+			}
+			if !inQuotes && (unicode.IsSpace(c) || c == '(' || c == ')') {
+				break
+			}
+			word.WriteRune(c)
+		}
+		if inQuotes {
+			return nil, fmt.Errorf("missing closing quote")
+		}
+
+		token := queryToken{kind: wordToken, text: word.String(), quoted: quoted}
+		if !quoted {
+			switch token.text {
+			case "AND":
+				token.kind = andToken
+			case "OR":
+				token.kind = orToken
+			case "NOT":
+				token.kind = notToken
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// queryParser reads tokens by precedence: NOT binds tightest, then AND, then
+// OR.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	fuzzy  bool
+}
+
+func (p *queryParser) next(kind tokenKind) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	var nodes orNode
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		if !p.next(orToken) {
+			break
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var nodes andNode
+	for p.pos < len(p.tokens) {
+		if kind := p.tokens[p.pos].kind; kind == orToken || kind == closeToken {
+			break
+		}
+		if p.next(andToken) && len(nodes) == 0 {
+			return nil, fmt.Errorf("AND needs a term on both sides")
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+
+	switch len(nodes) {
+	case 0:
+		afterOr := p.pos > 0 && p.tokens[p.pos-1].kind == orToken
+		beforeOr := p.pos < len(p.tokens) && p.tokens[p.pos].kind == orToken
+		if afterOr || beforeOr {
+			return nil, fmt.Errorf("OR needs a term on both sides")
+		}
+		return nil, fmt.Errorf("expected a search term")
+	case 1:
+		return nodes[0], nil
+	default:
+		return nodes, nil
+	}
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("expected a search term at the end of the query")
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case notToken:
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node}, nil
+	case openToken:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next(closeToken) {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return node, nil
+	case wordToken:
+		return parseTerm(token, p.fuzzy)
+	default:
+		return nil, fmt.Errorf("unexpected %q", token.text)
+	}
+}
+
+func parseTerm(token queryToken, fuzzy bool) (queryNode, error) {
+	text := token.text
+	field, value, scoped := strings.Cut(text, ":")
+	field = strings.ToLower(field)
+
+	if getDate, ok := dateFields[field]; ok && scoped {
+		return parseDateTerm(field, value, getDate)
+	}
+	name, ok := textFields[field]
+	if !ok || !scoped {
+		// Words like "https://example.com" are plain text.
+		name, value = "", text
+	}
+	if value == "" {
+		if name == "" {
+			return nil, fmt.Errorf("empty search term")
+		}
+		return nil, fmt.Errorf("%s: needs a value", field)
+	}
+
+	term := textTerm{field: name, value: strings.ToLower(value), exact: token.quoted || !fuzzy}
+	if strings.ContainsAny(value, "*?") {
+		pattern := regexp.QuoteMeta(value)
+		pattern = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(pattern)
+		term.pattern = regexp.MustCompile("(?is)^" + pattern + "$")
+	}
+	return term, nil
+}
+
+type andNode []queryNode
+
+func (n andNode) match(entry Entry) (int, bool) {
+	total := 0
+	for _, node := range n {
+		score, ok := node.match(entry)
+		if !ok {
+			return 0, false
+		}
+		total += score
+	}
+	return total, true
+}
+
+type orNode []queryNode
+
+func (n orNode) match(entry Entry) (int, bool) {
+	total, matched := 0, false
+	for _, node := range n {
+		if score, ok := node.match(entry); ok {
+			total += score
+			matched = true
+		}
+	}
+	return total, matched
+}
+
+type notNode struct {
+	node queryNode
+}
+
+func (n notNode) match(entry Entry) (int, bool) {
+	_, ok := n.node.match(entry)
+	return 0, !ok
+}
+
+// textTerm matches a word against one field, or every text field when field
+// is empty.
+type textTerm struct {
+	field string
+	value string
+	// exact turns off fuzzy matching, for quoted values and filters.
+	exact   bool
+	pattern *regexp.Regexp
+}
+
+func (t textTerm) match(entry Entry) (int, bool) {
+	best := 0
+	for _, f := range []struct {
+		name   string
+		values []string
+	}{
+		{"service", []string{entry.Service}},
+		{"username", []string{entry.Username}},
+		{"tag", entry.Tags},
+		{"folder", []string{entry.Folder}},
+		{"url", entry.URLs},
+		{"notes", []string{entry.Notes}},
+	} {
+		if t.field != "" && t.field != f.name {
+			continue
+		}
+		for _, value := range f.values {
+			best = max(best, t.score(f.name, value)*fieldWeights[f.name])
+		}
+	}
+	return best, best > 0
+}
+
+func (t textTerm) score(field, value string) int {
+	if t.pattern != nil {
+		if t.pattern.MatchString(value) {
+			return exactScore
+		}
+		return 0
+	}
+
+	// Scoped tags and folders match whole names, like the export filter.
+	switch {
+	case t.field == "tag" && field == "tag":
+		if strings.EqualFold(value, t.value) {
+			return exactScore
+		}
+		return 0
+	case t.field == "folder" && field == "folder":
+		if inFolder(value, t.value) {
+			return exactScore
+		}
+		return 0
+	}
+	return fuzzyScore(t.value, strings.ToLower(value), !t.exact)
+}
+
+// dateTerm matches entries whose date falls in [from, to), or before or
+// after it.
+type dateTerm struct {
+	date     func(Entry) time.Time
+	op       string
+	from, to time.Time
+}
+
+var queryDateLayouts = []struct {
+	layout string
+	years  int
+	months int
+	days   int
+}{
+	{"2006-01-02", 0, 0, 1},
+	{"2006-01", 0, 1, 0},
+	{"2006", 1, 0, 0},
+}
+
+func parseDateTerm(field, value string, date func(Entry) time.Time) (queryNode, error) {
+	op := "="
+	for _, prefix := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, prefix) {
+			op, value = prefix, value[len(prefix):]
+			break
+		}
+	}
+
+	for _, l := range queryDateLayouts {
+		from, err := time.ParseInLocation(l.layout, value, time.Local)
+		if err == nil {
+			return dateTerm{date: date, op: op, from: from, to: from.AddDate(l.years, l.months, l.days)}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: invalid date %q, expected YYYY-MM-DD, YYYY-MM or YYYY", field, value)
+}
+
+func (t dateTerm) match(entry Entry) (int, bool) {
+	date := t.date(entry)
+	if date.IsZero() {
+		return 0, false
+	}
+
+	switch t.op {
+	case ">":
+		return 0, !date.Before(t.to)
+	case ">=":
+		return 0, !date.Before(t.from)
+	case "<":
+		return 0, date.Before(t.from)
+	case "<=":
+		return 0, date.Before(t.to)
+	default:
+		return 0, !date.Before(t.from) && date.Before(t.to)
+	}
+}
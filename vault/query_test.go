@@ -0,0 +1,249 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var queryEntries = []Entry{
+	{Service: "GitHub", Username: "alice", Folder: "Work/Dev", Tags: []string{"code", "prod"},
+		URLs: []string{"https://github.com"}, Notes: "recovery codes in the safe",
+		CreatedAt: time.Date(2023, 6, 15, 12, 0, 0, 0, time.Local)},
+	{Service: "GitLab", Username: "bob", Folder: "Work", Tags: []string{"code"},
+		CreatedAt: time.Date(2024, 1, 10, 12, 0, 0, 0, time.Local), ExpiresAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local)},
+	{Service: "Bank of Examples", Username: "alice", Folder: "Finance", Tags: []string{"old"},
+		CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)},
+	{Service: "Backblaze", Username: "carol", Folder: "Archive",
+		CreatedAt: time.Date(2022, 12, 31, 12, 0, 0, 0, time.Local)},
+}
+
+// matching returns the services of queryEntries that q matches, in order.
+func matching(t *testing.T, q *Query) string {
+	t.Helper()
+	var services []string
+	for _, e := range queryEntries {
+		if _, ok := q.Match(e); ok {
+			services = append(services, e.Service)
+		}
+	}
+	return strings.Join(services, ",")
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "GitHub,GitLab,Bank of Examples,Backblaze"},
+		{"git", "GitHub,GitLab"},
+		{"GIT", "GitHub,GitLab"},
+		{"git alice", "GitHub"},
+		{"git AND alice", "GitHub"},
+		{"github OR finance", "GitHub,Bank of Examples"},
+		{"-git", "Bank of Examples,Backblaze"},
+		{"NOT git", "Bank of Examples,Backblaze"},
+		{"git -user:bob", "GitHub"},
+		{"(github OR gitlab) AND user:bob", "GitLab"},
+		{"user:alice (finance OR dev)", "GitHub,Bank of Examples"},
+		{"NOT (git OR finance)", "Backblaze"},
+		{"service:git*", "GitHub,GitLab"},
+		{"service:g?tlab", "GitLab"},
+		{"service:*lab", "GitLab"},
+		{"user:alice", "GitHub,Bank of Examples"},
+		{"username:carol", "Backblaze"},
+		{"tag:code", "GitHub,GitLab"},
+		{"tag:cod", ""},
+		{"-tag:old", "GitHub,GitLab,Backblaze"},
+		{"folder:work", "GitHub,GitLab"},
+		{"folder:wor", ""},
+		{"url:github.com", "GitHub"},
+		{"notes:\"recovery codes\"", "GitHub"},
+		{"note:safe", "GitHub"},
+		{"\"bank of\"", "Bank of Examples"},
+		{"https://github.com", "GitHub"},
+		{"unknown:github", ""},
+		{"created:2024", "GitLab,Bank of Examples"},
+		{"created:2024-01", "GitLab"},
+		{"created:2023-06-15", "GitHub"},
+		{"created:=2023-06-15", "GitHub"},
+		{"created:<2023", "Backblaze"},
+		{"created:<=2023", "GitHub,Backblaze"},
+		{"created:>2023", "GitLab,Bank of Examples"},
+		{"created:>=2024-03", "Bank of Examples"},
+		{"created:>2023-06-15 created:<2024-03", "GitLab"},
+		{"expires:<=2025-06", "GitLab"},
+		{"expires:2025", "GitLab"},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("%q: %v", tt.query, err)
+			continue
+		}
+		if got := matching(t, q); got != tt.want {
+			t.Errorf("%q matched %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"(git", "missing closing parenthesis"},
+		{"git)", "unexpected"},
+		{"()", "expected a search term"},
+		{"git OR", "OR needs a term on both sides"},
+		{"OR git", "OR needs a term on both sides"},
+		{"AND git", "AND needs a term on both sides"},
+		{"git AND", "expected a search term at the end"},
+		{"git NOT", "expected a search term at the end"},
+		{"\"git", "missing closing quote"},
+		{"user:", "user: needs a value"},
+		{"created:2024-13-01", "invalid date"},
+		{"created:>yesterday", "invalid date"},
+		{"expires:", "invalid date"},
+	}
+	for _, tt := range tests {
+		for _, parse := range []func(string) (*Query, error){ParseQuery, ParseFilterQuery} {
+			_, err := parse(tt.query)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("%q: err = %v, want one containing %q", tt.query, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestFuzzyQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"gh", "GitHub"},
+		{"gthb", "GitHub"},
+		{"githbu", "GitHub"},
+		{"gihtub", "GitHub"},
+		{"backblaez", "Backblaze"},
+		{"\"gihtub\"", ""},
+		{"bnk", "Bank of Examples"},
+		{"bank", "Bank of Examples,Backblaze"},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matching(t, q); got != tt.want {
+			t.Errorf("fuzzy %q matched %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFilterQueryIsNotFuzzy(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"gh", ""},
+		{"gihtub", ""},
+		{"bank", "Bank of Examples"},
+		{"blaze", "Backblaze"},
+		{"git -gitlab", "GitHub"},
+		{"service:git*", "GitHub,GitLab"},
+	}
+	for _, tt := range tests {
+		q, err := ParseFilterQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matching(t, q); got != tt.want {
+			t.Errorf("filter %q matched %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	v := newTestVault(t,
+		Entry{Service: "Notes app", Notes: "my git server"},
+		Entry{Service: "Work mail", Username: "git"},
+		Entry{Service: "Forgejo", URLs: []string{"https://git.example.com"}},
+		Entry{Service: "GitLab"},
+		Entry{Service: "Git"},
+		Entry{Service: "My Git host"},
+		Entry{Service: "Digital"},
+	)
+
+	results, err := v.SearchEntries("git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range results {
+		got = append(got, e.Service)
+	}
+	// Exact beats prefix beats word prefix beats substring, weighted by
+	// field: an exact username (3×100) outranks a service substring (4×70).
+	// Equal scores keep the vault order.
+	want := "Git,GitLab,My Git host,Work mail,Digital,Notes app,Forgejo"
+	if strings.Join(got, ",") != want {
+		t.Errorf("ranking = %s\nwant      %s", strings.Join(got, ","), want)
+	}
+
+	if _, err := v.SearchEntries("(git"); err == nil {
+		t.Error("searched with an invalid query")
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		term, text string
+		fuzzy      bool
+		want       int
+	}{
+		{"github", "github", true, exactScore},
+		{"git", "github", true, prefixScore},
+		{"hub", "git hub", true, wordPrefixScore},
+		{"thu", "github", true, substringScore},
+		{"gh", "github", false, 0},
+		{"", "github", true, 0},
+		{"a", "bcd", true, 0},
+		{"gihtub", "github", true, typoScore},
+		{"gihtub", "github", false, 0},
+		{"gxhtub", "github", true, 0},
+		{"abc", "abd", true, 0},
+	}
+	for _, tt := range tests {
+		if got := fuzzyScore(tt.term, tt.text, tt.fuzzy); got != tt.want {
+			t.Errorf("fuzzyScore(%q, %q, %v) = %d, want %d", tt.term, tt.text, tt.fuzzy, got, tt.want)
+		}
+	}
+
+	// Tighter subsequences score higher, within the subsequence range.
+	tight := fuzzyScore("gs", "gxs", true)
+	loose := fuzzyScore("gs", "gxxxxs", true)
+	if tight <= loose || loose < minSubsequenceScore || tight > maxSubsequenceScore {
+		t.Errorf("subsequence scores %d and %d", tight, loose)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"gihtub", "github", 1},
+		{"ab", "ba", 1},
+		{"abcd", "acbd", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package vault
+
+import (
+	"fmt"
+
+	"pw/crypto"
+)
+
+func (v *Vault) SplitRecoveryKey(shares, threshold int) ([]crypto.Share, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if len(v.key) == 0 {
+		return nil, fmt.Errorf("vault is locked")
+	}
+	return crypto.Split(v.key, shares, threshold)
+}
+
+func RecoverKey(shares []crypto.Share) ([]byte, error) {
+	key, err := crypto.Combine(shares)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("recovered key has invalid length %d", len(key))
+	}
+	return key, nil
+}
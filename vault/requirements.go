@@ -0,0 +1,280 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"pw/crypto"
+)
+
+// PasswordRequirements are the rules a service imposes on its passwords.
+// Zero values impose nothing.
+type PasswordRequirements struct {
+	MinLength     int    `json:"min_length,omitempty"`
+	MaxLength     int    `json:"max_length,omitempty"`
+	RequireLower  bool   `json:"require_lowercase,omitempty"`
+	RequireUpper  bool   `json:"require_uppercase,omitempty"`
+	RequireDigit  bool   `json:"require_digit,omitempty"`
+	RequireSymbol bool   `json:"require_symbol,omitempty"`
+	Forbidden     string `json:"forbidden,omitempty"`
+}
+
+// RequirementsScope says whether requirements apply to a service or a folder.
+type RequirementsScope string
+
+const (
+	ServiceScope RequirementsScope = "service"
+	FolderScope  RequirementsScope = "folder"
+)
+
+// AppliedRequirements are the requirements that apply to an entry and where
+// they come from.
+type AppliedRequirements struct {
+	PasswordRequirements
+	Scope RequirementsScope
+	Name  string
+}
+
+// Source describes where the requirements come from, such as
+// "service github" or "folder work".
+func (a AppliedRequirements) Source() string {
+	if a.Scope == FolderScope && a.Name == "" {
+		return "all entries"
+	}
+	return string(a.Scope) + " " + a.Name
+}
+
+func (r PasswordRequirements) IsZero() bool {
+	return r == PasswordRequirements{}
+}
+
+func (r PasswordRequirements) Validate() error {
+	if r.MinLength < 0 || r.MaxLength < 0 {
+		return fmt.Errorf("lengths cannot be negative")
+	}
+	if r.MaxLength > 0 && r.MinLength > r.MaxLength {
+		return fmt.Errorf("minimum length %d exceeds maximum length %d", r.MinLength, r.MaxLength)
+	}
+	return nil
+}
+
+// Check lists the ways password breaks the requirements.
+func (r PasswordRequirements) Check(password string) []string {
+	var violations []string
+
+	length := len([]rune(password))
+	if r.MinLength > 0 && length < r.MinLength {
+		violations = append(violations, fmt.Sprintf("shorter than %d characters", r.MinLength))
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		violations = append(violations, fmt.Sprintf("longer than %d characters", r.MaxLength))
+	}
+
+	var lower, upper, digit, symbol bool
+	forbidden := make(map[rune]bool)
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		default:
+			symbol = true
+		}
+		if strings.ContainsRune(r.Forbidden, c) {
+			forbidden[c] = true
+		}
+	}
+
+	for _, class := range []struct {
+		required, present bool
+		name              string
+	}{
+		{r.RequireLower, lower, "lowercase letter"},
+		{r.RequireUpper, upper, "uppercase letter"},
+		{r.RequireDigit, digit, "digit"},
+		{r.RequireSymbol, symbol, "symbol"},
+	} {
+		if class.required && !class.present {
+			violations = append(violations, "no "+class.name)
+		}
+	}
+
+	if len(forbidden) > 0 {
+		chars := make([]string, 0, len(forbidden))
+		for c := range forbidden {
+			chars = append(chars, string(c))
+		}
+		sort.Strings(chars)
+		violations = append(violations, "contains forbidden characters "+strings.Join(chars, " "))
+	}
+	return violations
+}
+
+// Constrain adjusts a generator policy so the passwords it produces meet the
+// requirements.
+func (r PasswordRequirements) Constrain(p crypto.PasswordPolicy) crypto.PasswordPolicy {
+	if r.MinLength > 0 {
+		p.Length = max(p.Length, r.MinLength)
+		if p.MaxLength > 0 {
+			p.MaxLength = max(p.MaxLength, r.MinLength)
+		}
+	}
+	if r.MaxLength > 0 && (p.MaxLength == 0 || p.MaxLength > r.MaxLength) {
+		p.MaxLength = r.MaxLength
+	}
+
+	for _, class := range []struct {
+		required bool
+		min      *int
+	}{{r.RequireLower, &p.MinLower}, {r.RequireUpper, &p.MinUpper}, {r.RequireDigit, &p.MinDigits}, {r.RequireSymbol, &p.MinSymbols}} {
+		if class.required && *class.min < 1 {
+			*class.min = 1
+		}
+	}
+
+	p.Exclude += r.Forbidden
+	return p
+}
+
+// String summarises the requirements for listings.
+func (r PasswordRequirements) String() string {
+	var parts []string
+	if r.MinLength > 0 {
+		parts = append(parts, fmt.Sprintf("at least %d characters", r.MinLength))
+	}
+	if r.MaxLength > 0 {
+		parts = append(parts, fmt.Sprintf("at most %d characters", r.MaxLength))
+	}
+	for _, class := range []struct {
+		required bool
+		name     string
+	}{{r.RequireLower, "lowercase"}, {r.RequireUpper, "uppercase"}, {r.RequireDigit, "digit"}, {r.RequireSymbol, "symbol"}} {
+		if class.required {
+			parts = append(parts, class.name+" required")
+		}
+	}
+	if r.Forbidden != "" {
+		parts = append(parts, "no "+r.Forbidden)
+	}
+	if len(parts) == 0 {
+		return "no requirements"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetRequirements attaches requirements to a service or folder. Empty
+// requirements remove them. Service names are matched case-insensitively.
+func (v *Vault) SetRequirements(scope RequirementsScope, name string, r PasswordRequirements) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+
+	var policies *map[string]PasswordRequirements
+	switch scope {
+	case ServiceScope:
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return fmt.Errorf("service name cannot be empty")
+		}
+		policies = &v.serviceRequirements
+	case FolderScope:
+		name = strings.Trim(name, "/")
+		policies = &v.folderRequirements
+	default:
+		return fmt.Errorf("unknown scope %q", scope)
+	}
+
+	if r.IsZero() {
+		delete(*policies, name)
+	} else {
+		if *policies == nil {
+			*policies = make(map[string]PasswordRequirements)
+		}
+		(*policies)[name] = r
+	}
+	return v.Save()
+}
+
+// ListRequirements returns every attached requirement, services first.
+func (v *Vault) ListRequirements() []AppliedRequirements {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var list []AppliedRequirements
+	for _, scope := range []struct {
+		scope    RequirementsScope
+		policies map[string]PasswordRequirements
+	}{{ServiceScope, v.serviceRequirements}, {FolderScope, v.folderRequirements}} {
+		names := make([]string, 0, len(scope.policies))
+		for name := range scope.policies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			list = append(list, AppliedRequirements{PasswordRequirements: scope.policies[name], Scope: scope.scope, Name: name})
+		}
+	}
+	return list
+}
+
+// RequirementsFor returns the requirements for an entry: those of its
+// service, else those of its closest folder with requirements. An empty
+// folder name applies to every entry.
+func (v *Vault) RequirementsFor(entry Entry) (AppliedRequirements, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.requirementsFor(entry)
+}
+
+func (v *Vault) requirementsFor(entry Entry) (AppliedRequirements, bool) {
+	service := strings.ToLower(strings.TrimSpace(entry.Service))
+	if r, ok := v.serviceRequirements[service]; ok {
+		return AppliedRequirements{PasswordRequirements: r, Scope: ServiceScope, Name: service}, true
+	}
+
+	applied, depth := AppliedRequirements{}, -1
+	for folder, r := range v.folderRequirements {
+		if folder != "" && !inFolder(entry.Folder, folder) {
+			continue
+		}
+		n := 0
+		if folder != "" {
+			n = strings.Count(folder, "/") + 1
+		}
+		if n > depth {
+			applied, depth = AppliedRequirements{PasswordRequirements: r, Scope: FolderScope, Name: folder}, n
+		}
+	}
+	return applied, depth >= 0
+}
+
+// RequirementsChanged reports whether updated, after a change of service or
+// folder, falls under other requirements than original.
+func (v *Vault) RequirementsChanged(original, updated Entry) bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	before, _ := v.requirementsFor(original)
+	after, _ := v.requirementsFor(updated)
+	return before != after
+}
+
+// CheckCompliance lists how entry's password breaks the requirements that
+// apply to it.
+func (v *Vault) CheckCompliance(entry Entry) []string {
+	r, ok := v.RequirementsFor(entry)
+	if !ok {
+		return nil
+	}
+	return r.Check(entry.Password)
+}
@@ -0,0 +1,199 @@
+package vault
+
+import (
+	"reflect"
+	"testing"
+
+	"pw/crypto"
+)
+
+func TestRequirementsCheck(t *testing.T) {
+	r := PasswordRequirements{MinLength: 8, MaxLength: 12, RequireLower: true, RequireUpper: true, RequireDigit: true, RequireSymbol: true, Forbidden: "<>&"}
+	for password, want := range map[string][]string{
+		"Abcdef1!":       nil,
+		"Äbcdéf1!":       nil,
+		"Ab1!":           {"shorter than 8 characters"},
+		"Abcdefghij12!!": {"longer than 12 characters"},
+		"abcdefg1!":      {"no uppercase letter"},
+		"ABCDEFGH":       {"no lowercase letter", "no digit", "no symbol"},
+		"Abcdef1<&":      {"contains forbidden characters & <"},
+	} {
+		if got := r.Check(password); !reflect.DeepEqual(got, want) {
+			t.Errorf("Check(%q) = %q, want %q", password, got, want)
+		}
+	}
+
+	if got := (PasswordRequirements{}).Check("x"); got != nil {
+		t.Errorf("empty requirements: Check = %q", got)
+	}
+}
+
+func TestRequirementsValidate(t *testing.T) {
+	for r, ok := range map[PasswordRequirements]bool{
+		{}:                           true,
+		{MinLength: 8, MaxLength: 8}: true,
+		{MinLength: 12}:              true,
+		{MinLength: 9, MaxLength: 8}: false,
+		{MinLength: -1}:              false,
+		{MaxLength: -4}:              false,
+	} {
+		if err := r.Validate(); (err == nil) != ok {
+			t.Errorf("%+v: Validate = %v", r, err)
+		}
+	}
+}
+
+func TestConstrainedPolicyMeetsRequirements(t *testing.T) {
+	g := crypto.NewGenerator()
+	for _, test := range []struct {
+		requirements PasswordRequirements
+		policy       crypto.PasswordPolicy
+	}{
+		{PasswordRequirements{MinLength: 20}, crypto.DefaultPolicy(12)},
+		{PasswordRequirements{MaxLength: 8}, crypto.DefaultPolicy(16)},
+		{PasswordRequirements{MinLength: 10, MaxLength: 10}, crypto.PasswordPolicy{Length: 6, MaxLength: 8}},
+		{PasswordRequirements{RequireSymbol: true, RequireDigit: true}, crypto.PasswordPolicy{Length: 10, MinSymbols: -1}},
+		{PasswordRequirements{RequireUpper: true, Forbidden: "!@#$"}, crypto.PasswordPolicy{Length: 12, MinSymbols: 2}},
+	} {
+		policy := test.requirements.Constrain(test.policy)
+		for i := 0; i < 50; i++ {
+			password, err := g.GeneratePolicy(policy)
+			if err != nil {
+				t.Fatalf("%+v: %v", test.requirements, err)
+			}
+			if violations := test.requirements.Check(password); len(violations) > 0 {
+				t.Fatalf("%+v: generated %q, which breaks them: %q", test.requirements, password, violations)
+			}
+		}
+	}
+}
+
+func TestRequirementsFor(t *testing.T) {
+	v := newTestVault(t)
+	root := PasswordRequirements{MinLength: 8}
+	work := PasswordRequirements{MinLength: 12}
+	finance := PasswordRequirements{MinLength: 16, RequireSymbol: true}
+	bank := PasswordRequirements{MaxLength: 20, Forbidden: "&"}
+	for _, set := range []struct {
+		scope RequirementsScope
+		name  string
+		r     PasswordRequirements
+	}{
+		{FolderScope, "", root},
+		{FolderScope, "/Work/", work},
+		{FolderScope, "Work/Finance", finance},
+		{ServiceScope, " Bank ", bank},
+	} {
+		if err := v.SetRequirements(set.scope, set.name, set.r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		entry  Entry
+		want   PasswordRequirements
+		source string
+	}{
+		{Entry{Service: "Mail"}, root, "all entries"},
+		{Entry{Service: "Wiki", Folder: "work/docs"}, work, "folder Work"},
+		{Entry{Service: "Payroll", Folder: "Work/Finance/2024"}, finance, "folder Work/Finance"},
+		{Entry{Service: "Workshop", Folder: "Workshop"}, root, "all entries"},
+		// The service's requirements win over any folder's.
+		{Entry{Service: "BANK", Folder: "Work/Finance"}, bank, "service bank"},
+	} {
+		got, ok := v.RequirementsFor(test.entry)
+		if !ok || got.PasswordRequirements != test.want || got.Source() != test.source {
+			t.Errorf("%+v: got %+v from %q, %v; want %+v from %q", test.entry, got.PasswordRequirements, got.Source(), ok, test.want, test.source)
+		}
+	}
+
+	// Empty requirements remove the rule.
+	if err := v.SetRequirements(FolderScope, "", PasswordRequirements{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := v.RequirementsFor(Entry{Service: "Mail"}); ok {
+		t.Errorf("removed requirements still apply: %+v", got)
+	}
+
+	if err := v.SetRequirements(ServiceScope, "  ", work); err == nil {
+		t.Error("requirements for an empty service name were accepted")
+	}
+	if err := v.SetRequirements(FolderScope, "Work", PasswordRequirements{MinLength: 9, MaxLength: 8}); err == nil {
+		t.Error("invalid requirements were accepted")
+	}
+}
+
+func TestRequirementsPersist(t *testing.T) {
+	v := newTestVault(t)
+	if err := v.SetRequirements(ServiceScope, "GitHub", PasswordRequirements{MinLength: 16}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetRequirements(FolderScope, "Home", PasswordRequirements{RequireDigit: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewVault(v.filePath, v.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := reopened.ListRequirements(), v.ListRequirements(); !reflect.DeepEqual(got, want) || len(got) != 2 {
+		t.Errorf("reopened vault has %+v, want %+v", got, want)
+	}
+}
+
+// TestCheckComplianceOnAddAndUpdate follows the checks made when an entry is
+// added and when it is updated.
+func TestCheckComplianceOnAddAndUpdate(t *testing.T) {
+	v := newTestVault(t)
+	for _, set := range []struct {
+		scope RequirementsScope
+		name  string
+		r     PasswordRequirements
+	}{
+		{FolderScope, "Work", PasswordRequirements{MinLength: 12}},
+		{FolderScope, "Work/Team", PasswordRequirements{MinLength: 12}},
+		{ServiceScope, "Bank", PasswordRequirements{RequireSymbol: true}},
+	} {
+		if err := v.SetRequirements(set.scope, set.name, set.r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Adding: the new entry's folder decides.
+	if got := v.CheckCompliance(Entry{Service: "Mail", Folder: "Work", Password: "short1"}); len(got) != 1 {
+		t.Errorf("add to Work: violations %q, want one", got)
+	}
+	if got := v.CheckCompliance(Entry{Service: "Mail", Folder: "Home", Password: "short1"}); got != nil {
+		t.Errorf("add to Home: violations %q, want none", got)
+	}
+
+	// Updating: moving or renaming an entry can bring other requirements
+	// for the password it already has.
+	original := Entry{Service: "Mail", Folder: "Home", Password: "short1"}
+	for _, test := range []struct {
+		name       string
+		updated    Entry
+		changed    bool
+		violations int
+	}{
+		{"new username", Entry{Service: "Mail", Username: "alex", Folder: "Home", Password: "short1"}, false, 0},
+		{"into a folder with requirements", Entry{Service: "Mail", Folder: "work", Password: "short1"}, true, 1},
+		{"to a service with requirements", Entry{Service: "bank", Folder: "Home", Password: "short1"}, true, 1},
+		{"to a folder without requirements", Entry{Service: "Mail", Folder: "Personal", Password: "short1"}, false, 0},
+	} {
+		if got := v.RequirementsChanged(original, test.updated); got != test.changed {
+			t.Errorf("%s: RequirementsChanged = %v, want %v", test.name, got, test.changed)
+		}
+		if got := v.CheckCompliance(test.updated); len(got) != test.violations {
+			t.Errorf("%s: violations %q, want %d", test.name, got, test.violations)
+		}
+	}
+
+	// A subfolder without its own requirements keeps those of its parent.
+	if v.RequirementsChanged(Entry{Service: "Wiki", Folder: "Work"}, Entry{Service: "Wiki", Folder: "Work/Docs"}) {
+		t.Error("moving into a subfolder without requirements was reported as a change")
+	}
+	if !v.RequirementsChanged(Entry{Service: "Wiki", Folder: "Work"}, Entry{Service: "Wiki", Folder: "Work/Team"}) {
+		t.Error("moving into a subfolder with its own requirements was not reported")
+	}
+}
@@ -0,0 +1,181 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RotationStatus describes when an entry's password is due for rotation.
+type RotationStatus struct {
+	Index int
+	Entry Entry
+	// Days is the rotation interval that applies, or 0 when only the
+	// entry's expiry date makes it due.
+	Days    int
+	DueAt   time.Time
+	Overdue bool
+}
+
+// PasswordChanged returns when the entry's password was last set.
+func (e Entry) PasswordChanged() time.Time {
+	if !e.PasswordChangedAt.IsZero() {
+		return e.PasswordChangedAt
+	}
+	return e.CreatedAt
+}
+
+// SetFolderRotation makes passwords in folder and its subfolders rotate every
+// days days. Zero removes the policy.
+func (v *Vault) SetFolderRotation(folder string, days int) error {
+	if days < 0 {
+		return fmt.Errorf("rotation interval cannot be negative")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+
+	folder = strings.Trim(folder, "/")
+	if days == 0 {
+		delete(v.folderRotation, folder)
+	} else {
+		if v.folderRotation == nil {
+			v.folderRotation = make(map[string]int)
+		}
+		v.folderRotation[folder] = days
+	}
+	return v.Save()
+}
+
+// FolderRotation returns the folder rotation policies in days.
+func (v *Vault) FolderRotation() map[string]int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	policies := make(map[string]int, len(v.folderRotation))
+	for folder, days := range v.folderRotation {
+		policies[folder] = days
+	}
+	return policies
+}
+
+// RotationDays returns the interval that applies to entry: its own, or that
+// of the closest folder with a policy. An empty folder name applies to every
+// entry.
+func (v *Vault) RotationDays(entry Entry) int {
+	if entry.RotationDays > 0 {
+		return entry.RotationDays
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.folderRotationDays(entry)
+}
+
+func (v *Vault) folderRotationDays(entry Entry) int {
+	days, depth := 0, -1
+	for folder, d := range v.folderRotation {
+		if folder != "" && !inFolder(entry.Folder, folder) {
+			continue
+		}
+		n := 0
+		if folder != "" {
+			n = strings.Count(folder, "/") + 1
+		}
+		if n > depth {
+			days, depth = d, n
+		}
+	}
+	return days
+}
+
+func (v *Vault) rotationStatus(index int, entry Entry, now time.Time) (RotationStatus, bool) {
+	days := entry.RotationDays
+	if days <= 0 {
+		days = v.folderRotationDays(entry)
+	}
+
+	status := RotationStatus{Index: index, Entry: entry, Days: days}
+	if days > 0 {
+		status.DueAt = entry.PasswordChanged().AddDate(0, 0, days)
+	}
+	if !entry.ExpiresAt.IsZero() && (status.DueAt.IsZero() || entry.ExpiresAt.Before(status.DueAt)) {
+		status.DueAt = entry.ExpiresAt
+	}
+	if status.DueAt.IsZero() {
+		return status, false
+	}
+
+	status.Overdue = !now.Before(status.DueAt)
+	return status, true
+}
+
+// RotationDue lists the entries that are overdue or become due within the
+// given duration, soonest first.
+func (v *Vault) RotationDue(within time.Duration) []RotationStatus {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	now := time.Now()
+	var due []RotationStatus
+	for i, entry := range v.Entries {
+		status, ok := v.rotationStatus(i, entry, now)
+		if ok && status.DueAt.Before(now.Add(within)) {
+			due = append(due, status)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].DueAt.Before(due[j].DueAt)
+	})
+	return due
+}
+
+// OverdueCount returns how many entries are past their rotation date.
+func (v *Vault) OverdueCount() int {
+	count := 0
+	for _, status := range v.RotationDue(0) {
+		if status.Overdue {
+			count++
+		}
+	}
+	return count
+}
+
+// changePassword sets a new password, keeping the old one in the history.
+func changePassword(entry *Entry, password string, now time.Time) {
+	if entry.Password == password {
+		return
+	}
+	if entry.Password != "" {
+		entry.History = append(entry.History, PasswordHistory{Password: entry.Password, ChangedAt: now})
+	}
+	entry.Password = password
+	entry.PasswordChangedAt = now
+	if !entry.ExpiresAt.IsZero() && !now.Before(entry.ExpiresAt) {
+		entry.ExpiresAt = time.Time{}
+	}
+}
+
+// RotatePassword replaces the password of the entry at index.
+func (v *Vault) RotatePassword(index int, password string) error {
+	if password == "" {
+		return fmt.Errorf("password cannot be empty")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+	if index < 0 || index >= len(v.Entries) {
+		return fmt.Errorf("invalid entry index")
+	}
+
+	changePassword(&v.Entries[index], password, time.Now())
+	return v.Save()
+}
@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotationDays(t *testing.T) {
+	v := newTestVault(t)
+	for folder, days := range map[string]int{"/": 365, "Work/": 90, "Work/Prod": 30} {
+		if err := v.SetFolderRotation(folder, days); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := v.SetFolderRotation("Home", -1); err == nil {
+		t.Error("accepted a negative interval")
+	}
+
+	tests := []struct {
+		entry Entry
+		want  int
+	}{
+		{Entry{Folder: "Work/Prod", RotationDays: 7}, 7},
+		{Entry{Folder: "Work/Prod/DB"}, 30},
+		{Entry{Folder: "work/dev"}, 90},
+		{Entry{Folder: "Work"}, 90},
+		{Entry{Folder: "Workshop"}, 365},
+		{Entry{Folder: "Home"}, 365},
+		{Entry{}, 365},
+	}
+	for _, tt := range tests {
+		if got := v.RotationDays(tt.entry); got != tt.want {
+			t.Errorf("RotationDays(%q, own %d) = %d, want %d", tt.entry.Folder, tt.entry.RotationDays, got, tt.want)
+		}
+	}
+
+	// Without the root policy, entries outside the folders have none.
+	if err := v.SetFolderRotation("", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.RotationDays(Entry{Folder: "Home"}); got != 0 {
+		t.Errorf("RotationDays(Home) = %d after removing the root policy", got)
+	}
+	if got := v.FolderRotation(); len(got) != 2 || got["Work"] != 90 || got["Work/Prod"] != 30 {
+		t.Errorf("FolderRotation() = %v", got)
+	}
+}
+
+func TestRotationDue(t *testing.T) {
+	now := time.Now()
+	daysAgo := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+
+	v := newTestVault(t,
+		Entry{Service: "Later", Folder: "Work", PasswordChangedAt: daysAgo(30)},
+		Entry{Service: "Soon", Folder: "Work", PasswordChangedAt: daysAgo(80)},
+		Entry{Service: "Overdue", Folder: "Work", CreatedAt: daysAgo(100)},
+		Entry{Service: "Expiring", Folder: "Work", PasswordChangedAt: daysAgo(1), ExpiresAt: now.AddDate(0, 0, 2)},
+		Entry{Service: "Own interval", Folder: "Work", PasswordChangedAt: daysAgo(10), RotationDays: 7},
+		Entry{Service: "No policy", Folder: "Home", CreatedAt: daysAgo(1000)},
+	)
+	if err := v.SetFolderRotation("Work", 90); err != nil {
+		t.Fatal(err)
+	}
+
+	due := v.RotationDue(30 * 24 * time.Hour)
+	var services []string
+	for _, s := range due {
+		services = append(services, s.Entry.Service)
+	}
+	want := []string{"Overdue", "Own interval", "Expiring", "Soon"}
+	if len(services) != len(want) {
+		t.Fatalf("due = %q, want %q", services, want)
+	}
+	for i := range want {
+		if services[i] != want[i] {
+			t.Fatalf("due = %q, want %q", services, want)
+		}
+	}
+
+	for _, s := range due {
+		wantOverdue := s.Entry.Service == "Overdue" || s.Entry.Service == "Own interval"
+		if s.Overdue != wantOverdue {
+			t.Errorf("%s overdue = %v", s.Entry.Service, s.Overdue)
+		}
+	}
+	if s := due[0]; s.Index != 2 || s.Days != 90 || !s.DueAt.Equal(daysAgo(100).AddDate(0, 0, 90)) {
+		t.Errorf("overdue status = %+v", s)
+	}
+	if s := due[2]; !s.DueAt.Equal(now.AddDate(0, 0, 2)) {
+		t.Errorf("expiry should come before the interval: %+v", s)
+	}
+	if got := v.OverdueCount(); got != 2 {
+		t.Errorf("OverdueCount() = %d, want 2", got)
+	}
+}
+
+func TestRotatePassword(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	v := newTestVault(t, Entry{Service: "Mail", Password: "old", ExpiresAt: expired})
+
+	if err := v.RotatePassword(0, ""); err == nil {
+		t.Error("rotated to an empty password")
+	}
+	if err := v.RotatePassword(1, "new"); err == nil {
+		t.Error("rotated a missing entry")
+	}
+	if err := v.RotatePassword(0, "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	e := v.Entries[0]
+	if e.Password != "new" || len(e.History) != 1 || e.History[0].Password != "old" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.PasswordChangedAt.IsZero() || !e.ExpiresAt.IsZero() {
+		t.Errorf("changed at %v, expiry %v", e.PasswordChangedAt, e.ExpiresAt)
+	}
+	if len(v.RotationDue(0)) != 0 {
+		t.Error("a rotated password is still due")
+	}
+
+	// Setting the same password again changes nothing.
+	changed := e.PasswordChangedAt
+	if err := v.RotatePassword(0, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Entries[0].History) != 1 || !v.Entries[0].PasswordChangedAt.Equal(changed) {
+		t.Errorf("entry = %+v", v.Entries[0])
+	}
+}
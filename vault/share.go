@@ -0,0 +1,241 @@
+package vault
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"filippo.io/age/armor"
+)
+
+type EntryFilter struct {
+	Query  string
+	Tags   []string
+	Folder string
+	// Since and Until limit entries by creation time when set.
+	Since time.Time
+	Until time.Time
+}
+
+// Validate reports a query that cannot be parsed. Invalid queries match no
+// entries. Filter queries are not fuzzy; see ParseFilterQuery.
+func (f EntryFilter) Validate() error {
+	_, err := ParseFilterQuery(f.Query)
+	return err
+}
+
+func (f EntryFilter) Matches(entry Entry) bool {
+	query, err := ParseFilterQuery(f.Query)
+	return err == nil && f.matches(query, entry)
+}
+
+func (f EntryFilter) matches(query *Query, entry Entry) bool {
+	if _, ok := query.Match(entry); !ok {
+		return false
+	}
+
+	if f.Folder != "" && !inFolder(entry.Folder, f.Folder) {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		if !entry.HasTag(tag) {
+			return false
+		}
+	}
+
+	if !f.Since.IsZero() && entry.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !entry.CreatedAt.Before(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+func (e Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func inFolder(folder, parent string) bool {
+	folder = strings.Trim(folder, "/")
+	parent = strings.Trim(parent, "/")
+	return strings.EqualFold(folder, parent) ||
+		strings.HasPrefix(strings.ToLower(folder), strings.ToLower(parent)+"/")
+}
+
+func (v *Vault) SelectEntries(filter EntryFilter) []Entry {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	results := make([]Entry, 0)
+	query, err := ParseFilterQuery(filter.Query)
+	if err != nil {
+		return results
+	}
+	for _, entry := range v.Entries {
+		if filter.matches(query, entry) {
+			results = append(results, entry)
+		}
+	}
+	return results
+}
+
+// ExportVault writes the selected entries, with all their fields, to a new
+// age file protected by passphrase. MergeVault reads it back, and the age
+// tool can decrypt it too.
+func (v *Vault) ExportVault(path string, passphrase []byte, filter EntryFilter) (int, error) {
+	if len(passphrase) == 0 {
+		return 0, fmt.Errorf("passphrase cannot be empty")
+	}
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+
+	entries := v.SelectEntries(filter)
+	if len(entries) == 0 {
+		return 0, fmt.Errorf("no entries match the selection")
+	}
+	data, err := json.Marshal(VaultData{Entries: entries})
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return 0, fmt.Errorf("file %s already exists", path)
+		}
+		return 0, err
+	}
+	if err := writeSharedVault(file, data, string(passphrase)); err != nil {
+		file.Close()
+		os.Remove(path)
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func writeSharedVault(w io.Writer, data []byte, passphrase string) error {
+	encrypted, err := encryptExport(w, passphrase)
+	if err != nil {
+		return err
+	}
+	if _, err := encrypted.Write(data); err != nil {
+		return err
+	}
+	return encrypted.Close()
+}
+
+// readSharedVault reads a file written by ExportVault.
+func readSharedVault(path string, passphrase []byte) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buffered := bufio.NewReader(file)
+	if prefix, _ := buffered.Peek(len(armor.Header)); !isEncryptedExport(prefix) {
+		return nil, fmt.Errorf("%s is not a shared vault", path)
+	}
+
+	decrypted, err := decryptExport(buffered, string(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	var data VaultData
+	if err := json.NewDecoder(decrypted).Decode(&data); err != nil {
+		return nil, fmt.Errorf("%s is not a shared vault: %v", path, err)
+	}
+	return data.Entries, nil
+}
+
+func (v *Vault) MergeVault(path string, passphrase []byte, options ImportOptions) (ImportResult, error) {
+	var result ImportResult
+	if len(passphrase) == 0 {
+		return result, fmt.Errorf("passphrase cannot be empty")
+	}
+	entries, err := readSharedVault(path, passphrase)
+	if err != nil {
+		return result, err
+	}
+
+	err = v.processImportedEntries(entries, options, &result)
+	return result, err
+}
+
+func (v *Vault) MoveEntries(dst *Vault, filter EntryFilter) (int, error) {
+	if dst == v {
+		return 0, fmt.Errorf("source and destination vault are the same")
+	}
+	if err := v.checkWritable(); err != nil {
+		return 0, err
+	}
+	if err := dst.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	query, err := ParseFilterQuery(filter.Query)
+	if err != nil {
+		return 0, err
+	}
+
+	// The source stays locked until both vaults are saved, so nothing can
+	// change between choosing the entries and removing them.
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	kept := make([]Entry, 0, len(v.Entries))
+	moved := make([]Entry, 0)
+	for _, entry := range v.Entries {
+		if filter.matches(query, entry) {
+			moved = append(moved, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	if len(moved) == 0 {
+		return 0, fmt.Errorf("no entries match the selection")
+	}
+
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	previous := dst.Entries
+	dst.Entries = append(append(make([]Entry, 0, len(previous)+len(moved)), previous...), moved...)
+	if err := dst.Save(); err != nil {
+		dst.Entries = previous
+		return 0, err
+	}
+
+	original := v.Entries
+	v.Entries = kept
+	if err := v.Save(); err != nil {
+		// Take the copies out of the destination again rather than leave
+		// the entries in both vaults.
+		v.Entries = original
+		dst.Entries = previous
+		if rollbackErr := dst.Save(); rollbackErr != nil {
+			return 0, fmt.Errorf("%v; the entries are now in both vaults: %v", err, rollbackErr)
+		}
+		return 0, err
+	}
+	return len(moved), nil
+}
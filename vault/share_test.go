@@ -0,0 +1,134 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryFilter(t *testing.T) {
+	jan := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	entry := Entry{Service: "GitHub", Username: "alice", Folder: "Work/Dev", Tags: []string{"Code"}, CreatedAt: jan}
+
+	tests := []struct {
+		name   string
+		filter EntryFilter
+		want   bool
+	}{
+		{"empty", EntryFilter{}, true},
+		{"query", EntryFilter{Query: "git"}, true},
+		{"query miss", EntryFilter{Query: "gitlab"}, false},
+		{"tag any case", EntryFilter{Tags: []string{"code"}}, true},
+		{"all tags needed", EntryFilter{Tags: []string{"code", "prod"}}, false},
+		{"parent folder", EntryFilter{Folder: "/work/"}, true},
+		{"folder prefix is not a parent", EntryFilter{Folder: "Wor"}, false},
+		{"since", EntryFilter{Since: jan}, true},
+		{"since after", EntryFilter{Since: jan.Add(time.Hour)}, false},
+		{"until is exclusive", EntryFilter{Until: jan}, false},
+		{"invalid query", EntryFilter{Query: "(git"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(entry); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestShareRoundTrip(t *testing.T) {
+	changed := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	github := Entry{
+		Service: "GitHub", Username: "alice", Password: "new", Folder: "Work", Tags: []string{"dev"},
+		Fields:            []CustomField{{Name: "PIN", Value: "1234", Hidden: true}},
+		Attachments:       []Attachment{{Name: "codes.txt", Data: []byte("codes")}},
+		History:           []PasswordHistory{{Password: "old", ChangedAt: changed}},
+		CreatedAt:         changed.AddDate(-1, 0, 0),
+		PasswordChangedAt: changed,
+		RotationDays:      90,
+	}
+	v := newTestVault(t, github, Entry{Service: "Bank", Username: "bob", Password: "pw"})
+
+	path := filepath.Join(t.TempDir(), "share.age")
+	if n, err := v.ExportVault(path, []byte("share phrase"), EntryFilter{Tags: []string{"dev"}}); err != nil || n != 1 {
+		t.Fatalf("ExportVault = %d, %v", n, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("share file: %v, %v", info, err)
+	}
+	if _, err := v.ExportVault(path, []byte("share phrase"), EntryFilter{}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("overwrote an existing file: %v", err)
+	}
+
+	other := newTestVault(t)
+	if _, err := other.MergeVault(path, []byte("wrong"), ImportOptions{}); err == nil {
+		t.Error("merged with the wrong passphrase")
+	}
+	result, err := other.MergeVault(path, []byte("share phrase"), ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Added) != 1 || len(other.Entries) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	got := other.Entries[0]
+	got.CreatedAt, got.PasswordChangedAt = got.CreatedAt.UTC(), got.PasswordChangedAt.UTC()
+	got.History[0].ChangedAt = got.History[0].ChangedAt.UTC()
+	if !reflect.DeepEqual(got, github) {
+		t.Errorf("merged entry:\n got %+v\nwant %+v", got, github)
+	}
+}
+
+func TestMergeRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.dat")
+	if err := os.WriteFile(path, []byte("not an age file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	v := newTestVault(t)
+	for _, passphrase := range []string{"", "phrase"} {
+		if _, err := v.MergeVault(path, []byte(passphrase), ImportOptions{}); err == nil {
+			t.Errorf("passphrase %q: merged a file that is not a shared vault", passphrase)
+		}
+	}
+	if _, err := v.MergeVault(path, []byte("phrase"), ImportOptions{}); err == nil || !strings.Contains(err.Error(), "not a shared vault") {
+		t.Errorf("err = %v, want not a shared vault", err)
+	}
+}
+
+func TestMoveEntries(t *testing.T) {
+	src := newTestVault(t,
+		Entry{Service: "GitHub", Folder: "Work", Password: "a"},
+		Entry{Service: "Bank", Password: "b"},
+	)
+	dst := newTestVault(t, Entry{Service: "Mail", Password: "c"})
+
+	n, err := src.MoveEntries(dst, EntryFilter{Folder: "Work"})
+	if err != nil || n != 1 {
+		t.Fatalf("MoveEntries = %d, %v", n, err)
+	}
+	if len(src.Entries) != 1 || src.Entries[0].Service != "Bank" || len(dst.Entries) != 2 || dst.Entries[1].Service != "GitHub" {
+		t.Errorf("src = %+v, dst = %+v", src.Entries, dst.Entries)
+	}
+	if _, err := src.MoveEntries(src, EntryFilter{}); err == nil {
+		t.Error("moved entries into the same vault")
+	}
+	if _, err := src.MoveEntries(dst, EntryFilter{Query: "nothing"}); err == nil {
+		t.Error("moved no entries without an error")
+	}
+}
+
+func TestMoveEntriesRollsBack(t *testing.T) {
+	src := newTestVault(t, Entry{Service: "GitHub", Password: "a"})
+	dst := newTestVault(t, Entry{Service: "Mail", Password: "c"})
+	// The destination cannot be saved, as its directory is gone.
+	dst.filePath = filepath.Join(t.TempDir(), "missing", "vault.dat")
+
+	if _, err := src.MoveEntries(dst, EntryFilter{}); err == nil {
+		t.Fatal("MoveEntries succeeded without saving the destination")
+	}
+	if len(src.Entries) != 1 || len(dst.Entries) != 1 || dst.Entries[0].Service != "Mail" {
+		t.Errorf("src = %+v, dst = %+v", src.Entries, dst.Entries)
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"sort"
 	"strings"
 	"time"
-	"unicode"
+
+	"pw/security"
 )
 
 type VaultStatistics struct {
@@ -17,9 +18,11 @@ type VaultStatistics struct {
 	StrongPasswords    int
 	OldestEntry        time.Time
 	NewestEntry        time.Time
+	OverdueRotations   int
 	CommonServices     []ServiceCount
 	CommonUsernames    []UsernameCount
 	PasswordReuse      []PasswordReuseInfo
+	PasswordFamilies   []PasswordFamily
 	EntriesPerMonth    map[string]int
 }
 
@@ -29,14 +32,14 @@ type ServiceCount struct {
 }
 
 type UsernameCount struct {
-	Username string
-	Count    int
+	Username string `json:"username"`
+	Count    int    `json:"count"`
 }
 
 type PasswordReuseInfo struct {
-	Password     string
-	Count        int
-	ServicesList []string
+	Password     string   `json:"password"`
+	Count        int      `json:"count"`
+	ServicesList []string `json:"services"`
 }
 
 func (v *Vault) CalculateStatistics() VaultStatistics {
@@ -56,8 +59,9 @@ func (v *Vault) CalculateStatistics() VaultStatistics {
 
 	stats.OldestEntry = v.Entries[0].CreatedAt
 	stats.NewestEntry = v.Entries[0].CreatedAt
+	now := time.Now()
 
-	for _, entry := range v.Entries {
+	for i, entry := range v.Entries {
 		services[entry.Service]++
 		usernames[entry.Username]++
 		passwords[entry.Password] = append(passwords[entry.Password], entry.Service)
@@ -70,14 +74,17 @@ func (v *Vault) CalculateStatistics() VaultStatistics {
 			stats.NewestEntry = entry.CreatedAt
 		}
 
+		if status, ok := v.rotationStatus(i, entry, now); ok && status.Overdue {
+			stats.OverdueRotations++
+		}
+
 		monthKey := entry.CreatedAt.Format("2006-01")
 		stats.EntriesPerMonth[monthKey]++
 
-		strength := analyzePasswordStrength(entry.Password)
-		switch {
-		case strength < 40:
+		switch security.AnalyzePassword(entry.Password, entry.Service, entry.Username).Level {
+		case security.VeryWeak, security.Weak:
 			stats.WeakPasswords++
-		case strength < 70:
+		case security.Medium:
 			stats.MediumPasswords++
 		default:
 			stats.StrongPasswords++
@@ -91,6 +98,7 @@ func (v *Vault) CalculateStatistics() VaultStatistics {
 	stats.CommonServices = getTopCounts(services, 5)
 	stats.CommonUsernames = getTopUsernameCounts(usernames, 5)
 	stats.PasswordReuse = getPasswordReuse(passwords, 2)
+	stats.PasswordFamilies = findPasswordFamilies(passwords)
 
 	return stats
 }
@@ -153,48 +161,9 @@ func getPasswordReuse(passwords map[string][]string, minCount int) []PasswordReu
 }
 
 func maskPassword(password string) string {
-	if len(password) <= 4 {
+	runes := []rune(password)
+	if len(runes) <= 4 {
 		return "****"
 	}
-	return password[:2] + strings.Repeat("*", len(password)-4) + password[len(password)-2:]
-}
-
-func analyzePasswordStrength(password string) int {
-	var score int
-	length := len(password)
-
-	score += length * 4
-
-	hasUpper := false
-	hasLower := false
-	hasNumber := false
-	hasSpecial := false
-
-	for _, char := range password {
-		switch {
-		case unicode.IsUpper(char):
-			hasUpper = true
-		case unicode.IsLower(char):
-			hasLower = true
-		case unicode.IsNumber(char):
-			hasNumber = true
-		case unicode.IsPunct(char) || unicode.IsSymbol(char):
-			hasSpecial = true
-		}
-	}
-
-	if hasUpper {
-		score += 10
-	}
-	if hasLower {
-		score += 10
-	}
-	if hasNumber {
-		score += 10
-	}
-	if hasSpecial {
-		score += 15
-	}
-
-	return score
+	return string(runes[:2]) + strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-2:])
 }
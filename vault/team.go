@@ -0,0 +1,395 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+
+	"pw/crypto"
+)
+
+type Role string
+
+const (
+	RoleEditor   Role = "editor"
+	RoleReadOnly Role = "read-only"
+)
+
+var ErrReadOnly = errors.New("vault is read-only for your role")
+
+type TeamMember struct {
+	Name       string `json:"name"`
+	PublicKey  string `json:"public_key"`
+	Role       Role   `json:"role"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+type TeamHeader struct {
+	Members []TeamMember `json:"members"`
+}
+
+// teamFileVersion 2 seals the data with an AEAD bound to the team header.
+// Version 1 files used an unauthenticated cipher and are not accepted.
+const teamFileVersion = 2
+
+type teamFile struct {
+	Version int        `json:"version"`
+	Team    TeamHeader `json:"team"`
+	Data    []byte     `json:"data"`
+}
+
+func ParseRole(s string) (Role, error) {
+	switch Role(s) {
+	case RoleEditor, RoleReadOnly:
+		return Role(s), nil
+	default:
+		return "", fmt.Errorf("unknown role %q (use %s or %s)", s, RoleEditor, RoleReadOnly)
+	}
+}
+
+func CreateTeamVault(path, memberName string, identity *age.X25519Identity) (*Vault, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("file %s already exists", path)
+	}
+
+	key, err := newDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vault{
+		Entries:  make([]Entry, 0),
+		key:      key,
+		identity: identity.String(),
+		filePath: path,
+		team:     &TeamHeader{},
+		member:   identity.Recipient().String(),
+	}
+
+	v.team.Members = []TeamMember{{
+		Name:      memberName,
+		PublicKey: v.member,
+		Role:      RoleEditor,
+	}}
+	if err := v.wrapKeys(); err != nil {
+		return nil, err
+	}
+
+	return v, v.Save()
+}
+
+func OpenTeamVault(path string, identity *age.X25519Identity) (*Vault, error) {
+	file, err := readTeamFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := identity.Recipient().String()
+	var member *TeamMember
+	for i := range file.Team.Members {
+		if file.Team.Members[i].PublicKey == publicKey {
+			member = &file.Team.Members[i]
+			break
+		}
+	}
+	if member == nil {
+		return nil, fmt.Errorf("you are not a member of this team vault")
+	}
+
+	decrypted, err := age.Decrypt(bytes.NewReader(member.WrappedKey), identity)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unwrap data key: %v", err)
+	}
+	key, err := io.ReadAll(decrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Version != teamFileVersion {
+		return nil, fmt.Errorf("team vault format version %d is not supported: it was written without authentication, so re-create the vault and import its entries", file.Version)
+	}
+	additionalData, err := teamAdditionalData(file.Team)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := crypto.Open(file.Data, additionalData, key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt team vault: %v", err)
+	}
+
+	var data VaultData
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, fmt.Errorf("cannot decrypt team vault: %v", err)
+	}
+
+	team := file.Team
+	return &Vault{
+		Entries:  data.Entries,
+		key:      key,
+		identity: identity.String(),
+		filePath: path,
+		team:     &team,
+		member:   publicKey,
+
+		folderRotation:      data.FolderRotation,
+		serviceRequirements: data.ServiceRequirements,
+		folderRequirements:  data.FolderRequirements,
+	}, nil
+}
+
+func IsTeamVault(path string) bool {
+	_, err := readTeamFile(path)
+	return err == nil
+}
+
+func readTeamFile(path string) (*teamFile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file teamFile
+	if err := json.Unmarshal(raw, &file); err != nil || len(file.Team.Members) == 0 {
+		return nil, fmt.Errorf("%s is not a team vault", path)
+	}
+	return &file, nil
+}
+
+func newDataKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (v *Vault) IsTeam() bool {
+	return v.team != nil
+}
+
+func (v *Vault) Role() Role {
+	if v.team == nil {
+		return RoleEditor
+	}
+	for _, m := range v.team.Members {
+		if m.PublicKey == v.member {
+			return m.Role
+		}
+	}
+	return RoleReadOnly
+}
+
+func (v *Vault) ReadOnly() bool {
+	return v.Role() != RoleEditor
+}
+
+func (v *Vault) checkWritable() error {
+	if v.ReadOnly() {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+func (v *Vault) Members() []TeamMember {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if v.team == nil {
+		return nil
+	}
+	return append([]TeamMember{}, v.team.Members...)
+}
+
+func (v *Vault) AddMember(name, publicKey string, role Role) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.checkTeamEditor(); err != nil {
+		return err
+	}
+	if _, err := age.ParseX25519Recipient(publicKey); err != nil {
+		return fmt.Errorf("invalid public key: %v", err)
+	}
+	for _, m := range v.team.Members {
+		if m.PublicKey == publicKey || m.Name == name {
+			return fmt.Errorf("member %s is already part of this vault", m.Name)
+		}
+	}
+
+	v.team.Members = append(v.team.Members, TeamMember{
+		Name:      name,
+		PublicKey: publicKey,
+		Role:      role,
+	})
+	if err := v.wrapKeys(); err != nil {
+		return err
+	}
+	return v.Save()
+}
+
+func (v *Vault) RemoveMember(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.checkTeamEditor(); err != nil {
+		return err
+	}
+
+	remaining := make([]TeamMember, 0, len(v.team.Members))
+	for _, m := range v.team.Members {
+		if m.Name != name {
+			remaining = append(remaining, m)
+		}
+	}
+	if len(remaining) == len(v.team.Members) {
+		return fmt.Errorf("member %s not found", name)
+	}
+	if !hasEditor(remaining) {
+		return fmt.Errorf("a team vault needs at least one editor")
+	}
+
+	key, err := newDataKey()
+	if err != nil {
+		return err
+	}
+
+	previousMembers, previousKey := v.team.Members, v.key
+	v.team.Members = remaining
+	v.key = key
+	if err := v.wrapKeys(); err != nil {
+		v.team.Members, v.key = previousMembers, previousKey
+		return err
+	}
+	// The caller was checked above and may have just removed themselves.
+	if err := v.writeTeam(); err != nil {
+		v.team.Members, v.key = previousMembers, previousKey
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) SetMemberRole(name string, role Role) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.checkTeamEditor(); err != nil {
+		return err
+	}
+
+	members := append([]TeamMember{}, v.team.Members...)
+	found := false
+	for i := range members {
+		if members[i].Name == name {
+			members[i].Role = role
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("member %s not found", name)
+	}
+	if !hasEditor(members) {
+		return fmt.Errorf("a team vault needs at least one editor")
+	}
+
+	previous := v.team.Members
+	v.team.Members = members
+	// The caller was checked above and may have just made themselves read-only.
+	if err := v.writeTeam(); err != nil {
+		v.team.Members = previous
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) checkTeamEditor() error {
+	if v.team == nil {
+		return fmt.Errorf("not a team vault")
+	}
+	return v.checkWritable()
+}
+
+func hasEditor(members []TeamMember) bool {
+	for _, m := range members {
+		if m.Role == RoleEditor {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Vault) wrapKeys() error {
+	for i := range v.team.Members {
+		recipient, err := age.ParseX25519Recipient(v.team.Members[i].PublicKey)
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		w, err := age.Encrypt(&buf, recipient)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(v.key); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		v.team.Members[i].WrappedKey = buf.Bytes()
+	}
+	return nil
+}
+
+func (v *Vault) saveTeam() error {
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
+	return v.writeTeam()
+}
+
+func (v *Vault) writeTeam() error {
+	jsonData, err := json.Marshal(VaultData{
+		Entries:             v.Entries,
+		FolderRotation:      v.folderRotation,
+		ServiceRequirements: v.serviceRequirements,
+		FolderRequirements:  v.folderRequirements,
+	})
+	if err != nil {
+		return err
+	}
+
+	additionalData, err := teamAdditionalData(*v.team)
+	if err != nil {
+		return err
+	}
+	sealed, err := crypto.Seal(jsonData, additionalData, v.key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(teamFile{
+		Version: teamFileVersion,
+		Team:    *v.team,
+		Data:    sealed,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(v.filePath, data, 0600)
+}
+
+// teamAdditionalData binds the sealed data to the format version and the
+// member list, so neither can be swapped without failing authentication.
+func teamAdditionalData(team TeamHeader) ([]byte, error) {
+	return json.Marshal(struct {
+		Version int        `json:"version"`
+		Team    TeamHeader `json:"team"`
+	}{teamFileVersion, team})
+}
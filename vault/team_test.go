@@ -0,0 +1,132 @@
+package vault
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+
+	"pw/crypto"
+)
+
+func newTestIdentity(t *testing.T) *age.X25519Identity {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+func TestTeamMembers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.vault")
+	alice, bob := newTestIdentity(t), newTestIdentity(t)
+
+	v, err := CreateTeamVault(path, "alice", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddEntry(Entry{Service: "GitHub", Password: "gh-pass"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddMember("bob", bob.Recipient().String(), RoleReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddMember("bob2", bob.Recipient().String(), RoleEditor); err == nil {
+		t.Error("added the same key twice")
+	}
+	if err := v.AddMember("carol", "age1notakey", RoleEditor); err == nil {
+		t.Error("added an invalid key")
+	}
+
+	reader, err := OpenTeamVault(path, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reader.Entries) != 1 || reader.Entries[0].Password != "gh-pass" {
+		t.Errorf("read-only member sees %+v", reader.Entries)
+	}
+	if err := reader.AddEntry(Entry{Service: "Bank"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("AddEntry err = %v, want ErrReadOnly", err)
+	}
+	if err := reader.Save(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Save err = %v, want ErrReadOnly", err)
+	}
+	if err := reader.AddMember("mallory", newTestIdentity(t).Recipient().String(), RoleEditor); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("AddMember err = %v, want ErrReadOnly", err)
+	}
+
+	if err := v.SetMemberRole("alice", RoleReadOnly); err == nil {
+		t.Error("demoted the only editor")
+	}
+	if err := v.RemoveMember("alice"); err == nil {
+		t.Error("removed the only editor")
+	}
+
+	// An editor may hand over and demote themselves; the change is saved.
+	if err := v.SetMemberRole("bob", RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetMemberRole("alice", RoleReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenTeamVault(path, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Role() != RoleReadOnly {
+		t.Errorf("alice's role = %s after self-demotion", reopened.Role())
+	}
+}
+
+func TestTeamRemoveMemberRotatesKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.vault")
+	alice, bob := newTestIdentity(t), newTestIdentity(t)
+
+	v, err := CreateTeamVault(path, "alice", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddEntry(Entry{Service: "GitHub", Password: "gh-pass"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddMember("bob", bob.Recipient().String(), RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	before, err := OpenTeamVault(path, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey := before.key
+
+	if err := v.RemoveMember("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.RemoveMember("bob"); err == nil {
+		t.Error("removed a member twice")
+	}
+
+	if _, err := OpenTeamVault(path, bob); err == nil {
+		t.Error("a removed member opened the vault")
+	}
+	file, err := readTeamFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	additionalData, err := teamAdditionalData(file.Team)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crypto.Open(file.Data, additionalData, oldKey); err == nil {
+		t.Error("the removed member's data key still decrypts the vault")
+	}
+
+	after, err := OpenTeamVault(path, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after.Members()) != 1 || len(after.Entries) != 1 || after.Entries[0].Password != "gh-pass" {
+		t.Errorf("members %+v, entries %+v", after.Members(), after.Entries)
+	}
+}
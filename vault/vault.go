@@ -2,9 +2,9 @@ package vault
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
@@ -12,29 +12,73 @@ import (
 )
 
 type Entry struct {
-	Service   string
-	Username  string
+	Service     string
+	Username    string
+	Password    string
+	Notes       string
+	Folder      string
+	Tags        []string
+	URLs        []string
+	TOTP        string
+	Fields      []CustomField
+	Attachments []Attachment
+	History     []PasswordHistory
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	// PasswordChangedAt is zero until the password is first changed.
+	PasswordChangedAt time.Time
+	// RotationDays overrides the folder rotation policy when positive.
+	RotationDays int `json:",omitempty"`
+	// Preset names the generator preset that new passwords follow.
+	Preset string `json:",omitempty"`
+}
+
+type CustomField struct {
+	Name   string
+	Value  string
+	Hidden bool
+}
+
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+type PasswordHistory struct {
 	Password  string
-	Notes     string
-	CreatedAt time.Time
+	ChangedAt time.Time
 }
 
 type Vault struct {
 	Entries  []Entry
 	mu       sync.RWMutex
 	key      []byte
+	identity string
 	filePath string
+	team     *TeamHeader
+	member   string
+	// folderRotation maps folders to rotation intervals in days.
+	folderRotation map[string]int
+	// serviceRequirements and folderRequirements hold the password
+	// requirements of services, by lower-case name, and of folders.
+	serviceRequirements map[string]PasswordRequirements
+	folderRequirements  map[string]PasswordRequirements
 }
 
 func NewVault(path string, key []byte) (*Vault, error) {
 	v := &Vault{
 		Entries:  make([]Entry, 0),
 		key:      key,
-		filePath: "vault.dat",
+		filePath: path,
 	}
 
-	if _, err := os.Stat("vault.dat"); err == nil {
+	if _, err := os.Stat(path); err == nil {
 		if err := v.Load(); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				return nil, fmt.Errorf("invalid master key")
+			}
 			return nil, err
 		}
 		if string(v.key) != string(key) {
@@ -62,6 +106,9 @@ func New() *Vault {
 func (v *Vault) AddEntry(entry Entry) error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
 	v.Entries = append(v.Entries, entry)
 	return v.Save()
 }
@@ -75,7 +122,14 @@ func (v *Vault) GetEntries() []Entry {
 func (v *Vault) UpdateEntry(index int, entry Entry) error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
 	if index >= 0 && index < len(v.Entries) {
+		if password := entry.Password; password != v.Entries[index].Password {
+			entry.Password = v.Entries[index].Password
+			changePassword(&entry, password, time.Now())
+		}
 		v.Entries[index] = entry
 		return v.Save()
 	}
@@ -85,6 +139,9 @@ func (v *Vault) UpdateEntry(index int, entry Entry) error {
 func (v *Vault) DeleteEntry(index int) error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
+	if err := v.checkWritable(); err != nil {
+		return err
+	}
 	if index >= 0 && index < len(v.Entries) {
 		v.Entries = append(v.Entries[:index], v.Entries[index+1:]...)
 		return v.Save()
@@ -92,24 +149,6 @@ func (v *Vault) DeleteEntry(index int) error {
 	return fmt.Errorf("invalid entry index")
 }
 
-func (v *Vault) SearchEntries(query string) []Entry {
-	v.mu.RLock()
-	defer v.mu.RUnlock()
-
-	query = strings.ToLower(query)
-	results := make([]Entry, 0)
-
-	for _, entry := range v.Entries {
-		if strings.Contains(strings.ToLower(entry.Service), query) ||
-			strings.Contains(strings.ToLower(entry.Username), query) ||
-			strings.Contains(strings.ToLower(entry.Notes), query) {
-			results = append(results, entry)
-		}
-	}
-
-	return results
-}
-
 func (v *Vault) ToJSON() ([]byte, error) {
 	v.mu.RLock()
 	defer v.mu.RUnlock()
@@ -123,14 +162,28 @@ func (v *Vault) FromJSON(data []byte) error {
 }
 
 type VaultData struct {
-	Entries []Entry `json:"entries"`
-	Key     []byte  `json:"key"`
+	Entries  []Entry `json:"entries"`
+	Key      []byte  `json:"key"`
+	Identity string  `json:"identity,omitempty"`
+
+	FolderRotation      map[string]int                  `json:"folder_rotation,omitempty"`
+	ServiceRequirements map[string]PasswordRequirements `json:"service_requirements,omitempty"`
+	FolderRequirements  map[string]PasswordRequirements `json:"folder_requirements,omitempty"`
 }
 
 func (v *Vault) Save() error {
+	if v.team != nil {
+		return v.saveTeam()
+	}
+
 	data := VaultData{
-		Entries: v.Entries,
-		Key:     v.key,
+		Entries:  v.Entries,
+		Key:      v.key,
+		Identity: v.identity,
+
+		FolderRotation:      v.folderRotation,
+		ServiceRequirements: v.serviceRequirements,
+		FolderRequirements:  v.folderRequirements,
 	}
 
 	jsonData, err := json.Marshal(data)
@@ -157,18 +210,9 @@ func (v *Vault) Load() error {
 
 	v.Entries = vaultData.Entries
 	v.key = vaultData.Key
-	return nil
-}
-
-func (v *Vault) findEntry(service, username string) *Entry {
-	v.mu.RLock()
-	defer v.mu.RUnlock()
-
-	for i := range v.Entries {
-		if v.Entries[i].Service == service && v.Entries[i].Username == username {
-			return &v.Entries[i]
-		}
-	}
-
+	v.identity = vaultData.Identity
+	v.folderRotation = vaultData.FolderRotation
+	v.serviceRequirements = vaultData.ServiceRequirements
+	v.folderRequirements = vaultData.FolderRequirements
 	return nil
 }
@@ -0,0 +1,51 @@
+package vault
+
+import (
+	"bytes"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// exportYAML writes a YAML sequence with one mapping per entry. Each entry
+// is encoded as its own one-item sequence, so the output grows entry by
+// entry like the JSON export.
+func exportYAML(w io.Writer, entries []Entry, fields []string) error {
+	if len(entries) == 0 {
+		_, err := io.WriteString(w, "[]\n")
+		return err
+	}
+
+	for _, e := range entries {
+		item := &yaml.Node{Kind: yaml.MappingNode}
+		for _, field := range fields {
+			var value yaml.Node
+			if err := value.Encode(exportValue(e, field)); err != nil {
+				return err
+			}
+			item.Content = append(item.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field}, &value)
+		}
+
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{item}}); err != nil {
+			return err
+		}
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseYAML(r io.Reader) ([]Entry, error) {
+	var records []importedEntry
+	if err := yaml.NewDecoder(r).Decode(&records); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return importedEntries(records)
+}